@@ -2,12 +2,59 @@ package main
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/spf13/cobra"
+
+	"github.com/B-AJ-Amar/gokv/internal/config"
 	"github.com/B-AJ-Amar/gokv/internal/server"
 )
 
-func main() {
+func newRootCmd() *cobra.Command {
+	defaults := config.Default()
+	flags := map[string]*string{}
+
+	cmd := &cobra.Command{
+		Use:   "gokv [config-file]",
+		Short: "GoKV is a lightweight, Redis-compatible in-memory key-value store",
+		Long: "GoKV is a lightweight, Redis-compatible in-memory key-value store.\n\n" +
+			"Settings are read from the optional redis style config file, and any\n" +
+			"flag given on the command line overrides the file.",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.Default()
+			if len(args) == 1 {
+				var err error
+				if cfg, err = config.Load(args[0]); err != nil {
+					return err
+				}
+			}
+			for name, value := range flags {
+				if cmd.Flags().Changed(name) {
+					if err := cfg.Set(name, *value); err != nil {
+						return err
+					}
+				}
+			}
+
+			fmt.Println("GoKV")
+			return server.RunServer(cfg)
+		},
+	}
 
-	fmt.Print("GoKV")
-	server.RunServer()
+	// one flag per config parameter, named after it: --port 6380
+	for _, name := range config.Names() {
+		value, _ := defaults.Get(name)
+		flags[name] = cmd.Flags().String(name, value, config.Usage(name))
+	}
+	return cmd
+}
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "gokv:", err)
+		os.Exit(1)
+	}
 }
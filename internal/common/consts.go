@@ -2,7 +2,24 @@ package common
 
 import "time"
 
+// Version is reported by HELLO
+const Version = "0.1.0"
+
+// defaults for the server configuration, see internal/config
 const (
-	MaxDBIndex       = 15
-	KeepAliveTimeOut = 60 * time.Second
+	DefaultPort            = 6379
+	DefaultBind            = ""
+	DefaultDatabases       = 16
+	DefaultMaxClients      = 10000
+	DefaultSnapshotFile    = "dump.gkv"
+	DefaultAOFFile         = "appendonly.aof"
+	DefaultAppendFsync     = "everysec"
+	DefaultLogLevel        = "notice"
+	DefaultMaxMemPolicy    = "noeviction"
+	DefaultSlowlogSlower   = 10000 // microseconds
+	DefaultSlowlogMaxLen   = 128
+	KeepAliveTimeOut       = 60 * time.Second
+	MaxDatabases           = 1024
+	DefaultProtoMaxBulkLen = 512 << 20 // bytes
+	MaxMultibulkLen        = 1024 * 1024
 )
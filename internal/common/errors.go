@@ -2,17 +2,140 @@ package common
 
 import "errors"
 
+// Code is the class of an error reply, the first word of the message on the
+// wire. clients branch on it, and Go callers can too with
+// errors.Is(err, CodeWrongType).
+type Code string
+
+const (
+	CodeErr       Code = "ERR"
+	CodeWrongType Code = "WRONGTYPE"
+	CodeNoAuth    Code = "NOAUTH"
+	CodeWrongPass Code = "WRONGPASS"
+	CodeNoPerm    Code = "NOPERM"
+	CodeNoProto   Code = "NOPROTO"
+	CodeExecAbort Code = "EXECABORT"
+	CodeBusy      Code = "BUSY"
+	CodeOOM       Code = "OOM"
+	CodeReadOnly  Code = "READONLY"
+	CodeLoading   Code = "LOADING"
+	CodeNoScript  Code = "NOSCRIPT"
+	CodeMoved     Code = "MOVED"
+	CodeAsk       Code = "ASK"
+)
+
+func (c Code) Error() string { return string(c) }
+
+// Error is an error reply: its code and the message that follows it
+type Error struct {
+	Code Code
+	Msg  string
+}
+
+func NewError(code Code, msg string) *Error {
+	return &Error{Code: code, Msg: msg}
+}
+
+func (e *Error) Error() string { return string(e.Code) + " " + e.Msg }
+
+// Is matches the code of the error, the sentinels below are matched by
+// identity as usual
+func (e *Error) Is(target error) bool {
+	c, ok := target.(Code)
+	return ok && c == e.Code
+}
+
+// CodeOf is the code an error is replied with, ERR for the errors that are
+// not an *Error
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeErr
+}
+
 var (
-	ErrInvalidFormat      = errors.New("ERR invalid format")
-	ErrUnknownCommand     = errors.New("ERR unknown command")
-	ErrWrongNumberArgs    = errors.New("ERR wrong number of arguments")
-	ErrWrongArgLen        = errors.New("ERR wrong argument length")
-	ErrKeyNotFound        = errors.New("ERR key not found")
-	ErrParseLen           = errors.New("ERR parse len")
-	ErrNotIntOROutOfRange = errors.New("ERR value is not an integer or out of range")
-	ErrInvalidExpireTime  = errors.New("ERR invalid expire time")
-	ErrInvalidIncrement   = errors.New("ERR invalid increment value")
-	ErrInvalidDecrement   = errors.New("ERR invalid decrement value")
-	ErrSyntaxError        = errors.New("ERR syntax error")
-	ErrDBIndexOutOfRange  = errors.New("ERR DB index is out of range")
+	ErrInvalidFormat       = NewError(CodeErr, "invalid format")
+	ErrUnknownCommand      = NewError(CodeErr, "unknown command")
+	ErrWrongNumberArgs     = NewError(CodeErr, "wrong number of arguments")
+	ErrWrongArgLen         = NewError(CodeErr, "wrong argument length")
+	ErrKeyNotFound         = NewError(CodeErr, "key not found")
+	ErrParseLen            = NewError(CodeErr, "parse len")
+	ErrNotIntOROutOfRange  = NewError(CodeErr, "value is not an integer or out of range")
+	ErrInvalidExpireTime   = NewError(CodeErr, "invalid expire time")
+	ErrInvalidIncrement    = NewError(CodeErr, "invalid increment value")
+	ErrInvalidDecrement    = NewError(CodeErr, "invalid decrement value")
+	ErrIncrOverflow        = NewError(CodeErr, "increment or decrement would overflow")
+	ErrDecrOverflow        = NewError(CodeErr, "decrement would overflow")
+	ErrNotFloat            = NewError(CodeErr, "value is not a valid float")
+	ErrIncrNaN             = NewError(CodeErr, "increment would produce NaN or Infinity")
+	ErrHashNotInt          = NewError(CodeErr, "hash value is not an integer")
+	ErrHashNotFloat        = NewError(CodeErr, "hash value is not a float")
+	ErrInvalidCursor       = NewError(CodeErr, "invalid cursor")
+	ErrNoSuchKey           = NewError(CodeErr, "no such key")
+	ErrIndexOutOfRange     = NewError(CodeErr, "index out of range")
+	ErrMustBePositive      = NewError(CodeErr, "value is out of range, must be positive")
+	ErrRankZero            = NewError(CodeErr, "RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+	ErrCountNegative       = NewError(CodeErr, "COUNT can't be negative")
+	ErrMaxLenNegative      = NewError(CodeErr, "MAXLEN can't be negative")
+	ErrTimeoutNotFloat     = NewError(CodeErr, "timeout is not a float or out of range")
+	ErrTimeoutNegative     = NewError(CodeErr, "timeout is negative")
+	ErrNumKeysNotPositive  = NewError(CodeErr, "numkeys should be greater than 0")
+	ErrNumKeysTooMany      = NewError(CodeErr, "Number of keys can't be greater than number of args")
+	ErrLimitNegative       = NewError(CodeErr, "LIMIT can't be negative")
+	ErrScoreNaN            = NewError(CodeErr, "resulting score is not a number (NaN)")
+	ErrMinMaxNotFloat      = NewError(CodeErr, "min or max is not a float")
+	ErrMinMaxNotLex        = NewError(CodeErr, "min or max not valid string range item")
+	ErrZAddXXAndNX         = NewError(CodeErr, "XX and NX options at the same time are not compatible")
+	ErrZAddGTLTAndNX       = NewError(CodeErr, "GT, LT, and/or NX options at the same time are not compatible")
+	ErrZAddIncrPair        = NewError(CodeErr, "INCR option supports a single increment-element pair")
+	ErrLimitWithoutBy      = NewError(CodeErr, "syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	ErrWithScoresByLex     = NewError(CodeErr, "syntax error, WITHSCORES not supported in combination with BYLEX")
+	ErrWeightNotFloat      = NewError(CodeErr, "weight value is not a float")
+	ErrZStoreNoKeys        = NewError(CodeErr, "at least 1 input key is needed for ZUNIONSTORE/ZINTERSTORE")
+	ErrWrongType           = NewError(CodeWrongType, "Operation against a key holding the wrong kind of value")
+	ErrStringTooLong       = NewError(CodeErr, "string exceeds maximum allowed size (proto-max-bulk-len)")
+	ErrOffsetOutOfRange    = NewError(CodeErr, "offset is out of range")
+	ErrSyntaxError         = NewError(CodeErr, "syntax error")
+	ErrDBIndexOutOfRange   = NewError(CodeErr, "DB index is out of range")
+	ErrInvalidBulkLen      = NewError(CodeErr, "Protocol error: invalid bulk length")
+	ErrInvalidMultibulkLen = NewError(CodeErr, "Protocol error: invalid multibulk length")
+	ErrInlineTooBig        = NewError(CodeErr, "Protocol error: too big inline request")
+	ErrUnbalancedQuotes    = NewError(CodeErr, "Protocol error: unbalanced quotes in request")
+
+	// auth and limits
+	ErrNoAuth         = NewError(CodeNoAuth, "Authentication required.")
+	ErrWrongPass      = NewError(CodeWrongPass, "invalid username-password pair or user is disabled.")
+	ErrAuthNotEnabled = NewError(CodeErr, "AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+	ErrOOM            = NewError(CodeOOM, "command not allowed when used memory > 'maxmemory'.")
+	ErrMaxClients     = NewError(CodeErr, "max number of clients reached")
+	ErrShuttingDown   = NewError(CodeErr, "Server is shutting down")
+	ErrHelloNoAuth    = NewError(CodeNoAuth, "HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+	ErrNoProto        = NewError(CodeNoProto, "unsupported protocol version")
+
+	// persistence
+	ErrBgSaveInProgress     = NewError(CodeErr, "Background save already in progress")
+	ErrPersistenceDisabled  = NewError(CodeErr, "persistence is disabled")
+	ErrSnapshotBadMagic     = NewError(CodeErr, "snapshot: bad magic, not a gokv snapshot")
+	ErrSnapshotVersion      = NewError(CodeErr, "snapshot: unsupported version")
+	ErrSnapshotChecksum     = NewError(CodeErr, "snapshot: checksum mismatch")
+	ErrSnapshotCorrupted    = NewError(CodeErr, "snapshot: corrupted file")
+	ErrSnapshotDBOutOfRange = NewError(CodeErr, "snapshot: DB index is out of range")
+	ErrAOFDisabled          = NewError(CodeErr, "append only file is disabled")
+	ErrAOFRewriteInProgress = NewError(CodeErr, "Background append only file rewriting already in progress")
+	ErrAOFCorrupted         = NewError(CodeErr, "aof: bad file format")
+	ErrInvalidFsyncPolicy   = NewError(CodeErr, "invalid appendfsync policy, expected always, everysec or no")
+
+	// config
+	ErrUnknownConfigParam    = NewError(CodeErr, "unknown config parameter")
+	ErrInvalidConfigValue    = NewError(CodeErr, "invalid config value")
+	ErrInvalidLogLevel       = NewError(CodeErr, "invalid log level, expected debug, verbose, notice or warning")
+	ErrConfigSyntax          = NewError(CodeErr, "config file syntax error")
+	ErrConfigImmutable       = NewError(CodeErr, "can't set immutable config")
+	ErrConfigDuplicateParam  = NewError(CodeErr, "duplicate parameter")
+	ErrConfigNoFile          = NewError(CodeErr, "The server is running without a config file")
+	ErrInvalidEvictionPolicy = NewError(CodeErr, "invalid maxmemory-policy, expected noeviction, allkeys-random, volatile-random or volatile-ttl")
+	ErrConfigUnavailable     = NewError(CodeErr, "command not available without a server configuration")
+	ErrUnknownSubcommand     = NewError(CodeErr, "unknown subcommand or wrong number of arguments")
 )
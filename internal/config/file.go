@@ -0,0 +1,126 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+// Load reads a redis style config file on top of the defaults:
+//
+//	# comment
+//	port 6380
+//	requirepass "some secret"
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := Default()
+	if err := c.parse(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.File = path
+	return c, nil
+}
+
+func (c *Config) parse(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name, value, err := splitLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if err := c.Set(name, value); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// splitLine splits "name value" where value may be double quoted
+func splitLine(line string) (string, string, error) {
+	name, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+	if len(rest) >= 2 && rest[0] == '"' {
+		if rest[len(rest)-1] != '"' {
+			return "", "", common.ErrConfigSyntax
+		}
+		return name, rest[1 : len(rest)-1], nil
+	}
+	if strings.ContainsAny(rest, " \t") {
+		return "", "", common.ErrConfigSyntax
+	}
+	return name, rest, nil
+}
+
+// rewriteFile updates the config file in place: comments and the order of
+// the existing lines are kept, known parameters get their current value,
+// duplicates are dropped and parameters that differ from the defaults but
+// were not in the file yet are appended at the end
+func rewriteFile(path string, c *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	written := map[string]bool{}
+	out := []string{}
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' {
+			out = append(out, line)
+			continue
+		}
+		name, _, _ := strings.Cut(trimmed, " ")
+		p := findParam(name)
+		if p == nil {
+			// unknown lines would have failed Load, keep them untouched
+			out = append(out, line)
+			continue
+		}
+		if written[p.name] {
+			continue
+		}
+		written[p.name] = true
+		out = append(out, formatLine(p.name, p.get(c)))
+	}
+
+	defaults := Default()
+	appended := false
+	for _, p := range params {
+		if written[p.name] || p.get(c) == p.get(defaults) {
+			continue
+		}
+		if !appended {
+			out = append(out, "", "# Generated by CONFIG REWRITE")
+			appended = true
+		}
+		out = append(out, formatLine(p.name, p.get(c)))
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(out, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func formatLine(name, value string) string {
+	if value == "" || strings.ContainsAny(value, " \t#\"") {
+		value = `"` + value + `"`
+	}
+	return name + " " + value
+}
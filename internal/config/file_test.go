@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gokv.conf")
+	content := `# test config
+port 7000
+bind 127.0.0.1
+databases 4
+
+timeout 30
+maxmemory 100mb
+requirepass "my secret"
+dir ` + dir + `
+appendonly yes
+appendfsync always
+loglevel WARNING
+`
+	os.WriteFile(path, []byte(content), 0644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if cfg.Port != 7000 || cfg.Bind != "127.0.0.1" || cfg.Databases != 4 || cfg.Timeout != 30 {
+		t.Errorf("Unexpected network settings: %+v", cfg)
+	}
+	if cfg.MaxMemory != 100<<20 {
+		t.Errorf("Expected maxmemory %d, got %d", 100<<20, cfg.MaxMemory)
+	}
+	if cfg.RequirePass != "my secret" {
+		t.Errorf("Expected quoted requirepass, got %q", cfg.RequirePass)
+	}
+	if !cfg.AppendOnly || cfg.AppendFsync != "always" || cfg.LogLevel != "warning" {
+		t.Errorf("Unexpected persistence/log settings: %+v", cfg)
+	}
+	if cfg.AOFPath() != filepath.Join(dir, common.DefaultAOFFile) {
+		t.Errorf("Unexpected AOF path %q", cfg.AOFPath())
+	}
+	// untouched parameters keep their defaults
+	if cfg.MaxClients != common.DefaultMaxClients {
+		t.Errorf("Expected default maxclients, got %d", cfg.MaxClients)
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		wantErr error
+	}{
+		{"port abc\n", common.ErrInvalidConfigValue},
+		{"nosuchparam 1\n", common.ErrUnknownConfigParam},
+		{"requirepass \"unterminated\n", common.ErrConfigSyntax},
+		{"bind 1.2.3.4 5.6.7.8\n", common.ErrConfigSyntax},
+		{"appendonly maybe\n", common.ErrInvalidConfigValue},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "gokv.conf")
+		os.WriteFile(path, []byte(tt.content), 0644)
+		_, err := Load(path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: expected %v, got %v", tt.content, tt.wantErr, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("%q: expected the line number in %q", tt.content, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"port", "70000"},
+		{"databases", "0"},
+		{"timeout", "-1"},
+		{"maxclients", "0"},
+		{"appendfsync", "sometimes"},
+		{"loglevel", "loud"},
+		{"dir", "/does/not/exist"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		if err := cfg.Set(tt.name, tt.value); err != nil {
+			t.Fatalf("Set %s failed: %v", tt.name, err)
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected %s %s to be rejected", tt.name, tt.value)
+		}
+	}
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Expected the defaults to be valid, got %v", err)
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := map[string]int64{
+		"0": 0, "1024": 1024, "1k": 1000, "1kb": 1024, "2mb": 2 << 20,
+		"1m": 1000000, "1gb": 1 << 30, "3G": 3000000000, "10b": 10,
+	}
+	for in, want := range tests {
+		got, err := ParseMemory(in)
+		if err != nil || got != want {
+			t.Errorf("ParseMemory(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "mb", "-1", "1tb"} {
+		if _, err := ParseMemory(in); err == nil {
+			t.Errorf("ParseMemory(%q) expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+// Live is the running configuration. readers get an immutable *Config, and
+// CONFIG SET swaps in a modified copy, so a reader never sees half of an
+// update and no lock is needed on the hot path.
+type Live struct {
+	mu    sync.Mutex // serializes writers
+	cur   atomic.Pointer[Config]
+	hooks []func(old, new *Config)
+}
+
+func NewLive(c *Config) *Live {
+	l := &Live{}
+	l.cur.Store(c)
+	return l
+}
+
+// Get returns the current configuration, it must not be modified
+func (l *Live) Get() *Config {
+	return l.cur.Load()
+}
+
+// OnChange registers fn to be called after every successful SetMany,
+// this is how settings like loglevel get hot applied
+func (l *Live) OnChange(fn func(old, new *Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, fn)
+}
+
+// Match returns name/value pairs of the parameters matching any of the glob
+// patterns, in declaration order and without duplicates
+func (l *Live) Match(patterns []string) [][2]string {
+	c := l.Get()
+	res := [][2]string{}
+	for _, p := range params {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), p.name); ok {
+				res = append(res, [2]string{p.name, p.get(c)})
+				break
+			}
+		}
+	}
+	return res
+}
+
+// SetMany applies name/value pairs atomically: either every value is valid
+// and the new configuration is swapped in, or nothing changes
+func (l *Live) SetMany(pairs [][2]string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old := l.Get()
+	next := *old
+	seen := map[string]bool{}
+	for _, pair := range pairs {
+		p := findParam(pair[0])
+		if p == nil {
+			// quoted, the name comes from the client as is
+			return fmt.Errorf("%w %q", common.ErrUnknownConfigParam, pair[0])
+		}
+		if seen[p.name] {
+			return fmt.Errorf("%w '%s'", common.ErrConfigDuplicateParam, p.name)
+		}
+		seen[p.name] = true
+		if !p.mutable {
+			return fmt.Errorf("%w '%s'", common.ErrConfigImmutable, p.name)
+		}
+		if err := p.set(&next, pair[1]); err != nil {
+			return fmt.Errorf("'%s': %w", p.name, err)
+		}
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	l.cur.Store(&next)
+	for _, fn := range l.hooks {
+		fn(old, &next)
+	}
+	return nil
+}
+
+// Rewrite writes the running configuration back to the file it was loaded
+// from, see rewriteFile
+func (l *Live) Rewrite() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.Get()
+	if c.File == "" {
+		return common.ErrConfigNoFile
+	}
+	return rewriteFile(c.File, c)
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+func TestLiveMatch(t *testing.T) {
+	live := NewLive(Default())
+	got := live.Match([]string{"max*", "PORT", "maxclients"})
+	want := [][2]string{
+		{"port", "6379"},
+		{"maxclients", "10000"},
+		{"maxmemory", "0"},
+		{"maxmemory-policy", "noeviction"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := live.Match([]string{"nothing*"}); len(got) != 0 {
+		t.Errorf("Expected no match, got %v", got)
+	}
+}
+
+func TestLiveSetMany(t *testing.T) {
+	live := NewLive(Default())
+	calls := 0
+	live.OnChange(func(old, new *Config) {
+		calls++
+		if old.Timeout != 0 || new.Timeout != 30 {
+			t.Errorf("Unexpected hook values old=%d new=%d", old.Timeout, new.Timeout)
+		}
+	})
+	before := live.Get()
+
+	if err := live.SetMany([][2]string{{"timeout", "30"}, {"maxmemory", "1mb"}}); err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+	if cfg := live.Get(); cfg.Timeout != 30 || cfg.MaxMemory != 1<<20 {
+		t.Errorf("Expected timeout 30 and maxmemory 1mb, got %+v", cfg)
+	}
+	if before.Timeout != 0 {
+		t.Errorf("Expected readers holding the old config to be unaffected")
+	}
+	if calls != 1 {
+		t.Errorf("Expected the hook to run once, got %d", calls)
+	}
+
+	// one bad pair leaves everything untouched
+	err := live.SetMany([][2]string{{"timeout", "60"}, {"maxmemory-policy", "lru"}})
+	if !errors.Is(err, common.ErrInvalidEvictionPolicy) {
+		t.Errorf("Expected eviction policy error, got %v", err)
+	}
+	if live.Get().Timeout != 30 {
+		t.Errorf("Expected a failed SetMany to change nothing")
+	}
+
+	if err := live.SetMany([][2]string{{"port", "7000"}}); !errors.Is(err, common.ErrConfigImmutable) {
+		t.Errorf("Expected immutable error, got %v", err)
+	}
+	if err := live.SetMany([][2]string{{"nope", "1"}}); !errors.Is(err, common.ErrUnknownConfigParam) {
+		t.Errorf("Expected unknown param error, got %v", err)
+	}
+	if err := live.SetMany([][2]string{{"nope\r\n+OK", "1"}}); err == nil || strings.ContainsAny(err.Error(), "\r\n") {
+		t.Errorf("Expected the unknown name to be quoted, got %q", err)
+	}
+	if err := live.SetMany([][2]string{{"timeout", "1"}, {"TIMEOUT", "2"}}); !errors.Is(err, common.ErrConfigDuplicateParam) {
+		t.Errorf("Expected duplicate parameter error, got %v", err)
+	}
+}
+
+func TestLiveRewrite(t *testing.T) {
+	if err := NewLive(Default()).Rewrite(); !errors.Is(err, common.ErrConfigNoFile) {
+		t.Errorf("Expected no file error, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "gokv.conf")
+	os.WriteFile(path, []byte("# my server\nport 7000\ntimeout 10\n\n# dup\ntimeout 20\n"), 0644)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	live := NewLive(cfg)
+	live.SetMany([][2]string{{"timeout", "45"}, {"requirepass", "a b"}})
+	if err := live.Rewrite(); err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := "# my server\nport 7000\ntimeout 45\n\n# dup\n\n# Generated by CONFIG REWRITE\nrequirepass \"a b\"\n"
+	if string(data) != want {
+		t.Errorf("Unexpected rewritten file:\n%s", data)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("reloading the rewritten file failed: %v", err)
+	}
+	if reloaded.Timeout != 45 || reloaded.RequirePass != "a b" || !strings.HasSuffix(reloaded.File, "gokv.conf") {
+		t.Errorf("Unexpected reloaded config %+v", reloaded)
+	}
+}
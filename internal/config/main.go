@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/logger"
+	"github.com/B-AJ-Amar/gokv/internal/persistence"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// Config is the server configuration, built from the defaults, then the
+// config file, then the command line flags
+type Config struct {
+	Port            int
+	Bind            string
+	Databases       int
+	Timeout         int // seconds a client can stay idle, 0 to never close it
+	MaxClients      int
+	MaxMemory       int64 // bytes, 0 for no limit
+	MaxMemPolicy    string
+	RequirePass     string
+	Dir             string // working directory for the persistence files
+	DBFilename      string // empty disables snapshots
+	AppendOnly      bool
+	AppendFilename  string
+	AppendFsync     string
+	LogLevel        string
+	SlowlogSlower   int64 // microseconds, negative disables the slowlog
+	SlowlogMaxLen   int
+	SaveOnShutdown  bool  // snapshot on SIGINT/SIGTERM and plain SHUTDOWN
+	ProtoMaxBulkLen int64 // max size of one argument of a request, bytes
+
+	// file the config was loaded from, empty when started without one
+	File string
+}
+
+func Default() *Config {
+	return &Config{
+		Port:            common.DefaultPort,
+		Bind:            common.DefaultBind,
+		Databases:       common.DefaultDatabases,
+		MaxClients:      common.DefaultMaxClients,
+		MaxMemPolicy:    common.DefaultMaxMemPolicy,
+		Dir:             ".",
+		DBFilename:      common.DefaultSnapshotFile,
+		AppendFilename:  common.DefaultAOFFile,
+		AppendFsync:     common.DefaultAppendFsync,
+		LogLevel:        common.DefaultLogLevel,
+		SlowlogSlower:   common.DefaultSlowlogSlower,
+		SlowlogMaxLen:   common.DefaultSlowlogMaxLen,
+		ProtoMaxBulkLen: common.DefaultProtoMaxBulkLen,
+	}
+}
+
+// Validate checks the values that the setters can not check on their own
+func (c *Config) Validate() error {
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("port %d: %w", c.Port, common.ErrInvalidConfigValue)
+	}
+	if c.Databases < 1 || c.Databases > common.MaxDatabases {
+		return fmt.Errorf("databases %d: %w", c.Databases, common.ErrInvalidConfigValue)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout %d: %w", c.Timeout, common.ErrInvalidConfigValue)
+	}
+	if c.MaxClients < 1 {
+		return fmt.Errorf("maxclients %d: %w", c.MaxClients, common.ErrInvalidConfigValue)
+	}
+	if c.MaxMemory < 0 {
+		return fmt.Errorf("maxmemory %d: %w", c.MaxMemory, common.ErrInvalidConfigValue)
+	}
+	if !store.ValidEvictionPolicy(c.MaxMemPolicy) {
+		return common.ErrInvalidEvictionPolicy
+	}
+	if c.SlowlogMaxLen < 0 {
+		return fmt.Errorf("slowlog-max-len %d: %w", c.SlowlogMaxLen, common.ErrInvalidConfigValue)
+	}
+	if c.ProtoMaxBulkLen < 1<<20 {
+		return fmt.Errorf("proto-max-bulk-len %d, at least 1mb: %w", c.ProtoMaxBulkLen, common.ErrInvalidConfigValue)
+	}
+	if !persistence.ValidFsyncPolicy(c.AppendFsync) {
+		return common.ErrInvalidFsyncPolicy
+	}
+	if _, err := logger.ParseLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if c.AppendOnly && c.AppendFilename == "" {
+		return fmt.Errorf("empty appendfilename: %w", common.ErrInvalidConfigValue)
+	}
+	info, err := os.Stat(c.Dir)
+	if err != nil {
+		return fmt.Errorf("dir %q: %w", c.Dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("dir %q is not a directory: %w", c.Dir, common.ErrInvalidConfigValue)
+	}
+	return nil
+}
+
+func (c *Config) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Bind, c.Port)
+}
+
+func (c *Config) SnapshotPath() string {
+	return filepath.Join(c.Dir, c.DBFilename)
+}
+
+func (c *Config) AOFPath() string {
+	return filepath.Join(c.Dir, c.AppendFilename)
+}
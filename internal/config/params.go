@@ -0,0 +1,263 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+// every parameter is read and written as a string, the same way it shows up
+// in the config file and on the command line
+type param struct {
+	name    string
+	usage   string
+	mutable bool // can be changed at runtime with CONFIG SET
+	get     func(c *Config) string
+	set     func(c *Config, v string) error
+}
+
+var params = []param{
+	{
+		name:  "port",
+		usage: "TCP port to listen on (0 picks a free one)",
+		get:   func(c *Config) string { return strconv.Itoa(c.Port) },
+		set:   func(c *Config, v string) error { return setInt(&c.Port, v) },
+	},
+	{
+		name:  "bind",
+		usage: "interface to listen on, empty for all",
+		get:   func(c *Config) string { return c.Bind },
+		set:   func(c *Config, v string) error { c.Bind = v; return nil },
+	},
+	{
+		name:  "databases",
+		usage: "number of logical databases",
+		get:   func(c *Config) string { return strconv.Itoa(c.Databases) },
+		set:   func(c *Config, v string) error { return setInt(&c.Databases, v) },
+	},
+	{
+		name:    "timeout",
+		mutable: true,
+		usage:   "close a client after it is idle for N seconds (0 to disable)",
+		get:     func(c *Config) string { return strconv.Itoa(c.Timeout) },
+		set:     func(c *Config, v string) error { return setInt(&c.Timeout, v) },
+	},
+	{
+		name:    "maxclients",
+		mutable: true,
+		usage:   "max number of connected clients",
+		get:     func(c *Config) string { return strconv.Itoa(c.MaxClients) },
+		set:     func(c *Config, v string) error { return setInt(&c.MaxClients, v) },
+	},
+	{
+		name:    "maxmemory",
+		mutable: true,
+		usage:   "memory limit for the data, accepts units like 100mb (0 for no limit)",
+		get:     func(c *Config) string { return strconv.FormatInt(c.MaxMemory, 10) },
+		set: func(c *Config, v string) error {
+			n, err := ParseMemory(v)
+			if err != nil {
+				return err
+			}
+			c.MaxMemory = n
+			return nil
+		},
+	},
+	{
+		name:    "maxmemory-policy",
+		mutable: true,
+		usage:   "what to do when maxmemory is reached: noeviction, allkeys-random, volatile-random or volatile-ttl",
+		get:     func(c *Config) string { return c.MaxMemPolicy },
+		set:     func(c *Config, v string) error { c.MaxMemPolicy = strings.ToLower(v); return nil },
+	},
+	{
+		name:    "requirepass",
+		mutable: true,
+		usage:   "password clients must AUTH with",
+		get:     func(c *Config) string { return c.RequirePass },
+		set:     func(c *Config, v string) error { c.RequirePass = v; return nil },
+	},
+	{
+		name:  "dir",
+		usage: "working directory for the snapshot and append only files",
+		get:   func(c *Config) string { return c.Dir },
+		set:   func(c *Config, v string) error { c.Dir = v; return nil },
+	},
+	{
+		name:  "dbfilename",
+		usage: "snapshot file name, empty to disable snapshots",
+		get:   func(c *Config) string { return c.DBFilename },
+		set:   func(c *Config, v string) error { c.DBFilename = v; return nil },
+	},
+	{
+		name:  "appendonly",
+		usage: "enable the append only file (yes/no)",
+		get:   func(c *Config) string { return formatBool(c.AppendOnly) },
+		set:   func(c *Config, v string) error { return setBool(&c.AppendOnly, v) },
+	},
+	{
+		name:  "appendfilename",
+		usage: "append only file name",
+		get:   func(c *Config) string { return c.AppendFilename },
+		set:   func(c *Config, v string) error { c.AppendFilename = v; return nil },
+	},
+	{
+		name:    "appendfsync",
+		mutable: true,
+		usage:   "AOF fsync policy: always, everysec or no",
+		get:     func(c *Config) string { return c.AppendFsync },
+		set:     func(c *Config, v string) error { c.AppendFsync = strings.ToLower(v); return nil },
+	},
+	{
+		name:    "loglevel",
+		mutable: true,
+		usage:   "debug, verbose, notice or warning",
+		get:     func(c *Config) string { return c.LogLevel },
+		set:     func(c *Config, v string) error { c.LogLevel = strings.ToLower(v); return nil },
+	},
+	{
+		name:    "slowlog-log-slower-than",
+		mutable: true,
+		usage:   "log commands slower than N microseconds (negative to disable, 0 logs everything)",
+		get:     func(c *Config) string { return strconv.FormatInt(c.SlowlogSlower, 10) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return common.ErrInvalidConfigValue
+			}
+			c.SlowlogSlower = n
+			return nil
+		},
+	},
+	{
+		name:    "slowlog-max-len",
+		mutable: true,
+		usage:   "number of entries kept in the slowlog",
+		get:     func(c *Config) string { return strconv.Itoa(c.SlowlogMaxLen) },
+		set:     func(c *Config, v string) error { return setInt(&c.SlowlogMaxLen, v) },
+	},
+	{
+		name:    "save-on-shutdown",
+		mutable: true,
+		usage:   "write a snapshot when stopped by a signal or a plain SHUTDOWN (yes/no)",
+		get:     func(c *Config) string { return formatBool(c.SaveOnShutdown) },
+		set:     func(c *Config, v string) error { return setBool(&c.SaveOnShutdown, v) },
+	},
+	{
+		name:    "proto-max-bulk-len",
+		mutable: true,
+		usage:   "max size of a single argument sent by a client, accepts units like 512mb (at least 1mb)",
+		get:     func(c *Config) string { return strconv.FormatInt(c.ProtoMaxBulkLen, 10) },
+		set: func(c *Config, v string) error {
+			n, err := ParseMemory(v)
+			if err != nil {
+				return err
+			}
+			c.ProtoMaxBulkLen = n
+			return nil
+		},
+	},
+}
+
+func findParam(name string) *param {
+	name = strings.ToLower(name)
+	for i := range params {
+		if params[i].name == name {
+			return &params[i]
+		}
+	}
+	return nil
+}
+
+// Names returns every parameter name in declaration order
+func Names() []string {
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.name
+	}
+	return names
+}
+
+func Usage(name string) string {
+	if p := findParam(name); p != nil {
+		return p.usage
+	}
+	return ""
+}
+
+func (c *Config) Get(name string) (string, error) {
+	p := findParam(name)
+	if p == nil {
+		return "", common.ErrUnknownConfigParam
+	}
+	return p.get(c), nil
+}
+
+// Set parses and stores one parameter, range checks are left to Validate
+func (c *Config) Set(name, value string) error {
+	p := findParam(name)
+	if p == nil {
+		return fmt.Errorf("'%s': %w", name, common.ErrUnknownConfigParam)
+	}
+	if err := p.set(c, value); err != nil {
+		return fmt.Errorf("'%s %s': %w", name, value, err)
+	}
+	return nil
+}
+
+func setInt(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return common.ErrInvalidConfigValue
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, v string) error {
+	switch strings.ToLower(v) {
+	case "yes":
+		*dst = true
+	case "no":
+		*dst = false
+	default:
+		return common.ErrInvalidConfigValue
+	}
+	return nil
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// ParseMemory understands the redis memory units:
+// 1k => 1000 bytes, 1kb => 1024 bytes, same for m/mb and g/gb
+func ParseMemory(v string) (int64, error) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	units := []struct {
+		suffix string
+		mul    int64
+	}{
+		{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+		{"b", 1},
+	}
+	mul := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(v, u.suffix) {
+			mul = u.mul
+			v = strings.TrimSuffix(v, u.suffix)
+			break
+		}
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, common.ErrInvalidConfigValue
+	}
+	return n * mul, nil
+}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+// levels follow the redis "loglevel" names, from the most to the least verbose
+const (
+	LevelDebug = iota
+	LevelVerbose
+	LevelNotice
+	LevelWarning
+)
+
+var levelNames = [...]string{"debug", "verbose", "notice", "warning"}
+
+var level atomic.Int32
+
+func init() {
+	level.Store(LevelNotice)
+}
+
+func ParseLevel(name string) (int, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return i, nil
+		}
+	}
+	return 0, common.ErrInvalidLogLevel
+}
+
+func LevelName(l int) string {
+	return levelNames[l]
+}
+
+func SetLevel(l int) {
+	level.Store(int32(l))
+}
+
+func GetLevel() int {
+	return int(level.Load())
+}
+
+func logf(l int, format string, args ...any) {
+	if int32(l) < level.Load() {
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func Debugf(format string, args ...any)   { logf(LevelDebug, format, args...) }
+func Verbosef(format string, args ...any) { logf(LevelVerbose, format, args...) }
+func Noticef(format string, args ...any)  { logf(LevelNotice, format, args...) }
+func Warningf(format string, args ...any) { logf(LevelWarning, format, args...) }
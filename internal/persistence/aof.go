@@ -0,0 +1,464 @@
+package persistence
+
+import (
+	"bufio"
+	"errors"
+	"hash/maphash"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/logger"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// appendfsync policies
+const (
+	FsyncAlways   = "always"   // fsync after every write command
+	FsyncEverySec = "everysec" // fsync once per second in the background
+	FsyncNo       = "no"       // leave it to the OS
+)
+
+func ValidFsyncPolicy(policy string) bool {
+	return policy == FsyncAlways || policy == FsyncEverySec || policy == FsyncNo
+}
+
+// AOF is the append only file: every write command is appended in RESP form,
+// preceded by a SELECT whenever the database changes
+const keyStripes = 256
+
+var keySeed = maphash.MakeSeed()
+
+type AOF struct {
+	// write commands hold it shared from execution until they are appended,
+	// a rewrite holds it exclusively while copying the data so a command is
+	// either part of the copy or of the rewrite buffer, never both
+	barrier sync.RWMutex
+	// striped by key, see WriteBegin
+	keys [keyStripes]sync.Mutex
+
+	mu     sync.Mutex
+	path   string
+	policy string
+	f      *os.File
+	w      *bufio.Writer
+	curDB  int  // database of the last appended command, -1 forces a SELECT
+	dirty  bool // written but not fsynced yet (everysec)
+
+	rewriting  bool
+	rewriteBuf []byte // commands appended while a rewrite is running
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func OpenAOF(path string, policy string) (*AOF, error) {
+	if !ValidFsyncPolicy(policy) {
+		return nil, common.ErrInvalidFsyncPolicy
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	a := &AOF{
+		path:   path,
+		policy: policy,
+		f:      f,
+		w:      bufio.NewWriter(f),
+		curDB:  -1,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go a.syncLoop()
+	return a, nil
+}
+
+func (a *AOF) Path() string {
+	return a.path
+}
+
+func (a *AOF) SetPolicy(policy string) error {
+	if !ValidFsyncPolicy(policy) {
+		return common.ErrInvalidFsyncPolicy
+	}
+	a.mu.Lock()
+	a.policy = policy
+	a.mu.Unlock()
+	return nil
+}
+
+// WriteBegin must be called before executing a write command that will be
+// appended, the returned func once it was. the store releases its locks
+// before the command is appended, so WriteBegin also locks the keys of the
+// command in db: two commands writing a key are appended in the order they
+// ran.
+func (a *AOF) WriteBegin(db int, keys []string) func() {
+	a.barrier.RLock()
+
+	stripes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		h := maphash.String(keySeed, key)
+		stripes = append(stripes, int((h+uint64(db))%keyStripes))
+	}
+	// in ascending order, like the shard locks, so writers can't deadlock
+	slices.Sort(stripes)
+	stripes = slices.Compact(stripes)
+	for _, i := range stripes {
+		a.keys[i].Lock()
+	}
+	return func() {
+		for j := len(stripes) - 1; j >= 0; j-- {
+			a.keys[stripes[j]].Unlock()
+		}
+		a.barrier.RUnlock()
+	}
+}
+
+// Append logs one write command executed against database db
+func (a *AOF) Append(db int, args []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	buf := []byte{}
+	if db != a.curDB {
+		buf = appendCommand(buf, []string{"SELECT", strconv.Itoa(db)})
+		a.curDB = db
+	}
+	buf = appendCommand(buf, args)
+
+	if a.rewriting {
+		a.rewriteBuf = append(a.rewriteBuf, buf...)
+	}
+	if _, err := a.w.Write(buf); err != nil {
+		return err
+	}
+	// always hand the data to the OS, the policy only decides on fsync
+	if err := a.w.Flush(); err != nil {
+		return err
+	}
+	switch a.policy {
+	case FsyncAlways:
+		return a.f.Sync()
+	case FsyncEverySec:
+		a.dirty = true
+	}
+	return nil
+}
+
+func (a *AOF) syncLoop() {
+	defer close(a.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			if a.dirty {
+				if err := a.f.Sync(); err != nil {
+					logger.Warningf("aof fsync failed: %v", err)
+				}
+				a.dirty = false
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+// Close flushes and fsyncs whatever is pending and closes the file
+func (a *AOF) Close() error {
+	close(a.stop)
+	<-a.done
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.w.Flush(); err != nil {
+		return err
+	}
+	if err := a.f.Sync(); err != nil {
+		return err
+	}
+	return a.f.Close()
+}
+
+// Load replays the file by calling fn for every logged command, including
+// the SELECTs. when the file ends in the middle of a command (the server
+// died mid write) the incomplete tail is truncated and loading succeeds,
+// corruption anywhere else is an error. it returns the number of commands.
+func (a *AOF) Load(fn func(args []string) error) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := a.f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	info, err := a.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	r := &countingReader{r: a.f}
+	br := bufio.NewReader(r)
+
+	count := 0
+	validOffset := int64(0)
+	for {
+		args, err := readCommand(br, info.Size()-validOffset)
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			logger.Warningf("aof %s: truncated command at offset %d, discarding the tail", a.path, validOffset)
+			if err := a.f.Truncate(validOffset); err != nil {
+				return count, err
+			}
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		if err := fn(args); err != nil {
+			return count, err
+		}
+		count++
+		validOffset = r.n - int64(br.Buffered())
+	}
+	// appends must start on a clean SELECT
+	a.curDB = -1
+	return count, nil
+}
+
+// Rewrite compacts the log into the smallest set of commands rebuilding the
+// current state of dbs. the data is copied at a point in time, writers are
+// held off by the barrier meanwhile, and written to a temp file in the
+// background. commands appended meanwhile are buffered and added to the new
+// file before it replaces the old one.
+func (a *AOF) Rewrite(dbs []*store.InMemoryStore) error {
+	a.barrier.Lock()
+	defer a.barrier.Unlock()
+	a.mu.Lock()
+	if a.rewriting {
+		a.mu.Unlock()
+		return common.ErrAOFRewriteInProgress
+	}
+	a.rewriting = true
+	a.rewriteBuf = nil
+	// the buffered commands will follow the rewritten data, make sure they
+	// start with their own SELECT
+	a.curDB = -1
+	entries := store.SnapshotAll(dbs)
+	a.mu.Unlock()
+
+	go func() {
+		if err := a.rewrite(entries); err != nil {
+			logger.Warningf("aof rewrite failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (a *AOF) RewriteInProgress() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rewriting
+}
+
+func (a *AOF) rewrite(dbs [][]store.Entry) error {
+	tmpPath := a.path + ".rewrite"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		a.abortRewrite()
+		return err
+	}
+	if err := writeRewrite(tmp, dbs); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		a.abortRewrite()
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rewriting = false
+
+	// the commands that arrived while the data was being written
+	_, err = tmp.Write(a.rewriteBuf)
+	a.rewriteBuf = nil
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, a.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// from now on append to the new file
+	a.w.Flush()
+	a.f.Close()
+	a.f = tmp
+	a.w = bufio.NewWriter(tmp)
+	return nil
+}
+
+func (a *AOF) abortRewrite() {
+	a.mu.Lock()
+	a.rewriting = false
+	a.rewriteBuf = nil
+	a.mu.Unlock()
+}
+
+// writeRewrite emits one SET per string key, with an absolute PXAT when it
+// has a ttl. the other types are rebuilt with their write command, batched
+// by rewriteBatch items, followed by a PEXPIREAT for the ttl.
+func writeRewrite(f *os.File, dbs [][]store.Entry) error {
+	w := bufio.NewWriter(f)
+	buf := []byte{}
+	for i, entries := range dbs {
+		if len(entries) == 0 {
+			continue
+		}
+		buf = appendCommand(buf[:0], []string{"SELECT", strconv.Itoa(i)})
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			for _, args := range rewriteCommands(e) {
+				buf = appendCommand(buf[:0], args)
+				if _, err := w.Write(buf); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// items per command when rebuilding aggregate types, big values are split so
+// that loading them never needs a huge command
+const rewriteBatch = 64
+
+func rewriteCommands(e store.Entry) [][]string {
+	if e.Type == store.TypeString {
+		args := []string{"SET", e.Key, string(e.Value)}
+		if e.Exp != -1 {
+			args = append(args, "PXAT", strconv.FormatInt(e.Exp, 10))
+		}
+		return [][]string{args}
+	}
+
+	var name string
+	var step int
+	switch e.Type {
+	case store.TypeHash:
+		name, step = "HSET", 2
+	case store.TypeList:
+		name, step = "RPUSH", 1
+	case store.TypeSet:
+		name, step = "SADD", 1
+	case store.TypeZSet:
+		name, step = "ZADD", 2
+	default:
+		return nil
+	}
+	var cmds [][]string
+	for i := 0; i < len(e.Items); i += rewriteBatch * step {
+		args := []string{name, e.Key}
+		for _, item := range e.Items[i:min(i+rewriteBatch*step, len(e.Items))] {
+			args = append(args, string(item))
+		}
+		cmds = append(cmds, args)
+	}
+	if e.Exp != -1 && len(cmds) > 0 {
+		cmds = append(cmds, []string{"PEXPIREAT", e.Key, strconv.FormatInt(e.Exp, 10)})
+	}
+	return cmds
+}
+
+func appendCommand(buf []byte, args []string) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// readCommand reads one RESP array of bulk strings, left is how many bytes
+// the file has from the start of the command. io.EOF means a clean end of
+// file, io.ErrUnexpectedEOF a command cut in the middle. lengths are checked
+// against left before anything is allocated, so a corrupt length can't ask
+// for more memory than the file holds.
+func readCommand(br *bufio.Reader, left int64) ([]string, error) {
+	line, err := br.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if len(line) < 3 || line[0] != '*' {
+		return nil, common.ErrAOFCorrupted
+	}
+	n, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil || n < 0 || n > common.MaxMultibulkLen {
+		return nil, common.ErrAOFCorrupted
+	}
+	left -= int64(len(line))
+	// the shortest argument is $0\r\n\r\n
+	if int64(n)*6 > left {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if len(line) < 3 || line[0] != '$' {
+			return nil, common.ErrAOFCorrupted
+		}
+		size, err := strconv.Atoi(line[1 : len(line)-2])
+		if err != nil || size < 0 {
+			return nil, common.ErrAOFCorrupted
+		}
+		left -= int64(len(line))
+		if int64(size)+2 > left {
+			return nil, io.ErrUnexpectedEOF
+		}
+		left -= int64(size) + 2
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if data[size] != '\r' || data[size+1] != '\n' {
+			return nil, common.ErrAOFCorrupted
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
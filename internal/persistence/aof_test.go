@@ -0,0 +1,173 @@
+package persistence
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func loadAll(t *testing.T, path string) [][]string {
+	t.Helper()
+	aof, err := OpenAOF(path, FsyncNo)
+	if err != nil {
+		t.Fatalf("OpenAOF failed: %v", err)
+	}
+	defer aof.Close()
+	cmds := [][]string{}
+	if _, err := aof.Load(func(args []string) error {
+		cmds = append(cmds, args)
+		return nil
+	}); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return cmds
+}
+
+func TestAOFAppendAndLoad(t *testing.T) {
+	for _, policy := range []string{FsyncAlways, FsyncEverySec, FsyncNo} {
+		path := filepath.Join(t.TempDir(), "appendonly.aof")
+		aof, err := OpenAOF(path, policy)
+		if err != nil {
+			t.Fatalf("OpenAOF failed: %v", err)
+		}
+		aof.Append(0, []string{"set", "a", "1"})
+		aof.Append(0, []string{"incr", "a"})
+		aof.Append(3, []string{"set", "bin", "x\r\ny"})
+		if err := aof.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		want := [][]string{
+			{"SELECT", "0"}, {"set", "a", "1"}, {"incr", "a"},
+			{"SELECT", "3"}, {"set", "bin", "x\r\ny"},
+		}
+		if got := loadAll(t, path); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %q, got %q", policy, want, got)
+		}
+	}
+}
+
+func TestAOFTruncatedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, _ := OpenAOF(path, FsyncAlways)
+	aof.Append(0, []string{"set", "a", "1"})
+	aof.Close()
+
+	full, _ := os.ReadFile(path)
+	partial := "*3\r\n$3\r\nset\r\n$1\r\nb\r\n$5\r\nab"
+	os.WriteFile(path, append(full, partial...), 0644)
+
+	if got := loadAll(t, path); len(got) != 2 {
+		t.Fatalf("Expected the 2 complete commands, got %q", got)
+	}
+	after, _ := os.ReadFile(path)
+	if string(after) != string(full) {
+		t.Errorf("Expected the incomplete tail to be truncated, got %q", after)
+	}
+}
+
+// lengths past the end of the file are a cut tail, and are not allocated
+func TestAOFHugeLengths(t *testing.T) {
+	for _, tail := range []string{"*2\r\n$3\r\nget\r\n$4000000000\r\nab", "*900000\r\n$4\r\nPING\r\n"} {
+		path := filepath.Join(t.TempDir(), "appendonly.aof")
+		os.WriteFile(path, []byte("*1\r\n$4\r\nPING\r\n"+tail), 0644)
+		if got := loadAll(t, path); len(got) != 1 {
+			t.Errorf("Expected the complete command only, got %q", got)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	os.WriteFile(path, []byte("*99999999\r\n$4\r\nPING\r\n"), 0644)
+	aof, _ := OpenAOF(path, FsyncNo)
+	defer aof.Close()
+	if _, err := aof.Load(func(args []string) error { return nil }); !errors.Is(err, common.ErrAOFCorrupted) {
+		t.Errorf("Expected an array past the protocol limit to be corrupted, got %v", err)
+	}
+}
+
+func TestAOFCorruptedMiddle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	os.WriteFile(path, []byte("*1\r\n$4\r\nPING\r\ngarbage\r\n*1\r\n$4\r\nPING\r\n"), 0644)
+
+	aof, _ := OpenAOF(path, FsyncNo)
+	defer aof.Close()
+	_, err := aof.Load(func(args []string) error { return nil })
+	if err == nil {
+		t.Errorf("Expected an error on a corrupted file")
+	}
+}
+
+func TestAOFRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, _ := OpenAOF(path, FsyncNo)
+
+	dbs := store.NewInMemoryStoreArray(2)
+	for i := 0; i < 10; i++ {
+		dbs[0].Set("a", []byte(strings.Repeat("x", i)))
+		aof.Append(0, []string{"set", "a", strings.Repeat("x", i)})
+	}
+	exp := time.Now().Add(time.Hour).UnixMilli()
+	dbs[1].Restore(store.Entry{Key: "t", Value: []byte("v"), Exp: exp})
+	dbs[1].HSet("h", []string{"f"}, [][]byte{[]byte("v")})
+	dbs[1].ExpireAt("h", exp)
+	dbs[1].ZAdd("z", store.ZAddArgs{}, []store.ScoredMember{{Member: "b", Score: 2.5}, {Member: "a", Score: 1}})
+
+	if err := aof.Rewrite(dbs); err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	aof.Append(1, []string{"del", "t"})
+	for aof.RewriteInProgress() {
+		time.Sleep(time.Millisecond)
+	}
+	aof.Append(0, []string{"set", "b", "2"})
+	aof.Close()
+
+	want := [][]string{
+		{"SELECT", "0"}, {"SET", "a", "xxxxxxxxx"},
+		{"SELECT", "1"}, {"SET", "t", "v", "PXAT", strconv.FormatInt(exp, 10)},
+		{"ZADD", "z", "1", "a", "2.5", "b"},
+		{"HSET", "h", "f", "v"}, {"PEXPIREAT", "h", strconv.FormatInt(exp, 10)},
+		// issued while the rewrite was running
+		{"SELECT", "1"}, {"del", "t"},
+		// issued after the new file replaced the old one
+		{"SELECT", "0"}, {"set", "b", "2"},
+	}
+	if got := loadAll(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected compacted log %q, got %q", want, got)
+	}
+}
+
+// a write to a key waits for the previous one to be appended, so the log
+// keeps the order the commands ran in
+func TestAOFWriteBeginOrdersKeys(t *testing.T) {
+	aof, _ := OpenAOF(filepath.Join(t.TempDir(), "appendonly.aof"), FsyncNo)
+	defer aof.Close()
+
+	end := aof.WriteBegin(0, []string{"a", "b"})
+	same := make(chan struct{})
+	go func() {
+		aof.WriteBegin(0, []string{"b", "b"})()
+		close(same)
+	}()
+	select {
+	case <-same:
+		t.Fatalf("Expected a write to the same key to wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	end()
+	<-same
+}
+
+func TestOpenAOFInvalidPolicy(t *testing.T) {
+	if _, err := OpenAOF(filepath.Join(t.TempDir(), "a.aof"), "sometimes"); err == nil {
+		t.Errorf("Expected invalid policy error")
+	}
+}
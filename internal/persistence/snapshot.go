@@ -0,0 +1,251 @@
+package persistence
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash"
+	"hash/crc64"
+	"io"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// snapshot file layout (all varints are encoding/binary varints):
+//
+//	"GOKVSNAP"            8 bytes magic
+//	version               uint16 big endian
+//	opDB dbIndex count    one section per non empty database
+//	  keyLen key type value exp(signed, -1 for no ttl)   * count
+//	opEOF
+//	crc64(ECMA)           uint64 big endian, of every byte before it
+//
+// type is a byte holding the store.ValueType of the entry. a string value is
+// valLen val, any other type is itemCount followed by itemLen item for each
+// item of store.Entry.Items.
+
+const (
+	snapshotMagic   = "GOKVSNAP"
+	SnapshotVersion = 2
+
+	opDB  = 0xFE
+	opEOF = 0xFF
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+func EncodeSnapshot(w io.Writer, dbs [][]store.Entry) error {
+	crc := crc64.New(crcTable)
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	enc := encoder{w: bw}
+
+	enc.raw([]byte(snapshotMagic))
+	enc.raw(binary.BigEndian.AppendUint16(nil, SnapshotVersion))
+	for i, entries := range dbs {
+		if len(entries) == 0 {
+			continue
+		}
+		enc.raw([]byte{opDB})
+		enc.uvarint(uint64(i))
+		enc.uvarint(uint64(len(entries)))
+		for _, e := range entries {
+			enc.bytes([]byte(e.Key))
+			enc.raw([]byte{byte(e.Type)})
+			if e.Type == store.TypeString {
+				enc.bytes(e.Value)
+			} else {
+				enc.uvarint(uint64(len(e.Items)))
+				for _, item := range e.Items {
+					enc.bytes(item)
+				}
+			}
+			enc.varint(e.Exp)
+		}
+	}
+	enc.raw([]byte{opEOF})
+	if enc.err != nil {
+		return enc.err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	// the checksum itself is not part of the checksum
+	_, err := w.Write(binary.BigEndian.AppendUint64(nil, crc.Sum64()))
+	return err
+}
+
+// DecodeSnapshot reads a snapshot of size bytes written by EncodeSnapshot.
+// nothing is returned unless the checksum matches, so a partial file never
+// gets loaded.
+func DecodeSnapshot(r io.Reader, size int64, maxDBs int) ([][]store.Entry, error) {
+	crc := crc64.New(crcTable)
+	br := bufio.NewReader(r)
+	dec := decoder{r: br, crc: crc, left: size}
+
+	magic := dec.raw(len(snapshotMagic))
+	if dec.err != nil || string(magic) != snapshotMagic {
+		return nil, common.ErrSnapshotBadMagic
+	}
+	version := binary.BigEndian.Uint16(dec.raw(2))
+	if dec.err != nil {
+		return nil, common.ErrSnapshotCorrupted
+	}
+	if version != SnapshotVersion {
+		return nil, common.ErrSnapshotVersion
+	}
+
+	dbs := make([][]store.Entry, maxDBs)
+	for {
+		op := dec.raw(1)
+		if dec.err != nil {
+			return nil, common.ErrSnapshotCorrupted
+		}
+		if op[0] == opEOF {
+			break
+		}
+		if op[0] != opDB {
+			return nil, common.ErrSnapshotCorrupted
+		}
+
+		idx := dec.uvarint()
+		count := dec.uvarint()
+		if dec.err != nil {
+			return nil, common.ErrSnapshotCorrupted
+		}
+		if idx >= uint64(maxDBs) {
+			return nil, common.ErrSnapshotDBOutOfRange
+		}
+		// an entry takes at least 4 bytes, a count the rest of the file
+		// can't hold is corrupted, not something to allocate
+		if count > uint64(dec.left)/4 {
+			return nil, common.ErrSnapshotCorrupted
+		}
+		entries := make([]store.Entry, 0, count)
+		for j := uint64(0); j < count; j++ {
+			e := store.Entry{Key: string(dec.bytes())}
+			e.Type = store.ValueType(dec.raw(1)[0])
+			if e.Type == store.TypeString {
+				e.Value = dec.bytes()
+			} else {
+				n := dec.uvarint()
+				if n > uint64(dec.left) {
+					return nil, common.ErrSnapshotCorrupted
+				}
+				e.Items = make([][]byte, 0, n)
+				for k := uint64(0); k < n && dec.err == nil; k++ {
+					e.Items = append(e.Items, dec.bytes())
+				}
+			}
+			e.Exp = dec.varint()
+			if dec.err != nil {
+				return nil, common.ErrSnapshotCorrupted
+			}
+			entries = append(entries, e)
+		}
+		dbs[idx] = append(dbs[idx], entries...)
+	}
+
+	want := crc.Sum64()
+	var sum [8]byte
+	if _, err := io.ReadFull(br, sum[:]); err != nil {
+		return nil, common.ErrSnapshotCorrupted
+	}
+	if binary.BigEndian.Uint64(sum[:]) != want {
+		return nil, common.ErrSnapshotChecksum
+	}
+	return dbs, nil
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) raw(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) uvarint(v uint64) {
+	e.raw(binary.AppendUvarint(nil, v))
+}
+
+func (e *encoder) varint(v int64) {
+	e.raw(binary.AppendVarint(nil, v))
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.raw(b)
+}
+
+// decoder feeds every byte it consumes into the running checksum, and keeps
+// track of how many are left so corrupted lengths fail before allocating
+type decoder struct {
+	r    *bufio.Reader
+	crc  hash.Hash64
+	left int64
+	err  error
+}
+
+func (d *decoder) raw(n int) []byte {
+	if d.err == nil && int64(n) > d.left {
+		d.err = common.ErrSnapshotCorrupted
+	}
+	if d.err != nil {
+		return make([]byte, n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.err = err
+		return b
+	}
+	d.left -= int64(n)
+	d.crc.Write(b)
+	return b
+}
+
+func (d *decoder) ReadByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	d.left--
+	d.crc.Write([]byte{b})
+	return b, nil
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d)
+	if err != nil {
+		d.err = err
+	}
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d)
+	if err != nil {
+		d.err = err
+	}
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(d.left) {
+		d.err = common.ErrSnapshotCorrupted
+		return nil
+	}
+	return d.raw(int(n))
+}
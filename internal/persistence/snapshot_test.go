@@ -0,0 +1,185 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc64"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	exp := time.Now().Add(time.Hour).UnixMilli()
+	dbs := [][]store.Entry{
+		{{Key: "a", Value: []byte("1"), Exp: -1}, {Key: "bin", Value: []byte("x\r\ny\x00"), Exp: exp}},
+		nil,
+		{{Key: "empty", Value: []byte{}, Exp: -1}, {Key: "h", Type: store.TypeHash, Items: [][]byte{[]byte("f"), []byte("v")}, Exp: exp}},
+		{{Key: "l", Type: store.TypeList, Items: [][]byte{[]byte("a"), []byte("b"), []byte("a")}, Exp: -1}, {Key: "s", Type: store.TypeSet, Items: [][]byte{[]byte("1"), []byte("2")}, Exp: -1}},
+		{{Key: "z", Type: store.TypeZSet, Items: [][]byte{[]byte("1.5"), []byte("a"), []byte("inf"), []byte("b")}, Exp: exp}},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeSnapshot(&buf, dbs); err != nil {
+		t.Fatalf("EncodeSnapshot failed: %v", err)
+	}
+	got, err := DecodeSnapshot(bytes.NewReader(buf.Bytes()), int64(buf.Len()), 5)
+	if err != nil {
+		t.Fatalf("DecodeSnapshot failed: %v", err)
+	}
+	for i := range dbs {
+		if len(got[i]) != len(dbs[i]) {
+			t.Fatalf("db %d: expected %d entries, got %d", i, len(dbs[i]), len(got[i]))
+		}
+		for j, e := range dbs[i] {
+			g := got[i][j]
+			if g.Key != e.Key || g.Type != e.Type || !bytes.Equal(g.Value, e.Value) || !reflect.DeepEqual(g.Items, e.Items) || g.Exp != e.Exp {
+				t.Errorf("db %d: expected %+v, got %+v", i, e, g)
+			}
+		}
+	}
+}
+
+// lengths the rest of the file can't hold are corrupted, and are not allocated
+func TestSnapshotHugeLengths(t *testing.T) {
+	for _, body := range [][]byte{
+		{opDB, 0, 1, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F},                               // key of 4GiB
+		{opDB, 0, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F},                                  // 4G entries
+		{opDB, 0, 1, 1, 'k', byte(store.TypeList), 0xFF, 0xFF, 0xFF, 0xFF, 0x0F}, // 4G items
+	} {
+		raw := append([]byte(snapshotMagic), 0, SnapshotVersion)
+		raw = append(raw, body...)
+		raw = binary.BigEndian.AppendUint64(raw, crc64.Checksum(raw, crcTable))
+		if _, err := DecodeSnapshot(bytes.NewReader(raw), int64(len(raw)), 1); !errors.Is(err, common.ErrSnapshotCorrupted) {
+			t.Errorf("%x: expected corrupted error, got %v", body, err)
+		}
+	}
+}
+
+func TestSnapshotRejectsCorruption(t *testing.T) {
+	var buf bytes.Buffer
+	EncodeSnapshot(&buf, [][]store.Entry{{{Key: "k", Value: []byte("value"), Exp: -1}}})
+	raw := buf.Bytes()
+
+	flipped := append([]byte{}, raw...)
+	flipped[len(flipped)-12] ^= 0xFF
+	if _, err := DecodeSnapshot(bytes.NewReader(flipped), int64(len(flipped)), 1); !errors.Is(err, common.ErrSnapshotChecksum) {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+
+	if _, err := DecodeSnapshot(bytes.NewReader(raw[:len(raw)-3]), int64(len(raw)-3), 1); !errors.Is(err, common.ErrSnapshotCorrupted) {
+		t.Errorf("Expected corrupted error on truncated file, got %v", err)
+	}
+
+	if _, err := DecodeSnapshot(bytes.NewReader([]byte("REDIS0011")), 9, 1); !errors.Is(err, common.ErrSnapshotBadMagic) {
+		t.Errorf("Expected bad magic error, got %v", err)
+	}
+
+	for _, version := range []byte{1, 99} {
+		badVersion := append([]byte{}, raw...)
+		badVersion[len(snapshotMagic)+1] = version
+		if _, err := DecodeSnapshot(bytes.NewReader(badVersion), int64(len(raw)), 1); !errors.Is(err, common.ErrSnapshotVersion) {
+			t.Errorf("Expected version error for version %d, got %v", version, err)
+		}
+	}
+
+	if _, err := DecodeSnapshot(bytes.NewReader(raw), int64(len(raw)), 0); !errors.Is(err, common.ErrSnapshotDBOutOfRange) {
+		t.Errorf("Expected DB out of range error, got %v", err)
+	}
+}
+
+func TestSnapshotterSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.gkv")
+	dbs := store.NewInMemoryStoreArray(2)
+	dbs[0].Set("plain", []byte("v"))
+	dbs[1].Setx("ttl", []byte("v"), store.SetArgs{ExpType: store.ExpireEX, ExpVal: 100})
+	dbs[1].Setx("gone", []byte("v"), store.SetArgs{ExpType: store.ExpirePX, ExpVal: 1})
+	time.Sleep(5 * time.Millisecond)
+
+	saver := NewSnapshotter(path)
+	if err := saver.Save(dbs); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	restored := store.NewInMemoryStoreArray(2)
+	n, err := NewSnapshotter(path).Load(restored)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 loaded keys, got %d", n)
+	}
+	if v, _ := restored[0].Get("plain"); string(v) != "v" {
+		t.Errorf("Expected plain=v, got %q", v)
+	}
+	if ttl, _ := restored[1].TTL("ttl"); ttl < 98 || ttl > 100 {
+		t.Errorf("Expected ttl to survive the restart, got %d", ttl)
+	}
+}
+
+func TestSnapshotterBgSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.gkv")
+	dbs := store.NewInMemoryStoreArray(1)
+	dbs[0].Set("k", []byte("before"))
+
+	saver := NewSnapshotter(path)
+	if err := saver.BgSave(dbs); err != nil {
+		t.Fatalf("BgSave failed: %v", err)
+	}
+	// writes after BGSAVE returned are not part of the snapshot
+	dbs[0].Set("k", []byte("after"))
+
+	for saver.BgSaveInProgress() {
+		time.Sleep(time.Millisecond)
+	}
+	if err := saver.LastSaveErr(); err != nil {
+		t.Fatalf("background save failed: %v", err)
+	}
+
+	restored := store.NewInMemoryStoreArray(1)
+	if _, err := NewSnapshotter(path).Load(restored); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if v, _ := restored[0].Get("k"); string(v) != "before" {
+		t.Errorf("Expected point in time value 'before', got %q", v)
+	}
+}
+
+// the final save on shutdown waits for a running BGSAVE instead of failing
+func TestSnapshotterWaitSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.gkv")
+	dbs := store.NewInMemoryStoreArray(1)
+	dbs[0].Set("k", []byte("before"))
+
+	saver := NewSnapshotter(path)
+	if err := saver.BgSave(dbs); err != nil {
+		t.Fatalf("BgSave failed: %v", err)
+	}
+	dbs[0].Set("k", []byte("after"))
+	if err := saver.WaitSave(dbs); err != nil {
+		t.Fatalf("WaitSave failed: %v", err)
+	}
+	if saver.BgSaveInProgress() {
+		t.Errorf("Expected the background save to be done")
+	}
+
+	restored := store.NewInMemoryStoreArray(1)
+	if _, err := NewSnapshotter(path).Load(restored); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if v, _ := restored[0].Get("k"); string(v) != "after" {
+		t.Errorf("Expected the final save to win, got %q", v)
+	}
+}
+
+func TestSnapshotterLoadMissingFile(t *testing.T) {
+	n, err := NewSnapshotter(filepath.Join(t.TempDir(), "nope.gkv")).Load(store.NewInMemoryStoreArray(1))
+	if err != nil || n != 0 {
+		t.Errorf("Expected (0, nil) for a missing file, got (%d, %v)", n, err)
+	}
+}
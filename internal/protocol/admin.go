@@ -0,0 +1,258 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// CONFIG GET pattern [pattern ...] | SET name value [name value ...] |
+// RESETSTAT | REWRITE
+func validateConfig(req *RESPReq) error {
+	switch strings.ToLower(req.args[1]) {
+	case "get":
+		if len(req.args) < 3 {
+			return common.ErrUnknownSubcommand
+		}
+	case "set":
+		if len(req.args) < 4 || len(req.args)%2 != 0 {
+			return common.ErrUnknownSubcommand
+		}
+	case "resetstat", "rewrite":
+		if len(req.args) != 2 {
+			return common.ErrUnknownSubcommand
+		}
+	default:
+		return common.ErrUnknownSubcommand
+	}
+	return nil
+}
+
+func (r *RESP) processConfig(req *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	if r.Config == nil {
+		return nil, common.ErrConfigUnavailable
+	}
+	switch strings.ToLower(req.args[1]) {
+	case "get":
+		pairs := r.Config.Match(req.args[2:])
+		items := make([]*RESPRes, 0, len(pairs)*2)
+		for _, pair := range pairs {
+			items = append(items, bulkReply(pair[0]), bulkReply(pair[1]))
+		}
+		return mapReply(items...), nil
+	case "set":
+		pairs := [][2]string{}
+		for i := 2; i+1 < len(req.args); i += 2 {
+			pairs = append(pairs, [2]string{req.args[i], req.args[i+1]})
+		}
+		if err := r.Config.SetMany(pairs); err != nil {
+			return errorReply(fmt.Errorf("CONFIG SET failed - %w", err)), nil
+		}
+		return okReply(), nil
+	case "resetstat":
+		if r.Stats != nil {
+			r.Stats.Reset()
+		}
+		store.ResetExpireStats()
+		store.ResetEvictedKeys()
+		return okReply(), nil
+	case "rewrite":
+		if err := r.Config.Rewrite(); err != nil {
+			return errorReply(err), nil
+		}
+		return okReply(), nil
+	}
+	return nil, common.ErrUnknownSubcommand
+}
+
+// SLOWLOG GET [count] | LEN | RESET
+func validateSlowlog(req *RESPReq) error {
+	switch strings.ToLower(req.args[1]) {
+	case "get":
+		if len(req.args) > 3 {
+			return common.ErrUnknownSubcommand
+		}
+		if len(req.args) == 3 {
+			if _, err := strconv.Atoi(req.args[2]); err != nil {
+				return common.ErrNotIntOROutOfRange
+			}
+		}
+	case "len", "reset":
+		if len(req.args) != 2 {
+			return common.ErrUnknownSubcommand
+		}
+	default:
+		return common.ErrUnknownSubcommand
+	}
+	return nil
+}
+
+func (r *RESP) processSlowlog(req *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	if r.Stats == nil {
+		return nil, common.ErrConfigUnavailable
+	}
+	switch strings.ToLower(req.args[1]) {
+	case "get":
+		count := 10
+		if len(req.args) == 3 {
+			count, _ = strconv.Atoi(req.args[2])
+		}
+		entries := r.Stats.Slowlog.Get(count)
+		items := make([]*RESPRes, len(entries))
+		for i, e := range entries {
+			items[i] = arrayReply(
+				intReply(e.ID),
+				intReply(e.Time),
+				intReply(e.Duration),
+				bulkArrayReply(e.Args),
+				bulkReply(e.Client),
+				bulkReply(""),
+			)
+		}
+		return arrayReply(items...), nil
+	case "len":
+		return intReply(int64(r.Stats.Slowlog.Len())), nil
+	case "reset":
+		r.Stats.Slowlog.Reset()
+		return okReply(), nil
+	}
+	return nil, common.ErrUnknownSubcommand
+}
+
+// SHUTDOWN [NOSAVE | SAVE]
+func validateShutdown(req *RESPReq) error {
+	if len(req.args) > 2 {
+		return common.ErrSyntaxError
+	}
+	if len(req.args) == 2 {
+		if mode := strings.ToUpper(req.args[1]); mode != "SAVE" && mode != "NOSAVE" {
+			return common.ErrSyntaxError
+		}
+	}
+	return nil
+}
+
+func (r *RESP) shutdownCommand(req *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	if r.Shutdown == nil {
+		return nil, common.ErrConfigUnavailable
+	}
+	mode := ShutdownDefault
+	if len(req.args) == 2 {
+		if strings.ToUpper(req.args[1]) == "SAVE" {
+			mode = ShutdownSave
+		} else {
+			mode = ShutdownNoSave
+		}
+	}
+	if err := r.Shutdown(mode); err != nil {
+		return errorReply(err), nil
+	}
+	// like redis, a successful SHUTDOWN gets no reply, the connection is
+	// closed instead
+	return noReply(), nil
+}
+
+func (r *RESP) saveCommand(_ *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	if r.Saver == nil {
+		return nil, common.ErrPersistenceDisabled
+	}
+	if err := r.Saver.Save(r.DBs); err != nil {
+		return errorReply(err), nil
+	}
+	return okReply(), nil
+}
+
+func (r *RESP) bgsaveCommand(_ *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	if r.Saver == nil {
+		return nil, common.ErrPersistenceDisabled
+	}
+	if err := r.Saver.BgSave(r.DBs); err != nil {
+		return errorReply(err), nil
+	}
+	return simpleReply("Background saving started"), nil
+}
+
+func (r *RESP) lastsaveCommand(_ *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	if r.Saver == nil {
+		return nil, common.ErrPersistenceDisabled
+	}
+	return intReply(r.Saver.LastSave()), nil
+}
+
+func (r *RESP) bgrewriteaofCommand(_ *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	if r.AOF == nil {
+		return nil, common.ErrAOFDisabled
+	}
+	if err := r.AOF.Rewrite(r.DBs); err != nil {
+		return errorReply(err), nil
+	}
+	return simpleReply("Background append only file rewriting started"), nil
+}
+
+// INFO [section ...] replies the stats, memory and keyspace sections, all of
+// them without a section or with all, default or everything
+func (r *RESP) infoCommand(req *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	want := map[string]bool{}
+	for _, section := range req.args[1:] {
+		want[strings.ToLower(section)] = true
+	}
+	all := len(want) == 0 || want["all"] || want["default"] || want["everything"]
+
+	var b strings.Builder
+	section := func(name string, lines ...string) {
+		if !all && !want[strings.ToLower(name)] {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString("# " + name + "\r\n")
+		for _, line := range lines {
+			b.WriteString(line + "\r\n")
+		}
+	}
+
+	var conns, rejected, cmds int64
+	if r.Stats != nil {
+		conns = r.Stats.TotalConnections.Load()
+		rejected = r.Stats.RejectedConnections.Load()
+		cmds = r.Stats.TotalCommands.Load()
+	}
+	exp := store.GetExpireStats()
+	section("Stats",
+		fmt.Sprintf("total_connections_received:%d", conns),
+		fmt.Sprintf("total_commands_processed:%d", cmds),
+		fmt.Sprintf("rejected_connections:%d", rejected),
+		fmt.Sprintf("expired_keys:%d", exp.ExpiredKeys),
+		fmt.Sprintf("expire_cycles:%d", exp.ExpireCycles),
+		fmt.Sprintf("expired_time_cap_reached_count:%d", exp.ExpireCyclesCapped),
+		fmt.Sprintf("expire_cycle_cpu_milliseconds:%d", exp.ExpireCycleTimeUs/1000),
+		fmt.Sprintf("evicted_keys:%d", store.EvictedKeys()),
+	)
+
+	used := store.TotalUsedMemory(r.DBs)
+	maxMemory, policy := int64(0), store.EvictNoEviction
+	if r.Config != nil {
+		cfg := r.Config.Get()
+		maxMemory, policy = cfg.MaxMemory, cfg.MaxMemPolicy
+	}
+	section("Memory",
+		fmt.Sprintf("used_memory:%d", used),
+		fmt.Sprintf("maxmemory:%d", maxMemory),
+		"maxmemory_policy:"+policy,
+	)
+
+	// like redis, only the databases holding keys are listed
+	var keyspace []string
+	for i, db := range r.DBs {
+		if keys := db.KeyCount(); keys > 0 {
+			keyspace = append(keyspace, fmt.Sprintf("db%d:keys=%d,expires=%d", i, keys, db.ExpiresCount()))
+		}
+	}
+	section("Keyspace", keyspace...)
+
+	return bulkReply(b.String()), nil
+}
@@ -0,0 +1,912 @@
+package protocol
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// command flags, reported by COMMAND like redis does
+const (
+	flagWrite    = 1 << iota // changes data, appended to the AOF
+	flagReadonly             // only reads data
+	flagDenyOOM              // may grow memory, refused over maxmemory
+	flagAdmin                // server administration
+	flagNoAuth               // allowed before AUTH
+	flagFast                 // O(1) or O(log n)
+)
+
+var flagNames = []struct {
+	flag int
+	name string
+}{
+	{flagWrite, "write"},
+	{flagReadonly, "readonly"},
+	{flagDenyOOM, "denyoom"},
+	{flagAdmin, "admin"},
+	{flagNoAuth, "no_auth"},
+	{flagFast, "fast"},
+}
+
+type handler func(r *RESP, req *RESPReq, dbIndex *int, mem *store.InMemoryStore) (*RESPRes, error)
+
+// command describes everything the server knows about a command: how to
+// validate it, how to run it and how COMMAND reports it
+type command struct {
+	name  string
+	arity int // like redis: N for exactly N arguments, -N for at least N, the name included
+	flags int
+	// positions of the keys in the arguments, 0 when there are none and a
+	// negative last key counts from the end
+	firstKey, lastKey, step int
+	group                   string // string, hash, list, set, sortedset, keyspace, connection or server
+	summary                 string
+	args                    []argDoc // for COMMAND DOCS
+	// the keys only the arguments tell, like the sources of ZUNIONSTORE
+	movableKeys func(args []string) []string
+
+	// checks that go beyond the arity, run when the request is parsed
+	validate func(req *RESPReq) error
+	run      handler
+}
+
+// argDoc documents an argument of a command, nested for blocks and choices
+type argDoc struct {
+	name     string
+	typ      string // key, string, integer, unix-time, pure-token, oneof or block
+	token    string // keyword introducing the argument, like EX
+	optional bool
+	multiple bool
+	sub      []argDoc
+}
+
+var commandTable = []*command{
+	// strings
+	{name: "get", arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Returns the string value of a key.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).getCommand},
+	{name: "set", arity: -3, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.",
+		args: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "value", typ: "string"},
+			{name: "condition", typ: "oneof", optional: true, sub: []argDoc{
+				{name: "nx", typ: "pure-token", token: "NX"},
+				{name: "xx", typ: "pure-token", token: "XX"},
+			}},
+			{name: "get", typ: "pure-token", token: "GET", optional: true},
+			{name: "expiration", typ: "oneof", optional: true, sub: []argDoc{
+				{name: "seconds", typ: "integer", token: "EX"},
+				{name: "milliseconds", typ: "integer", token: "PX"},
+				{name: "unix-time-seconds", typ: "unix-time", token: "EXAT"},
+				{name: "unix-time-milliseconds", typ: "unix-time", token: "PXAT"},
+				{name: "keepttl", typ: "pure-token", token: "KEEPTTL"},
+			}},
+		},
+		validate: validateSet, run: (*RESP).setCommand},
+	{name: "incr", arity: 2, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).incrCommand},
+	{name: "incrby", arity: 3, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "increment", typ: "integer"}},
+		validate: validateIntArg(2, common.ErrInvalidIncrement), run: (*RESP).incrCommand},
+	{name: "decr", arity: 2, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Decrements the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).incrCommand},
+	{name: "decrby", arity: 3, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "decrement", typ: "integer"}},
+		validate: validateIntArg(2, common.ErrInvalidDecrement), run: (*RESP).incrCommand},
+	{name: "incrbyfloat", arity: 3, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Increment the floating point value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "increment", typ: "double"}},
+		validate: validateIncrbyfloat, run: (*RESP).incrbyfloatCommand},
+	{name: "mget", arity: -2, flags: flagReadonly, firstKey: 1, lastKey: -1, step: 1,
+		group: "string", summary: "Atomically returns the string values of one or more keys.",
+		args: []argDoc{{name: "key", typ: "key", multiple: true}},
+		run:  (*RESP).mgetCommand},
+	{name: "mset", arity: -3, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: -1, step: 2,
+		group: "string", summary: "Atomically creates or modifies the string values of one or more keys.",
+		args: []argDoc{{name: "data", typ: "block", multiple: true, sub: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "value", typ: "string"},
+		}}},
+		validate: validatePairs, run: (*RESP).msetCommand},
+	{name: "msetnx", arity: -3, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: -1, step: 2,
+		group: "string", summary: "Atomically modifies the string values of one or more keys only when all keys don't exist.",
+		args: []argDoc{{name: "data", typ: "block", multiple: true, sub: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "value", typ: "string"},
+		}}},
+		validate: validatePairs, run: (*RESP).msetCommand},
+	{name: "setnx", arity: 3, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Set the string value of a key only when the key doesn't exist.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "value", typ: "string"}},
+		run:  (*RESP).setnxCommand},
+	{name: "setex", arity: 4, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Sets the string value and expiration time of a key. Creates the key if it doesn't exist.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "seconds", typ: "integer"}, {name: "value", typ: "string"}},
+		validate: validateSetex, run: (*RESP).setexCommand},
+	{name: "psetex", arity: 4, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Sets both string value and expiration time in milliseconds of a key. The key is created if it doesn't exist.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "milliseconds", typ: "integer"}, {name: "value", typ: "string"}},
+		validate: validateSetex, run: (*RESP).setexCommand},
+	{name: "getdel", arity: 2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Returns the string value of a key after deleting the key.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).getdelCommand},
+	{name: "getex", arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Returns the string value of a key after setting its expiration time.",
+		args: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "expiration", typ: "oneof", optional: true, sub: []argDoc{
+				{name: "seconds", typ: "integer", token: "EX"},
+				{name: "milliseconds", typ: "integer", token: "PX"},
+				{name: "unix-time-seconds", typ: "unix-time", token: "EXAT"},
+				{name: "unix-time-milliseconds", typ: "unix-time", token: "PXAT"},
+				{name: "persist", typ: "pure-token", token: "PERSIST"},
+			}},
+		},
+		validate: validateGetex, run: (*RESP).getexCommand},
+	{name: "append", arity: 3, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Appends a string to the value of a key. Creates the key if it doesn't exist.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "value", typ: "string"}},
+		run:  (*RESP).appendCommand},
+	{name: "strlen", arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Returns the length of a string value.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).strlenCommand},
+	{name: "getrange", arity: 4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Returns a substring of the string stored at a key.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "start", typ: "integer"}, {name: "end", typ: "integer"}},
+		validate: validateInts(2, 3), run: (*RESP).getrangeCommand},
+	{name: "setrange", arity: 4, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 1, step: 1,
+		group: "string", summary: "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "offset", typ: "integer"}, {name: "value", typ: "string"}},
+		validate: validateSetrange, run: (*RESP).setrangeCommand},
+
+	// hash
+	{name: "hset", arity: -4, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Creates or modifies the value of a field in a hash.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "data", typ: "block", multiple: true, sub: []argDoc{
+			{name: "field", typ: "string"},
+			{name: "value", typ: "string"},
+		}}},
+		validate: validateFieldPairs, run: (*RESP).hsetCommand},
+	{name: "hmset", arity: -4, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Sets the values of multiple fields.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "data", typ: "block", multiple: true, sub: []argDoc{
+			{name: "field", typ: "string"},
+			{name: "value", typ: "string"},
+		}}},
+		validate: validateFieldPairs, run: (*RESP).hsetCommand},
+	{name: "hsetnx", arity: 4, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Sets the value of a field in a hash only when the field doesn't exist.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "field", typ: "string"}, {name: "value", typ: "string"}},
+		run:  (*RESP).hsetnxCommand},
+	{name: "hget", arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Returns the value of a field in a hash.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "field", typ: "string"}},
+		run:  (*RESP).hgetCommand},
+	{name: "hmget", arity: -3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Returns the values of all fields in a hash.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "field", typ: "string", multiple: true}},
+		run:  (*RESP).hmgetCommand},
+	{name: "hgetall", arity: 2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Returns all fields and values in a hash.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).hgetallCommand},
+	{name: "hdel", arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Deletes one or more fields and their values from a hash. Deletes the hash if no fields remain.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "field", typ: "string", multiple: true}},
+		run:  (*RESP).hdelCommand},
+	{name: "hexists", arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Determines whether a field exists in a hash.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "field", typ: "string"}},
+		run:  (*RESP).hexistsCommand},
+	{name: "hlen", arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Returns the number of fields in a hash.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).hlenCommand},
+	{name: "hkeys", arity: 2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Returns all fields in a hash.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).hkeysCommand},
+	{name: "hvals", arity: 2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Returns all values in a hash.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).hvalsCommand},
+	{name: "hstrlen", arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Returns the length of the value of a field.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "field", typ: "string"}},
+		run:  (*RESP).hstrlenCommand},
+	{name: "hincrby", arity: 4, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Increments the integer value of a field in a hash by a number. Uses 0 as initial value if the field doesn't exist.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "field", typ: "string"}, {name: "increment", typ: "integer"}},
+		validate: validateIntArg(3, common.ErrNotIntOROutOfRange), run: (*RESP).hincrbyCommand},
+	{name: "hincrbyfloat", arity: 4, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Increments the floating point value of a field by a number. Uses 0 as initial value if the field doesn't exist.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "field", typ: "string"}, {name: "increment", typ: "double"}},
+		validate: validateHincrbyfloat, run: (*RESP).hincrbyfloatCommand},
+	{name: "hrandfield", arity: -2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Returns one or more random fields from a hash.",
+		args: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "options", typ: "block", optional: true, sub: []argDoc{
+				{name: "count", typ: "integer"},
+				{name: "withvalues", typ: "pure-token", token: "WITHVALUES", optional: true},
+			}},
+		},
+		validate: validateHrandfield, run: (*RESP).hrandfieldCommand},
+	{name: "hscan", arity: -3, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "hash", summary: "Iterates over fields and values of a hash.",
+		args: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "cursor", typ: "integer"},
+			{name: "pattern", typ: "pattern", token: "MATCH", optional: true},
+			{name: "count", typ: "integer", token: "COUNT", optional: true},
+			{name: "novalues", typ: "pure-token", token: "NOVALUES", optional: true},
+		},
+		validate: validateScan, run: (*RESP).hscanCommand},
+
+	// list
+	{name: "lpush", arity: -3, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Prepends one or more elements to a list. Creates the key if it doesn't exist.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "element", typ: "string", multiple: true}},
+		run:  (*RESP).pushCommand},
+	{name: "rpush", arity: -3, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Appends one or more elements to a list. Creates the key if it doesn't exist.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "element", typ: "string", multiple: true}},
+		run:  (*RESP).pushCommand},
+	{name: "lpop", arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Returns the first elements in a list after removing it. Deletes the list if the last element was popped.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "count", typ: "integer", optional: true}},
+		validate: validatePop, run: (*RESP).popCommand},
+	{name: "rpop", arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Returns and removes the last elements of a list. Deletes the list if the last element was popped.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "count", typ: "integer", optional: true}},
+		validate: validatePop, run: (*RESP).popCommand},
+	{name: "llen", arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Returns the length of a list.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).llenCommand},
+	{name: "lrange", arity: 4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Returns a range of elements from a list.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "start", typ: "integer"}, {name: "stop", typ: "integer"}},
+		validate: validateInts(2, 3), run: (*RESP).lrangeCommand},
+	{name: "lindex", arity: 3, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Returns an element from a list by its index.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "index", typ: "integer"}},
+		validate: validateInts(2), run: (*RESP).lindexCommand},
+	{name: "lset", arity: 4, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Sets the value of an element in a list by its index.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "index", typ: "integer"}, {name: "element", typ: "string"}},
+		validate: validateInts(2), run: (*RESP).lsetCommand},
+	{name: "linsert", arity: 5, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Inserts an element before or after another element in a list.",
+		args: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "where", typ: "oneof", sub: []argDoc{
+				{name: "before", typ: "pure-token", token: "BEFORE"},
+				{name: "after", typ: "pure-token", token: "AFTER"},
+			}},
+			{name: "pivot", typ: "string"},
+			{name: "element", typ: "string"},
+		},
+		validate: validateLinsert, run: (*RESP).linsertCommand},
+	{name: "lrem", arity: 4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Removes elements from a list. Deletes the list if the last element was removed.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "count", typ: "integer"}, {name: "element", typ: "string"}},
+		validate: validateInts(2), run: (*RESP).lremCommand},
+	{name: "ltrim", arity: 4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Removes elements from both ends a list. Deletes the list if all elements were trimmed.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "start", typ: "integer"}, {name: "stop", typ: "integer"}},
+		validate: validateInts(2, 3), run: (*RESP).ltrimCommand},
+	{name: "lpos", arity: -3, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "list", summary: "Returns the index of matching elements in a list.",
+		args: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "element", typ: "string"},
+			{name: "rank", typ: "integer", token: "RANK", optional: true},
+			{name: "num-matches", typ: "integer", token: "COUNT", optional: true},
+			{name: "len", typ: "integer", token: "MAXLEN", optional: true},
+		},
+		validate: validateLpos, run: (*RESP).lposCommand},
+	{name: "lmove", arity: 5, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 2, step: 1,
+		group: "list", summary: "Returns an element after popping it from one list and pushing it to another. Deletes the list if the last element was moved.",
+		args:     moveArgs(),
+		validate: validateLmove, run: (*RESP).lmoveCommand},
+	{name: "blpop", arity: -3, flags: flagWrite, firstKey: 1, lastKey: -2, step: 1,
+		group: "list", summary: "Removes and returns the first element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+		args:     []argDoc{{name: "key", typ: "key", multiple: true}, {name: "timeout", typ: "double"}},
+		validate: validateBlockingPop, run: (*RESP).blockingPopCommand},
+	{name: "brpop", arity: -3, flags: flagWrite, firstKey: 1, lastKey: -2, step: 1,
+		group: "list", summary: "Removes and returns the last element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+		args:     []argDoc{{name: "key", typ: "key", multiple: true}, {name: "timeout", typ: "double"}},
+		validate: validateBlockingPop, run: (*RESP).blockingPopCommand},
+	{name: "blmove", arity: 6, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 2, step: 1,
+		group: "list", summary: "Pops an element from a list, pushes it to another list and returns it. Blocks until an element is available otherwise. Deletes the list if the last element was moved.",
+		args:     append(moveArgs(), argDoc{name: "timeout", typ: "double"}),
+		validate: validateLmove, run: (*RESP).lmoveCommand},
+
+	// set
+	{name: "sadd", arity: -3, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "set", summary: "Adds one or more members to a set. Creates the key if it doesn't exist.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "member", typ: "string", multiple: true}},
+		run:  (*RESP).saddCommand},
+	{name: "srem", arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "set", summary: "Removes one or more members from a set. Deletes the set if the last member was removed.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "member", typ: "string", multiple: true}},
+		run:  (*RESP).sremCommand},
+	{name: "sismember", arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "set", summary: "Determines whether a member belongs to a set.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "member", typ: "string"}},
+		run:  (*RESP).sismemberCommand},
+	{name: "smismember", arity: -3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "set", summary: "Determines whether multiple members belong to a set.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "member", typ: "string", multiple: true}},
+		run:  (*RESP).smismemberCommand},
+	{name: "smembers", arity: 2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "set", summary: "Returns all members of a set.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).smembersCommand},
+	{name: "scard", arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "set", summary: "Returns the number of members in a set.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).scardCommand},
+	{name: "spop", arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "set", summary: "Returns one or more random members from a set after removing them. Deletes the set if the last member was popped.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "count", typ: "integer", optional: true}},
+		validate: validatePop, run: (*RESP).spopCommand},
+	{name: "srandmember", arity: -2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "set", summary: "Returns one or more random members from a set.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "count", typ: "integer", optional: true}},
+		validate: validateSrandmember, run: (*RESP).srandmemberCommand},
+	{name: "smove", arity: 4, flags: flagWrite | flagFast, firstKey: 1, lastKey: 2, step: 1,
+		group: "set", summary: "Moves a member from one set to another.",
+		args: []argDoc{{name: "source", typ: "key"}, {name: "destination", typ: "key"}, {name: "member", typ: "string"}},
+		run:  (*RESP).smoveCommand},
+	{name: "sinter", arity: -2, flags: flagReadonly, firstKey: 1, lastKey: -1, step: 1,
+		group: "set", summary: "Returns the intersect of multiple sets.",
+		args: []argDoc{{name: "key", typ: "key", multiple: true}},
+		run:  (*RESP).setAlgebraCommand},
+	{name: "sunion", arity: -2, flags: flagReadonly, firstKey: 1, lastKey: -1, step: 1,
+		group: "set", summary: "Returns the union of multiple sets.",
+		args: []argDoc{{name: "key", typ: "key", multiple: true}},
+		run:  (*RESP).setAlgebraCommand},
+	{name: "sdiff", arity: -2, flags: flagReadonly, firstKey: 1, lastKey: -1, step: 1,
+		group: "set", summary: "Returns the difference of multiple sets.",
+		args: []argDoc{{name: "key", typ: "key", multiple: true}},
+		run:  (*RESP).setAlgebraCommand},
+	{name: "sinterstore", arity: -3, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: -1, step: 1,
+		group: "set", summary: "Stores the intersect of multiple sets in a key.",
+		args: []argDoc{{name: "destination", typ: "key"}, {name: "key", typ: "key", multiple: true}},
+		run:  (*RESP).setAlgebraStoreCommand},
+	{name: "sunionstore", arity: -3, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: -1, step: 1,
+		group: "set", summary: "Stores the union of multiple sets in a key.",
+		args: []argDoc{{name: "destination", typ: "key"}, {name: "key", typ: "key", multiple: true}},
+		run:  (*RESP).setAlgebraStoreCommand},
+	{name: "sdiffstore", arity: -3, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: -1, step: 1,
+		group: "set", summary: "Stores the difference of multiple sets in a key.",
+		args: []argDoc{{name: "destination", typ: "key"}, {name: "key", typ: "key", multiple: true}},
+		run:  (*RESP).setAlgebraStoreCommand},
+	// the keys follow numkeys, there are no fixed positions
+	{name: "sintercard", arity: -3, flags: flagReadonly,
+		group: "set", summary: "Returns the number of members of the intersect of multiple sets.",
+		args: []argDoc{
+			{name: "numkeys", typ: "integer"},
+			{name: "key", typ: "key", multiple: true},
+			{name: "limit", typ: "integer", token: "LIMIT", optional: true},
+		},
+		movableKeys: sintercardKeys,
+		validate:    validateSintercard, run: (*RESP).sintercardCommand},
+	{name: "sscan", arity: -3, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "set", summary: "Iterates over members of a set.",
+		args: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "cursor", typ: "integer"},
+			{name: "pattern", typ: "pattern", token: "MATCH", optional: true},
+			{name: "count", typ: "integer", token: "COUNT", optional: true},
+		},
+		validate: validateScan, run: (*RESP).sscanCommand},
+
+	// sorted set
+	{name: "zadd", arity: -4, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Adds one or more members to a sorted set, or updates their scores. Creates the key if it doesn't exist.",
+		args: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "condition", typ: "oneof", optional: true, sub: []argDoc{
+				{name: "nx", typ: "pure-token", token: "NX"},
+				{name: "xx", typ: "pure-token", token: "XX"},
+			}},
+			{name: "comparison", typ: "oneof", optional: true, sub: []argDoc{
+				{name: "gt", typ: "pure-token", token: "GT"},
+				{name: "lt", typ: "pure-token", token: "LT"},
+			}},
+			{name: "change", typ: "pure-token", token: "CH", optional: true},
+			{name: "increment", typ: "pure-token", token: "INCR", optional: true},
+			{name: "data", typ: "block", multiple: true, sub: []argDoc{
+				{name: "score", typ: "double"},
+				{name: "member", typ: "string"},
+			}},
+		},
+		validate: validateZadd, run: (*RESP).zaddCommand},
+	{name: "zrem", arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Removes one or more members from a sorted set. Deletes the sorted set if all members were removed.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "member", typ: "string", multiple: true}},
+		run:  (*RESP).zremCommand},
+	{name: "zscore", arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Returns the score of a member in a sorted set.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "member", typ: "string"}},
+		run:  (*RESP).zscoreCommand},
+	{name: "zmscore", arity: -3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Returns the score of one or more members in a sorted set.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "member", typ: "string", multiple: true}},
+		run:  (*RESP).zmscoreCommand},
+	{name: "zincrby", arity: 4, flags: flagWrite | flagDenyOOM | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Increments the score of a member in a sorted set.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "increment", typ: "double"}, {name: "member", typ: "string"}},
+		validate: validateZincrby, run: (*RESP).zincrbyCommand},
+	{name: "zcard", arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Returns the number of members in a sorted set.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).zcardCommand},
+	{name: "zcount", arity: 4, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Returns the count of members in a sorted set that have scores within a range.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "min", typ: "double"}, {name: "max", typ: "double"}},
+		validate: validateZcount, run: (*RESP).zcountCommand},
+	{name: "zrank", arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Returns the index of a member in a sorted set ordered by ascending scores.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "member", typ: "string"}},
+		run:  (*RESP).zrankCommand},
+	{name: "zrevrank", arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Returns the index of a member in a sorted set ordered by descending scores.",
+		args: []argDoc{{name: "key", typ: "key"}, {name: "member", typ: "string"}},
+		run:  (*RESP).zrankCommand},
+	{name: "zrange", arity: -4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Returns members in a sorted set within a range of indexes, scores or members.",
+		args: append(append([]argDoc{{name: "key", typ: "key"}}, zrangeArgs()...),
+			argDoc{name: "withscores", typ: "pure-token", token: "WITHSCORES", optional: true}),
+		validate: validateZrange, run: (*RESP).zrangeCommand},
+	{name: "zrangestore", arity: -5, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 2, step: 1,
+		group: "sortedset", summary: "Stores a range of members from a sorted set in a key.",
+		args:     append([]argDoc{{name: "dst", typ: "key"}, {name: "src", typ: "key"}}, zrangeArgs()...),
+		validate: validateZrange, run: (*RESP).zrangestoreCommand},
+	{name: "zpopmin", arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Returns the lowest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "count", typ: "integer", optional: true}},
+		validate: validatePop, run: (*RESP).zpopCommand},
+	{name: "zpopmax", arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Returns the highest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "count", typ: "integer", optional: true}},
+		validate: validatePop, run: (*RESP).zpopCommand},
+	{name: "bzpopmin", arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: -2, step: 1,
+		group: "sortedset", summary: "Removes and returns the member with the lowest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.",
+		args:     []argDoc{{name: "key", typ: "key", multiple: true}, {name: "timeout", typ: "double"}},
+		validate: validateBlockingPop, run: (*RESP).blockingZpopCommand},
+	{name: "bzpopmax", arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: -2, step: 1,
+		group: "sortedset", summary: "Removes and returns the member with the highest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.",
+		args:     []argDoc{{name: "key", typ: "key", multiple: true}, {name: "timeout", typ: "double"}},
+		validate: validateBlockingPop, run: (*RESP).blockingZpopCommand},
+	{name: "zunionstore", arity: -4, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Stores the union of multiple sorted sets in a key.",
+		args:        append([]argDoc{{name: "destination", typ: "key"}}, zstoreArgDocs()...),
+		movableKeys: zstoreKeys,
+		validate:    validateZstore, run: (*RESP).zstoreCommand},
+	{name: "zinterstore", arity: -4, flags: flagWrite | flagDenyOOM, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Stores the intersect of multiple sorted sets in a key.",
+		args:        append([]argDoc{{name: "destination", typ: "key"}}, zstoreArgDocs()...),
+		movableKeys: zstoreKeys,
+		validate:    validateZstore, run: (*RESP).zstoreCommand},
+	{name: "zscan", arity: -3, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		group: "sortedset", summary: "Iterates over members and scores of a sorted set.",
+		args: []argDoc{
+			{name: "key", typ: "key"},
+			{name: "cursor", typ: "integer"},
+			{name: "pattern", typ: "pattern", token: "MATCH", optional: true},
+			{name: "count", typ: "integer", token: "COUNT", optional: true},
+		},
+		validate: validateScan, run: (*RESP).zscanCommand},
+
+	// keyspace
+	{name: "del", arity: -2, flags: flagWrite, firstKey: 1, lastKey: -1, step: 1,
+		group: "keyspace", summary: "Deletes one or more keys.",
+		args: []argDoc{{name: "key", typ: "key", multiple: true}},
+		run:  (*RESP).delCommand},
+	{name: "exists", arity: -2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: -1, step: 1,
+		group: "keyspace", summary: "Determines whether one or more keys exist.",
+		args: []argDoc{{name: "key", typ: "key", multiple: true}},
+		run:  (*RESP).existsCommand},
+	{name: "type", arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "keyspace", summary: "Determines the type of value stored at a key.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).typeCommand},
+	{name: "ttl", arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "keyspace", summary: "Returns the expiration time in seconds of a key.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).ttlCommand},
+	{name: "expire", arity: 3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "keyspace", summary: "Sets the expiration time of a key in seconds.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "seconds", typ: "integer"}},
+		validate: validateIntArg(2, common.ErrInvalidExpireTime), run: (*RESP).expireCommand},
+	{name: "pexpireat", arity: 3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "keyspace", summary: "Sets the expiration time of a key to a Unix milliseconds timestamp.",
+		args:     []argDoc{{name: "key", typ: "key"}, {name: "unix-time-milliseconds", typ: "unix-time"}},
+		validate: validateIntArg(2, common.ErrInvalidExpireTime), run: (*RESP).pexpireatCommand},
+	{name: "persist", arity: 2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		group: "keyspace", summary: "Removes the expiration time of a key.",
+		args: []argDoc{{name: "key", typ: "key"}},
+		run:  (*RESP).persistCommand},
+
+	// connection
+	{name: "select", arity: 2, flags: flagFast,
+		group: "connection", summary: "Changes the selected database.",
+		args: []argDoc{{name: "index", typ: "integer"}},
+		run:  (*RESP).selectCommand},
+	{name: "auth", arity: -2, flags: flagNoAuth | flagFast,
+		group: "connection", summary: "Authenticates the connection.",
+		args:     []argDoc{{name: "username", typ: "string", optional: true}, {name: "password", typ: "string"}},
+		validate: validateMaxArgs(3), run: (*RESP).authCommand},
+	{name: "hello", arity: -1, flags: flagNoAuth | flagFast,
+		group: "connection", summary: "Handshakes with the server.",
+		args: []argDoc{{name: "arguments", typ: "block", optional: true, sub: []argDoc{
+			{name: "protover", typ: "integer"},
+			{name: "auth", typ: "block", token: "AUTH", optional: true, sub: []argDoc{
+				{name: "username", typ: "string"},
+				{name: "password", typ: "string"},
+			}},
+			{name: "clientname", typ: "string", token: "SETNAME", optional: true},
+		}}},
+		validate: validateHello, run: (*RESP).processHello},
+	{name: "ping", arity: 1, flags: flagFast,
+		group: "connection", summary: "Returns the server's liveliness response.",
+		run: (*RESP).pingCommand},
+
+	// server
+	{name: "command", arity: -1,
+		group: "server", summary: "Returns detailed information about all commands.",
+		args: []argDoc{{name: "subcommand", typ: "oneof", optional: true, sub: []argDoc{
+			{name: "count", typ: "pure-token", token: "COUNT"},
+			{name: "list", typ: "pure-token", token: "LIST"},
+			{name: "info", typ: "string", token: "INFO", multiple: true},
+			{name: "docs", typ: "string", token: "DOCS", multiple: true},
+		}}},
+		validate: validateCommand, run: (*RESP).commandCommand},
+	{name: "config", arity: -2, flags: flagAdmin,
+		group: "server", summary: "Gets, sets, rewrites the configuration or resets the statistics.",
+		args: []argDoc{{name: "subcommand", typ: "oneof", sub: []argDoc{
+			{name: "get", typ: "string", token: "GET", multiple: true},
+			{name: "set", typ: "string", token: "SET", multiple: true},
+			{name: "resetstat", typ: "pure-token", token: "RESETSTAT"},
+			{name: "rewrite", typ: "pure-token", token: "REWRITE"},
+		}}},
+		validate: validateConfig, run: (*RESP).processConfig},
+	{name: "info", arity: -1,
+		group: "server", summary: "Returns information and statistics about the server.",
+		args: []argDoc{{name: "section", typ: "string", optional: true, multiple: true}},
+		run:  (*RESP).infoCommand},
+	{name: "slowlog", arity: -2, flags: flagAdmin,
+		group: "server", summary: "Inspects or resets the slow log.",
+		args: []argDoc{{name: "subcommand", typ: "oneof", sub: []argDoc{
+			{name: "get", typ: "integer", token: "GET", optional: true},
+			{name: "len", typ: "pure-token", token: "LEN"},
+			{name: "reset", typ: "pure-token", token: "RESET"},
+		}}},
+		validate: validateSlowlog, run: (*RESP).processSlowlog},
+	{name: "shutdown", arity: -1, flags: flagAdmin,
+		group: "server", summary: "Synchronously saves the database(s) to disk and shuts down the server.",
+		args: []argDoc{{name: "save-selector", typ: "oneof", optional: true, sub: []argDoc{
+			{name: "nosave", typ: "pure-token", token: "NOSAVE"},
+			{name: "save", typ: "pure-token", token: "SAVE"},
+		}}},
+		validate: validateShutdown, run: (*RESP).shutdownCommand},
+	{name: "save", arity: 1, flags: flagAdmin,
+		group: "server", summary: "Synchronously saves the database(s) to disk.",
+		run: (*RESP).saveCommand},
+	{name: "bgsave", arity: 1, flags: flagAdmin,
+		group: "server", summary: "Asynchronously saves the database(s) to disk.",
+		run: (*RESP).bgsaveCommand},
+	{name: "lastsave", arity: 1, flags: flagFast,
+		group: "server", summary: "Returns the Unix timestamp of the last successful save to disk.",
+		run: (*RESP).lastsaveCommand},
+	{name: "bgrewriteaof", arity: 1, flags: flagAdmin,
+		group: "server", summary: "Asynchronously rewrites the append-only file to disk.",
+		run: (*RESP).bgrewriteaofCommand},
+}
+
+// commands indexes the table by name, commandList is the table again for the
+// handlers that walk it, which can't refer to commandTable directly as it
+// refers to them
+var (
+	commands    = map[string]*command{}
+	commandList []*command
+)
+
+func init() {
+	commandList = commandTable
+	for _, c := range commandTable {
+		commands[c.name] = c
+	}
+}
+
+func lookupCommand(name string) *command {
+	return commands[strings.ToLower(name)]
+}
+
+// checkArity validates the number of arguments against the table
+func (c *command) checkArity(n int) error {
+	if (c.arity > 0 && n != c.arity) || (c.arity < 0 && n < -c.arity) {
+		return common.ErrWrongNumberArgs
+	}
+	return nil
+}
+
+func validateIntArg(i int, err error) func(req *RESPReq) error {
+	return func(req *RESPReq) error {
+		if _, e := strconv.ParseInt(req.args[i], 10, 64); e != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// validateInts checks the arguments at positions are integers
+func validateInts(positions ...int) func(req *RESPReq) error {
+	return func(req *RESPReq) error {
+		for _, i := range positions {
+			if _, err := strconv.Atoi(req.args[i]); err != nil {
+				return common.ErrNotIntOROutOfRange
+			}
+		}
+		return nil
+	}
+}
+
+// validatePairs checks the arguments after the name come as key value pairs
+func validatePairs(req *RESPReq) error {
+	if len(req.args)%2 == 0 {
+		return common.ErrWrongNumberArgs
+	}
+	return nil
+}
+
+func validateMaxArgs(n int) func(req *RESPReq) error {
+	return func(req *RESPReq) error {
+		if len(req.args) > n {
+			return common.ErrWrongNumberArgs
+		}
+		return nil
+	}
+}
+
+// moveArgs are the arguments of LMOVE, BLMOVE adds a timeout
+func moveArgs() []argDoc {
+	dir := func(name string) argDoc {
+		return argDoc{name: name, typ: "oneof", sub: []argDoc{
+			{name: "left", typ: "pure-token", token: "LEFT"},
+			{name: "right", typ: "pure-token", token: "RIGHT"},
+		}}
+	}
+	return []argDoc{{name: "source", typ: "key"}, {name: "destination", typ: "key"}, dir("wherefrom"), dir("whereto")}
+}
+
+// keys returns the keys of a request, those at the fixed positions then the
+// movable ones
+func (c *command) keys(args []string) []string {
+	var keys []string
+	if c.firstKey > 0 {
+		last := c.lastKey
+		if last < 0 {
+			last += len(args)
+		}
+		for i := c.firstKey; i <= last && i < len(args); i += c.step {
+			keys = append(keys, args[i])
+		}
+	}
+	if c.movableKeys != nil {
+		keys = append(keys, c.movableKeys(args)...)
+	}
+	return keys
+}
+
+// zrangeArgs are the range arguments shared by ZRANGE and ZRANGESTORE
+func zrangeArgs() []argDoc {
+	return []argDoc{
+		{name: "start", typ: "string"},
+		{name: "stop", typ: "string"},
+		{name: "sortby", typ: "oneof", optional: true, sub: []argDoc{
+			{name: "byscore", typ: "pure-token", token: "BYSCORE"},
+			{name: "bylex", typ: "pure-token", token: "BYLEX"},
+		}},
+		{name: "rev", typ: "pure-token", token: "REV", optional: true},
+		{name: "limit", typ: "block", token: "LIMIT", optional: true, sub: []argDoc{
+			{name: "offset", typ: "integer"},
+			{name: "count", typ: "integer"},
+		}},
+	}
+}
+
+// zstoreArgDocs are the arguments of ZUNIONSTORE and ZINTERSTORE after the
+// destination
+func zstoreArgDocs() []argDoc {
+	return []argDoc{
+		{name: "numkeys", typ: "integer"},
+		{name: "key", typ: "key", multiple: true},
+		{name: "weight", typ: "integer", token: "WEIGHTS", optional: true, multiple: true},
+		{name: "aggregate", typ: "oneof", token: "AGGREGATE", optional: true, sub: []argDoc{
+			{name: "sum", typ: "pure-token", token: "SUM"},
+			{name: "min", typ: "pure-token", token: "MIN"},
+			{name: "max", typ: "pure-token", token: "MAX"},
+		}},
+	}
+}
+
+// COMMAND [COUNT | LIST | INFO [name ...] | DOCS [name ...]]
+func validateCommand(req *RESPReq) error {
+	if len(req.args) == 1 {
+		return nil
+	}
+	switch strings.ToLower(req.args[1]) {
+	case "count", "list":
+		if len(req.args) != 2 {
+			return common.ErrUnknownSubcommand
+		}
+	case "info", "docs":
+	default:
+		return common.ErrUnknownSubcommand
+	}
+	return nil
+}
+
+func (r *RESP) commandCommand(req *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	if len(req.args) == 1 {
+		items := make([]*RESPRes, len(commandList))
+		for i, c := range commandList {
+			items[i] = c.info()
+		}
+		return arrayReply(items...), nil
+	}
+
+	switch strings.ToLower(req.args[1]) {
+	case "count":
+		return intReply(int64(len(commandList))), nil
+	case "list":
+		names := make([]string, len(commandList))
+		for i, c := range commandList {
+			names[i] = c.name
+		}
+		sort.Strings(names)
+		return bulkArrayReply(names), nil
+	case "info":
+		items := make([]*RESPRes, 0, len(req.args)-2)
+		for _, name := range req.args[2:] {
+			if c := lookupCommand(name); c != nil {
+				items = append(items, c.info())
+			} else {
+				items = append(items, nullArrayReply())
+			}
+		}
+		if len(req.args) == 2 {
+			for _, c := range commandList {
+				items = append(items, c.info())
+			}
+		}
+		return arrayReply(items...), nil
+	default: // docs
+		names := req.args[2:]
+		if len(names) == 0 {
+			for _, c := range commandList {
+				names = append(names, c.name)
+			}
+		}
+		items := []*RESPRes{}
+		for _, name := range names {
+			// unknown commands are left out
+			if c := lookupCommand(name); c != nil {
+				items = append(items, bulkReply(c.name), c.docs())
+			}
+		}
+		return mapReply(items...), nil
+	}
+}
+
+// info is the COMMAND / COMMAND INFO entry: name, arity, flags, first key,
+// last key, step, acl categories, tips, key specs and subcommands
+func (c *command) info() *RESPRes {
+	flags := []*RESPRes{}
+	for _, f := range flagNames {
+		if c.flags&f.flag != 0 {
+			flags = append(flags, simpleReply(f.name))
+		}
+	}
+	if c.movableKeys != nil {
+		flags = append(flags, simpleReply("movablekeys"))
+	}
+	return arrayReply(
+		bulkReply(c.name),
+		intReply(int64(c.arity)),
+		setReply(flags...),
+		intReply(int64(c.firstKey)),
+		intReply(int64(c.lastKey)),
+		intReply(int64(c.step)),
+		setReply(c.categories()...),
+		arrayReply(),
+		arrayReply(),
+		arrayReply(),
+	)
+}
+
+func (c *command) categories() []*RESPRes {
+	cats := []*RESPRes{}
+	add := func(name string) { cats = append(cats, simpleReply("@"+name)) }
+	if c.flags&flagWrite != 0 {
+		add("write")
+	}
+	if c.flags&flagReadonly != 0 {
+		add("read")
+	}
+	switch c.group {
+	case "string", "hash", "list", "set", "sortedset", "keyspace", "connection":
+		add(c.group)
+	}
+	if c.flags&flagAdmin != 0 {
+		add("admin")
+		add("dangerous")
+	}
+	if c.flags&flagFast != 0 {
+		add("fast")
+	} else {
+		add("slow")
+	}
+	return cats
+}
+
+func (c *command) docs() *RESPRes {
+	items := []*RESPRes{
+		bulkReply("summary"), bulkReply(c.summary),
+		bulkReply("since"), bulkReply("1.0.0"),
+		bulkReply("group"), bulkReply(c.group),
+	}
+	if len(c.args) > 0 {
+		items = append(items, bulkReply("arguments"), argDocs(c.args))
+	}
+	return mapReply(items...)
+}
+
+func argDocs(args []argDoc) *RESPRes {
+	items := make([]*RESPRes, len(args))
+	for i, a := range args {
+		doc := []*RESPRes{
+			bulkReply("name"), bulkReply(a.name),
+			bulkReply("type"), bulkReply(a.typ),
+		}
+		if a.typ == "key" {
+			doc = append(doc, bulkReply("key_spec_index"), intReply(0))
+		}
+		if a.token != "" {
+			doc = append(doc, bulkReply("token"), bulkReply(a.token))
+		}
+		flags := []*RESPRes{}
+		if a.optional {
+			flags = append(flags, simpleReply("optional"))
+		}
+		if a.multiple {
+			flags = append(flags, simpleReply("multiple"))
+		}
+		if len(flags) > 0 {
+			doc = append(doc, bulkReply("flags"), setReply(flags...))
+		}
+		if len(a.sub) > 0 {
+			doc = append(doc, bulkReply("arguments"), argDocs(a.sub))
+		}
+		items[i] = mapReply(doc...)
+	}
+	return arrayReply(items...)
+}
@@ -0,0 +1,143 @@
+package protocol
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func TestCommandTable(t *testing.T) {
+	seen := map[string]bool{}
+	for _, c := range commandTable {
+		if c.name != strings.ToLower(c.name) || seen[c.name] {
+			t.Errorf("%q: names must be lowercase and unique", c.name)
+		}
+		seen[c.name] = true
+		if c.run == nil {
+			t.Errorf("%q has no handler", c.name)
+		}
+		if c.arity == 0 {
+			t.Errorf("%q has no arity", c.name)
+		}
+		if c.flags&flagWrite != 0 && c.flags&flagReadonly != 0 {
+			t.Errorf("%q can't be both write and readonly", c.name)
+		}
+		if c.flags&flagDenyOOM != 0 && c.flags&flagWrite == 0 {
+			t.Errorf("%q is denyoom but not a write", c.name)
+		}
+		if (c.firstKey == 0) != (c.step == 0) {
+			t.Errorf("%q: first key and step must be set together", c.name)
+		}
+		takesKeys := slices.ContainsFunc(c.args, func(a argDoc) bool { return a.typ == "key" })
+		if takesKeys && c.firstKey == 0 && c.movableKeys == nil {
+			t.Errorf("%q takes keys but declares none", c.name)
+		}
+		if c.summary == "" || c.group == "" {
+			t.Errorf("%q is missing its docs", c.name)
+		}
+	}
+}
+
+func TestNewRequestArity(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  error
+	}{
+		{[]string{"GET", "k"}, nil},
+		{[]string{"get"}, common.ErrWrongNumberArgs},
+		{[]string{"get", "a", "b"}, common.ErrWrongNumberArgs},
+		{[]string{"del"}, common.ErrWrongNumberArgs},
+		{[]string{"del", "a", "b", "c"}, nil},
+		{[]string{"persist", "k"}, nil},
+		{[]string{"select", "1"}, nil},
+		{[]string{"ping", "x"}, common.ErrWrongNumberArgs},
+		{[]string{"auth", "a", "b", "c"}, common.ErrWrongNumberArgs},
+		{[]string{"incrby", "k", "x"}, common.ErrInvalidIncrement},
+		{[]string{"mget", "a", "b"}, nil},
+		{[]string{"mset", "a"}, common.ErrWrongNumberArgs},
+		{[]string{"mset", "a", "1", "b"}, common.ErrWrongNumberArgs},
+		{[]string{"msetnx", "a", "1", "b", "2"}, nil},
+		{[]string{"nope"}, common.ErrUnknownCommand},
+	}
+	for _, tt := range tests {
+		req, err := NewRequest(tt.args)
+		if err != tt.err {
+			t.Errorf("NewRequest %q: expected %v, got %v", tt.args, tt.err, err)
+		}
+		if err == nil && req.cmd != strings.ToLower(tt.args[0]) {
+			t.Errorf("NewRequest %q: unexpected cmd %q", tt.args, req.cmd)
+		}
+	}
+}
+
+func TestCommandKeys(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"get", "k"}, []string{"k"}},
+		{[]string{"mset", "a", "1", "b", "2"}, []string{"a", "b"}},
+		{[]string{"blpop", "a", "b", "0"}, []string{"a", "b"}},
+		{[]string{"zunionstore", "dst", "2", "a", "b", "WEIGHTS", "1", "2"}, []string{"dst", "a", "b"}},
+		{[]string{"sintercard", "2", "a", "b", "LIMIT", "1"}, []string{"a", "b"}},
+		{[]string{"ping"}, nil},
+	} {
+		if got := lookupCommand(tt.args[0]).keys(tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected keys %q, got %q", tt.args[0], tt.want, got)
+		}
+	}
+}
+
+func TestProcessCommand(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	resp := &RESP{DBs: dbs}
+	idx := 0
+	run := func(args ...string) *RESPRes {
+		req, err := NewRequest(args)
+		if err != nil {
+			t.Fatalf("NewRequest %q failed: %v", args, err)
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			t.Fatalf("Process %q failed: %v", args, err)
+		}
+		return res
+	}
+
+	if res := run("command", "count"); res.Int() != int64(len(commandTable)) {
+		t.Errorf("Expected COMMAND COUNT %d, got %d", len(commandTable), res.Int())
+	}
+	if res := run("command"); len(res.Items()) != len(commandTable) {
+		t.Errorf("Expected one COMMAND entry per command, got %d", len(res.Items()))
+	}
+
+	list := run("command", "list").Items()
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Message() > list[i].Message() {
+			t.Fatalf("Expected COMMAND LIST to be sorted, got %q before %q", list[i-1].Message(), list[i].Message())
+		}
+	}
+
+	info := wire(resp, run("command", "info", "GET", "nope"))
+	want := "*2\r\n*10\r\n$3\r\nget\r\n:2\r\n*2\r\n+readonly\r\n+fast\r\n:1\r\n:1\r\n:1\r\n" +
+		"*3\r\n+@read\r\n+@string\r\n+@fast\r\n*0\r\n*0\r\n*0\r\n*-1\r\n"
+	if info != want {
+		t.Errorf("Unexpected COMMAND INFO\n got %q\nwant %q", info, want)
+	}
+	if info := wire(resp, run("command", "info", "sintercard")); !strings.Contains(info, "+movablekeys\r\n") {
+		t.Errorf("Expected SINTERCARD to report movable keys, got %q", info)
+	}
+
+	docs := wire(resp, run("command", "docs", "persist", "nope"))
+	if !strings.HasPrefix(docs, "*2\r\n$7\r\npersist\r\n*8\r\n$7\r\nsummary\r\n") ||
+		!strings.Contains(docs, "$5\r\ngroup\r\n$8\r\nkeyspace\r\n") {
+		t.Errorf("Unexpected COMMAND DOCS %q", docs)
+	}
+
+	if _, err := NewRequest([]string{"command", "count", "x"}); err != common.ErrUnknownSubcommand {
+		t.Errorf("Expected an unknown subcommand error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func validateHello(req *RESPReq) error {
+	if len(req.args) > 1 {
+		if _, err := strconv.Atoi(req.args[1]); err != nil {
+			return common.ErrNoProto
+		}
+	}
+	for i := 2; i < len(req.args); {
+		switch strings.ToUpper(req.args[i]) {
+		case "AUTH":
+			i += 3
+		case "SETNAME":
+			i += 2
+		default:
+			return common.ErrSyntaxError
+		}
+		if i > len(req.args) {
+			return common.ErrSyntaxError
+		}
+	}
+	return nil
+}
+
+// HELLO [protover [AUTH username password] [SETNAME clientname]] switches
+// the connection to another RESP version and replies with the server info
+func (r *RESP) processHello(req *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	proto := r.protoVersion()
+	if len(req.args) > 1 {
+		proto, _ = strconv.Atoi(req.args[1])
+		if proto != RESP2 && proto != RESP3 {
+			return errorReply(common.ErrNoProto), nil
+		}
+	}
+
+	name, setName := "", false
+	for i := 2; i < len(req.args); {
+		switch strings.ToUpper(req.args[i]) {
+		case "AUTH":
+			if err := r.authenticate(req.args[i+1], req.args[i+2]); err != nil {
+				return errorReply(err), nil
+			}
+			i += 3
+		case "SETNAME":
+			name, setName = req.args[i+1], true
+			i += 2
+		}
+	}
+	if r.Config != nil && r.Config.Get().RequirePass != "" && !r.authenticated && !r.Trusted {
+		return errorReply(common.ErrHelloNoAuth), nil
+	}
+	if setName {
+		r.clientName = name
+	}
+	r.proto = proto
+
+	return mapReply(
+		bulkReply("server"), bulkReply("gokv"),
+		bulkReply("version"), bulkReply(common.Version),
+		bulkReply("proto"), intReply(int64(proto)),
+		bulkReply("id"), intReply(r.ClientID),
+		bulkReply("mode"), bulkReply("standalone"),
+		bulkReply("role"), bulkReply("master"),
+		bulkReply("modules"), arrayReply(),
+	), nil
+}
+
+func (r *RESP) authCommand(req *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	// AUTH [username] password
+	user, pass := "default", req.args[len(req.args)-1]
+	if len(req.args) == 3 {
+		user = req.args[1]
+	}
+	if err := r.authenticate(user, pass); err != nil {
+		return errorReply(err), nil
+	}
+	return okReply(), nil
+}
+
+// authenticate checks the credentials of AUTH and HELLO AUTH, only the
+// "default" user exists
+func (r *RESP) authenticate(user, pass string) error {
+	if r.Config == nil || r.Config.Get().RequirePass == "" {
+		return common.ErrAuthNotEnabled
+	}
+	if user != "default" || pass != r.Config.Get().RequirePass {
+		return common.ErrWrongPass
+	}
+	r.authenticated = true
+	return nil
+}
+
+func (r *RESP) selectCommand(req *RESPReq, dbIndex *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	newDBIndex, err := strconv.Atoi(req.args[1])
+	if err != nil {
+		return nil, common.ErrNotIntOROutOfRange
+	}
+	if newDBIndex < 0 || newDBIndex >= len(r.DBs) {
+		return nil, common.ErrDBIndexOutOfRange
+	}
+	*dbIndex = newDBIndex
+	return okReply(), nil
+}
+
+func (r *RESP) pingCommand(_ *RESPReq, _ *int, _ *store.InMemoryStore) (*RESPRes, error) {
+	return simpleReply("PONG"), nil
+}
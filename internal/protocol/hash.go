@@ -0,0 +1,218 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// HSET and HMSET key field value [field value ...]
+func validateFieldPairs(req *RESPReq) error {
+	if len(req.args)%2 != 0 {
+		return common.ErrWrongNumberArgs
+	}
+	return nil
+}
+
+func validateHincrbyfloat(req *RESPReq) error {
+	_, err := store.ParseFloat([]byte(req.args[3]))
+	return err
+}
+
+// HRANDFIELD key [count [WITHVALUES]]
+func validateHrandfield(req *RESPReq) error {
+	if len(req.args) == 2 {
+		return nil
+	}
+	if _, err := strconv.Atoi(req.args[2]); err != nil {
+		return common.ErrNotIntOROutOfRange
+	}
+	switch {
+	case len(req.args) == 4 && strings.EqualFold(req.args[3], "WITHVALUES"):
+		return nil
+	case len(req.args) > 3:
+		return common.ErrSyntaxError
+	}
+	return nil
+}
+
+func bulkItems(items [][]byte) []*RESPRes {
+	res := make([]*RESPRes, len(items))
+	for i, item := range items {
+		if item == nil {
+			res[i] = nullReply()
+		} else {
+			res[i] = bulkReply(string(item))
+		}
+	}
+	return res
+}
+
+// hsetCommand backs HSET and HMSET, the latter replies OK
+func (r *RESP) hsetCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n := (len(req.args) - 2) / 2
+	fields := make([]string, n)
+	values := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		fields[i] = req.args[2+2*i]
+		values[i] = []byte(req.args[3+2*i])
+	}
+	added, err := mem.HSet(req.args[1], fields, values)
+	if err != nil {
+		return nil, err
+	}
+	if req.cmd == "hmset" {
+		return okReply(), nil
+	}
+	return intReply(int64(added)), nil
+}
+
+func (r *RESP) hsetnxCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	added, err := mem.HSetNX(req.args[1], req.args[2], []byte(req.args[3]))
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(added)), nil
+}
+
+func (r *RESP) hgetCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	value, err := mem.HGet(req.args[1], req.args[2])
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nullReply(), nil
+	}
+	return bulkReply(string(value)), nil
+}
+
+func (r *RESP) hmgetCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	values, err := mem.HMGet(req.args[1], req.args[2:])
+	if err != nil {
+		return nil, err
+	}
+	return arrayReply(bulkItems(values)...), nil
+}
+
+func (r *RESP) hgetallCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	items, err := mem.HGetAll(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return mapReply(bulkItems(items)...), nil
+}
+
+func (r *RESP) hdelCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	deleted, err := mem.HDel(req.args[1], req.args[2:])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(deleted)), nil
+}
+
+func (r *RESP) hexistsCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	exists, err := mem.HExists(req.args[1], req.args[2])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(exists)), nil
+}
+
+func (r *RESP) hlenCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.HLen(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) hkeysCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	fields, err := mem.HKeys(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return bulkArrayReply(fields), nil
+}
+
+func (r *RESP) hvalsCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	values, err := mem.HVals(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return arrayReply(bulkItems(values)...), nil
+}
+
+func (r *RESP) hstrlenCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.HStrLen(req.args[1], req.args[2])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) hincrbyCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	by, _ := strconv.ParseInt(req.args[3], 10, 64)
+	n, err := mem.HIncrBy(req.args[1], req.args[2], by)
+	if err != nil {
+		return nil, err
+	}
+	return intReply(n), nil
+}
+
+func (r *RESP) hincrbyfloatCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	by, _ := store.ParseFloat([]byte(req.args[3]))
+	value, err := mem.HIncrByFloat(req.args[1], req.args[2], by)
+	if err != nil {
+		return nil, err
+	}
+	return bulkReply(string(value)), nil
+}
+
+// hrandfieldCommand replies a single field without count, an array
+// otherwise. WITHVALUES pairs each field with its value: a flat array in
+// RESP2, an array of pairs in RESP3 like redis.
+func (r *RESP) hrandfieldCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	count := 1
+	if len(req.args) > 2 {
+		count, _ = strconv.Atoi(req.args[2])
+	}
+	fields, values, err := mem.HRandField(req.args[1], count)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.args) == 2 {
+		if len(fields) == 0 {
+			return nullReply(), nil
+		}
+		return bulkReply(fields[0]), nil
+	}
+	if len(req.args) == 3 {
+		return bulkArrayReply(fields), nil
+	}
+	items := make([]*RESPRes, 0, 2*len(fields))
+	for i, f := range fields {
+		if r.protoVersion() == RESP3 {
+			items = append(items, arrayReply(bulkReply(f), bulkReply(string(values[i]))))
+		} else {
+			items = append(items, bulkReply(f), bulkReply(string(values[i])))
+		}
+	}
+	return arrayReply(items...), nil
+}
+
+func (r *RESP) hscanCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	cursor, items, err := mem.HScan(req.args[1], req.scan.cursor, req.scan.match, req.scan.count)
+	if err != nil {
+		return nil, err
+	}
+	if req.scan.noValues {
+		fields := make([][]byte, 0, len(items)/2)
+		for i := 0; i < len(items); i += 2 {
+			fields = append(fields, items[i])
+		}
+		items = fields
+	}
+	return scanReply(cursor, items), nil
+}
@@ -0,0 +1,99 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func TestProcessHashFamily(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	resp := &RESP{DBs: dbs}
+	idx := 0
+	run := func(args ...string) string {
+		req, err := NewRequest(args)
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		return wire(resp, res)
+	}
+
+	wrongType := "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"hset", "h", "a", "1", "b", "2"}, ":2\r\n"},
+		{[]string{"hset", "h", "a", "10", "c", "3"}, ":1\r\n"},
+		{[]string{"hset", "h", "a"}, "-ERR wrong number of arguments\r\n"},
+		{[]string{"hset", "h", "a", "1", "b"}, "-ERR wrong number of arguments\r\n"},
+		{[]string{"hget", "h", "a"}, "$2\r\n10\r\n"},
+		{[]string{"hget", "h", "missing"}, "$-1\r\n"},
+		{[]string{"hget", "missing", "a"}, "$-1\r\n"},
+		{[]string{"hmget", "h", "b", "x", "c"}, "*3\r\n$1\r\n2\r\n$-1\r\n$1\r\n3\r\n"},
+		{[]string{"hlen", "h"}, ":3\r\n"},
+		{[]string{"hexists", "h", "b"}, ":1\r\n"},
+		{[]string{"hstrlen", "h", "a"}, ":2\r\n"},
+		{[]string{"hsetnx", "h", "a", "x"}, ":0\r\n"},
+		{[]string{"hsetnx", "h", "d", "4"}, ":1\r\n"},
+		{[]string{"hdel", "h", "a", "b", "x"}, ":2\r\n"},
+		{[]string{"hincrby", "h", "c", "5"}, ":8\r\n"},
+		{[]string{"hincrby", "h", "n", "-3"}, ":-3\r\n"},
+		{[]string{"hincrby", "h", "c", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"hincrby", "h", "n", "-9223372036854775807"}, "-ERR increment or decrement would overflow\r\n"},
+		{[]string{"hincrbyfloat", "h", "f", "1.5"}, "$3\r\n1.5\r\n"},
+		{[]string{"hincrbyfloat", "h", "f", "1e2"}, "$5\r\n101.5\r\n"},
+		{[]string{"hset", "h", "s", "abc"}, ":1\r\n"},
+		{[]string{"hincrby", "h", "s", "1"}, "-ERR hash value is not an integer\r\n"},
+		{[]string{"hincrbyfloat", "h", "s", "1"}, "-ERR hash value is not a float\r\n"},
+		{[]string{"hdel", "h", "c", "d", "n", "f", "s"}, ":5\r\n"},
+		{[]string{"exists", "h"}, ":0\r\n"},
+		{[]string{"hgetall", "missing"}, "*0\r\n"},
+		{[]string{"hset", "one", "f", "v"}, ":1\r\n"},
+		{[]string{"hgetall", "one"}, "*2\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{[]string{"hkeys", "one"}, "*1\r\n$1\r\nf\r\n"},
+		{[]string{"hvals", "one"}, "*1\r\n$1\r\nv\r\n"},
+		{[]string{"hrandfield", "one"}, "$1\r\nf\r\n"},
+		{[]string{"hrandfield", "missing"}, "$-1\r\n"},
+		{[]string{"hrandfield", "one", "-2"}, "*2\r\n$1\r\nf\r\n$1\r\nf\r\n"},
+		{[]string{"hrandfield", "one", "5", "withvalues"}, "*2\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{[]string{"hrandfield", "one", "1", "values"}, "-ERR syntax error\r\n"},
+		{[]string{"hscan", "one", "0"}, "*2\r\n$1\r\n0\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{[]string{"hscan", "one", "0", "NOVALUES"}, "*2\r\n$1\r\n0\r\n*1\r\n$1\r\nf\r\n"},
+		{[]string{"hscan", "one", "0", "MATCH", "x*"}, "*2\r\n$1\r\n0\r\n*0\r\n"},
+		{[]string{"hscan", "one", "-1"}, "-ERR invalid cursor\r\n"},
+		{[]string{"hscan", "one", "0", "COUNT", "0"}, "-ERR syntax error\r\n"},
+
+		// strings and hashes don't mix
+		{[]string{"set", "str", "v"}, "+OK\r\n"},
+		{[]string{"hget", "str", "f"}, wrongType},
+		{[]string{"hset", "str", "f", "v"}, wrongType},
+		{[]string{"get", "one"}, wrongType},
+		{[]string{"append", "one", "x"}, wrongType},
+		{[]string{"incr", "one"}, wrongType},
+		{[]string{"set", "one", "x", "GET"}, wrongType},
+		{[]string{"mget", "one", "str"}, "*2\r\n$-1\r\n$1\r\nv\r\n"},
+		{[]string{"set", "one", "x"}, "+OK\r\n"},
+		{[]string{"get", "one"}, "$1\r\nx\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args...); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", strings.Join(tt.args, " "), tt.want, got)
+		}
+	}
+
+	// RESP3 replies HGETALL as a map and pairs HRANDFIELD WITHVALUES
+	run("hset", "h3", "f", "v")
+	resp.proto = RESP3
+	if got := run("hgetall", "h3"); got != "%1\r\n$1\r\nf\r\n$1\r\nv\r\n" {
+		t.Errorf("Unexpected RESP3 HGETALL %q", got)
+	}
+	if got := run("hrandfield", "h3", "1", "WITHVALUES"); got != "*1\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n" {
+		t.Errorf("Unexpected RESP3 HRANDFIELD %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package protocol
+
+import (
+	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+// inline commands are plain text lines like `SET key "hello world"`, sent by
+// telnet, nc or shell scripts instead of a RESP array
+
+// maxInlineLen bounds a single line of the protocol, an inline command or a
+// RESP header, so a client can not grow the buffer without sending a newline
+const maxInlineLen = 64 * 1024
+
+// splitInline splits an inline command into its arguments with the quoting
+// rules of redis-cli: "double quotes" understand \n \r \t \b \a \\ \" and
+// \xHH escapes, 'single quotes' only \'. a closing quote must be followed by
+// a space or the end of the line.
+func splitInline(line string) ([]string, error) {
+	var args []string
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var arg strings.Builder
+		inDouble, inSingle := false, false
+		for done := false; !done; {
+			if i == len(line) {
+				if inDouble || inSingle {
+					return nil, common.ErrUnbalancedQuotes
+				}
+				break
+			}
+			c := line[i]
+			switch {
+			case inDouble:
+				if c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]) {
+					arg.WriteByte(hexVal(line[i+2])<<4 | hexVal(line[i+3]))
+					i += 3
+				} else if c == '\\' && i+1 < len(line) {
+					i++
+					arg.WriteByte(unescape(line[i]))
+				} else if c == '"' {
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, common.ErrUnbalancedQuotes
+					}
+					done = true
+				} else {
+					arg.WriteByte(c)
+				}
+			case inSingle:
+				if c == '\\' && i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					arg.WriteByte('\'')
+				} else if c == '\'' {
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, common.ErrUnbalancedQuotes
+					}
+					done = true
+				} else {
+					arg.WriteByte(c)
+				}
+			default:
+				switch c {
+				case ' ', '\t', '\r', '\n', '\v', '\f', 0:
+					done = true
+				case '"':
+					inDouble = true
+				case '\'':
+					inSingle = true
+				default:
+					arg.WriteByte(c)
+				}
+			}
+			i++
+		}
+		args = append(args, arg.String())
+	}
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexVal(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'a':
+		return '\a'
+	}
+	return c
+}
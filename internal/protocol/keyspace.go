@@ -0,0 +1,94 @@
+package protocol
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func (r *RESP) delCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	return intReply(int64(mem.Del(req.args[1:]))), nil
+}
+
+func (r *RESP) existsCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	return intReply(int64(mem.Exists(req.args[1:]))), nil
+}
+
+func (r *RESP) typeCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	return simpleReply(mem.Type(req.args[1])), nil
+}
+
+func (r *RESP) ttlCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	ttl, _ := mem.TTL(req.args[1])
+	return intReply(int64(ttl)), nil
+}
+
+func (r *RESP) expireCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	seconds, err := strconv.Atoi(req.args[2])
+	// the deadline must fit in int64 ms, or it wraps to the past
+	if err != nil || seconds < 0 || int64(seconds) > (math.MaxInt64-time.Now().UnixMilli())/1000 {
+		return nil, common.ErrInvalidExpireTime
+	}
+	expired, _ := mem.Expire(req.args[1], seconds)
+	return intReply(int64(expired)), nil
+}
+
+func (r *RESP) pexpireatCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	unixMs, _ := strconv.ParseInt(req.args[2], 10, 64)
+	expired, _ := mem.ExpireAt(req.args[1], unixMs)
+	return intReply(int64(expired)), nil
+}
+
+func (r *RESP) persistCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	persisted, _ := mem.Persist(req.args[1])
+	return intReply(int64(persisted)), nil
+}
+
+type scanArgs struct {
+	cursor   uint64
+	match    string
+	count    int
+	noValues bool
+}
+
+// HSCAN, SSCAN and ZSCAN key cursor [MATCH pattern] [COUNT count], plus
+// NOVALUES for HSCAN
+func validateScan(req *RESPReq) error {
+	cursor, err := strconv.ParseUint(req.args[2], 10, 64)
+	if err != nil {
+		return common.ErrInvalidCursor
+	}
+	req.scan = scanArgs{cursor: cursor, count: 10}
+	for i := 3; i < len(req.args); i++ {
+		opt := strings.ToUpper(req.args[i])
+		switch {
+		case opt == "MATCH" && i+1 < len(req.args):
+			i++
+			req.scan.match = req.args[i]
+		case opt == "COUNT" && i+1 < len(req.args):
+			i++
+			count, err := strconv.Atoi(req.args[i])
+			if err != nil {
+				return common.ErrNotIntOROutOfRange
+			}
+			if count < 1 {
+				return common.ErrSyntaxError
+			}
+			req.scan.count = count
+		case opt == "NOVALUES" && req.cmd == "hscan":
+			req.scan.noValues = true
+		default:
+			return common.ErrSyntaxError
+		}
+	}
+	return nil
+}
+
+// scanReply is the cursor of the next call and the batch
+func scanReply(cursor uint64, items [][]byte) *RESPRes {
+	return arrayReply(bulkReply(strconv.FormatUint(cursor, 10)), arrayReply(bulkItems(items)...))
+}
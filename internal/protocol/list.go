@@ -0,0 +1,281 @@
+package protocol
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// LPOP and RPOP key [count]
+func validatePop(req *RESPReq) error {
+	if len(req.args) > 3 {
+		return common.ErrWrongNumberArgs
+	}
+	if len(req.args) == 3 {
+		count, err := strconv.Atoi(req.args[2])
+		if err != nil {
+			return common.ErrNotIntOROutOfRange
+		}
+		if count < 0 {
+			return common.ErrMustBePositive
+		}
+	}
+	return nil
+}
+
+// LINSERT key BEFORE | AFTER pivot element
+func validateLinsert(req *RESPReq) error {
+	switch strings.ToUpper(req.args[2]) {
+	case "BEFORE", "AFTER":
+		return nil
+	}
+	return common.ErrSyntaxError
+}
+
+// LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+func validateLpos(req *RESPReq) error {
+	_, _, _, err := lposOptions(req.args)
+	return err
+}
+
+func lposOptions(args []string) (rank, count, maxLen int, err error) {
+	rank = 1
+	for i := 3; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return 0, 0, 0, common.ErrSyntaxError
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			return 0, 0, 0, common.ErrNotIntOROutOfRange
+		}
+		switch strings.ToUpper(args[i]) {
+		case "RANK":
+			if n == 0 {
+				return 0, 0, 0, common.ErrRankZero
+			}
+			rank = n
+		case "COUNT":
+			if n < 0 {
+				return 0, 0, 0, common.ErrCountNegative
+			}
+			count = n
+		case "MAXLEN":
+			if n < 0 {
+				return 0, 0, 0, common.ErrMaxLenNegative
+			}
+			maxLen = n
+		default:
+			return 0, 0, 0, common.ErrSyntaxError
+		}
+	}
+	return rank, count, maxLen, nil
+}
+
+// LMOVE source destination LEFT | RIGHT LEFT | RIGHT, and BLMOVE with a
+// timeout after the directions
+func validateLmove(req *RESPReq) error {
+	for _, dir := range req.args[3:5] {
+		if d := strings.ToUpper(dir); d != "LEFT" && d != "RIGHT" {
+			return common.ErrSyntaxError
+		}
+	}
+	if req.cmd == "blmove" {
+		_, err := parseTimeout(req.args[5])
+		return err
+	}
+	return nil
+}
+
+// BLPOP and BRPOP key [key ...] timeout
+func validateBlockingPop(req *RESPReq) error {
+	_, err := parseTimeout(req.args[len(req.args)-1])
+	return err
+}
+
+// parseTimeout reads the timeout of a blocking command, seconds with
+// decimals, 0 to wait for ever
+func parseTimeout(s string) (time.Duration, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || f > math.MaxInt64/float64(time.Second) {
+		return 0, common.ErrTimeoutNotFloat
+	}
+	if f < 0 {
+		return 0, common.ErrTimeoutNegative
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+func elementArgs(args []string) [][]byte {
+	values := make([][]byte, len(args))
+	for i, arg := range args {
+		values[i] = []byte(arg)
+	}
+	return values
+}
+
+// pushCommand backs LPUSH and RPUSH
+func (r *RESP) pushCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	push := mem.RPush
+	if req.cmd == "lpush" {
+		push = mem.LPush
+	}
+	n, err := push(req.args[1], elementArgs(req.args[2:]))
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+// popCommand backs LPOP and RPOP: one element without count, an array with
+func (r *RESP) popCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	count := 1
+	if len(req.args) == 3 {
+		count, _ = strconv.Atoi(req.args[2])
+	}
+	pop := mem.RPop
+	if req.cmd == "lpop" {
+		pop = mem.LPop
+	}
+	values, err := pop(req.args[1], count)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(req.args) == 3 && values == nil:
+		return nullArrayReply(), nil
+	case len(req.args) == 3:
+		return arrayReply(bulkItems(values)...), nil
+	case len(values) == 0:
+		return nullReply(), nil
+	}
+	return bulkReply(string(values[0])), nil
+}
+
+func (r *RESP) llenCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.LLen(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) lrangeCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	start, _ := strconv.Atoi(req.args[2])
+	stop, _ := strconv.Atoi(req.args[3])
+	values, err := mem.LRange(req.args[1], start, stop)
+	if err != nil {
+		return nil, err
+	}
+	return arrayReply(bulkItems(values)...), nil
+}
+
+func (r *RESP) lindexCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	index, _ := strconv.Atoi(req.args[2])
+	value, err := mem.LIndex(req.args[1], index)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nullReply(), nil
+	}
+	return bulkReply(string(value)), nil
+}
+
+func (r *RESP) lsetCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	index, _ := strconv.Atoi(req.args[2])
+	if err := mem.LSet(req.args[1], index, []byte(req.args[3])); err != nil {
+		return nil, err
+	}
+	return okReply(), nil
+}
+
+func (r *RESP) linsertCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	before := strings.EqualFold(req.args[2], "BEFORE")
+	n, err := mem.LInsert(req.args[1], before, []byte(req.args[3]), []byte(req.args[4]))
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) lremCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	count, _ := strconv.Atoi(req.args[2])
+	n, err := mem.LRem(req.args[1], count, []byte(req.args[3]))
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) ltrimCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	start, _ := strconv.Atoi(req.args[2])
+	stop, _ := strconv.Atoi(req.args[3])
+	if err := mem.LTrim(req.args[1], start, stop); err != nil {
+		return nil, err
+	}
+	return okReply(), nil
+}
+
+// lposCommand replies an index or null, an array of indexes with COUNT
+func (r *RESP) lposCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	rank, count, maxLen, _ := lposOptions(req.args)
+	withCount := false
+	for i := 3; i < len(req.args); i += 2 {
+		withCount = withCount || strings.EqualFold(req.args[i], "COUNT")
+	}
+	if !withCount {
+		count = 1
+	}
+	matches, err := mem.LPos(req.args[1], []byte(req.args[2]), rank, count, maxLen)
+	if err != nil {
+		return nil, err
+	}
+	if withCount {
+		items := make([]*RESPRes, len(matches))
+		for i, m := range matches {
+			items[i] = intReply(int64(m))
+		}
+		return arrayReply(items...), nil
+	}
+	if len(matches) == 0 {
+		return nullReply(), nil
+	}
+	return intReply(int64(matches[0])), nil
+}
+
+// lmoveCommand backs LMOVE and BLMOVE, the latter blocks on the source
+func (r *RESP) lmoveCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	fromFront := strings.EqualFold(req.args[3], "LEFT")
+	toFront := strings.EqualFold(req.args[4], "LEFT")
+	value, err := mem.LMove(req.args[1], req.args[2], fromFront, toFront)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		if req.cmd == "blmove" {
+			timeout, _ := parseTimeout(req.args[5])
+			return blockedReply(req.args[1:2], timeout), nil
+		}
+		return nullReply(), nil
+	}
+	return bulkReply(string(value)), nil
+}
+
+// blockingPopCommand backs BLPOP and BRPOP: the key and the element popped
+// from the first non empty list
+func (r *RESP) blockingPopCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	keys := req.args[1 : len(req.args)-1]
+	key, value, err := mem.PopFirst(keys, req.cmd == "blpop")
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		timeout, _ := parseTimeout(req.args[len(req.args)-1])
+		return blockedReply(keys, timeout), nil
+	}
+	return arrayReply(bulkReply(key), bulkReply(string(value))), nil
+}
@@ -0,0 +1,78 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func TestProcessListFamily(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	resp := &RESP{DBs: dbs}
+	idx := 0
+	run := func(args ...string) string {
+		req, err := NewRequest(args)
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		return wire(resp, res)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"rpush", "l", "a", "b", "c"}, ":3\r\n"},
+		{[]string{"lpush", "l", "z"}, ":4\r\n"},
+		{[]string{"lrange", "l", "0", "-1"}, "*4\r\n$1\r\nz\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{[]string{"lrange", "l", "x", "1"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"llen", "l"}, ":4\r\n"},
+		{[]string{"lindex", "l", "1"}, "$1\r\na\r\n"},
+		{[]string{"lindex", "l", "9"}, "$-1\r\n"},
+		{[]string{"lset", "l", "0", "y"}, "+OK\r\n"},
+		{[]string{"lset", "l", "9", "y"}, "-ERR index out of range\r\n"},
+		{[]string{"lset", "missing", "0", "y"}, "-ERR no such key\r\n"},
+		{[]string{"linsert", "l", "before", "b", "a"}, ":5\r\n"},
+		{[]string{"linsert", "l", "middle", "b", "a"}, "-ERR syntax error\r\n"},
+		{[]string{"linsert", "missing", "after", "b", "a"}, ":0\r\n"},
+		{[]string{"lpos", "l", "a"}, ":1\r\n"},
+		{[]string{"lpos", "l", "a", "RANK", "-1"}, ":2\r\n"},
+		{[]string{"lpos", "l", "a", "COUNT", "0"}, "*2\r\n:1\r\n:2\r\n"},
+		{[]string{"lpos", "l", "nope"}, "$-1\r\n"},
+		{[]string{"lpos", "l", "a", "RANK", "0"}, "-ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list\r\n"},
+		{[]string{"lpos", "l", "a", "COUNT"}, "-ERR syntax error\r\n"},
+		{[]string{"lrem", "l", "0", "a"}, ":2\r\n"},
+		{[]string{"ltrim", "l", "0", "1"}, "+OK\r\n"},
+		{[]string{"lrange", "l", "0", "-1"}, "*2\r\n$1\r\ny\r\n$1\r\nb\r\n"},
+		{[]string{"lmove", "l", "m", "LEFT", "RIGHT"}, "$1\r\ny\r\n"},
+		{[]string{"lmove", "l", "m", "UP", "RIGHT"}, "-ERR syntax error\r\n"},
+		{[]string{"lmove", "missing", "m", "LEFT", "RIGHT"}, "$-1\r\n"},
+		{[]string{"rpop", "l"}, "$1\r\nb\r\n"},
+		{[]string{"rpop", "l"}, "$-1\r\n"},
+		{[]string{"lpop", "l", "2"}, "*-1\r\n"},
+		{[]string{"rpush", "l", "1", "2", "3"}, ":3\r\n"},
+		{[]string{"lpop", "l", "2"}, "*2\r\n$1\r\n1\r\n$1\r\n2\r\n"},
+		{[]string{"lpop", "l", "-1"}, "-ERR value is out of range, must be positive\r\n"},
+		{[]string{"lpop", "l", "1", "2"}, "-ERR wrong number of arguments\r\n"},
+		{[]string{"blpop", "missing", "l", "0"}, "*2\r\n$1\r\nl\r\n$1\r\n3\r\n"},
+		{[]string{"blpop", "l", "-1"}, "-ERR timeout is negative\r\n"},
+		{[]string{"blpop", "l", "soon"}, "-ERR timeout is not a float or out of range\r\n"},
+		{[]string{"blmove", "m", "n", "RIGHT", "LEFT", "0.5"}, "$1\r\ny\r\n"},
+		// a caller that can't block gets the timeout reply
+		{[]string{"brpop", "l", "0"}, "*-1\r\n"},
+		{[]string{"blmove", "l", "n", "RIGHT", "LEFT", "0"}, "*-1\r\n"},
+		{[]string{"set", "s", "v"}, "+OK\r\n"},
+		{[]string{"lpush", "s", "v"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"blpop", "s", "0"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args...); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", strings.Join(tt.args, " "), tt.want, got)
+		}
+	}
+}
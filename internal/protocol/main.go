@@ -3,13 +3,11 @@ package protocol
 import (
 	"bufio"
 
+	"github.com/B-AJ-Amar/gokv/internal/config"
+	"github.com/B-AJ-Amar/gokv/internal/persistence"
 	"github.com/B-AJ-Amar/gokv/internal/store"
 )
 
-var (
-	allowedCommands = [...]string{"set", "get", "del", "incr", "incrby", "exists", "ping", "select", "ttl", "expire", "persist", "hello"}
-)
-
 const (
 	SimpleStrType   = '+'
 	SimpleErrorType = '-'
@@ -18,13 +16,11 @@ const (
 	ArrayType       = '*'
 )
 
+// SHUTDOWN modes
 const (
-	SimpleRes    = iota // +MESSAGE\r\n
-	ErrorRes            // -ERROR\r\n
-	BulkStrRes          // $n\r\nXXX\r\n
-	NotExistsRes        // $-1\r\n
-	IntRes              // :1\r\n
-	SpecialRes          // to send directly hardcoded response
+	ShutdownDefault = iota // save only if save-on-shutdown is set
+	ShutdownSave
+	ShutdownNoSave
 )
 
 type RESPReq struct {
@@ -32,18 +28,50 @@ type RESPReq struct {
 	argsLen int
 	args    []string
 	setArgs store.SetArgs
+	scan    scanArgs // HSCAN and the other SCAN commands
 }
 
-type RESPRes struct {
-	msgType int
-	message string
+type Protocol interface {
+	Parse(reader *bufio.Reader) (*RESPReq, error)
+	Process(req *RESPReq, dbIndex *int, mem *store.InMemoryStore) (*RESPRes, error)
+	Send(writer *bufio.Writer, res *RESPRes) error
+	SendError(writer *bufio.Writer, err error) error
 }
 
-type Protocol interface {
-	Parse(reader *bufio.Reader, dbIndex *int) (*RESPReq, error)
-	Process(req *RESPReq, mem *store.InMemoryStore) (*RESPRes, error)
-	Send(writer *bufio.Writer, res *RESPRes) (int, error)
-	SendError(msg string)
+var _ Protocol = (*RESP)(nil)
+
+// RESP holds the state shared by the commands of one client connection,
+// the zero value works for commands that only touch the selected database
+type RESP struct {
+	DBs   []*store.InMemoryStore   // every logical database, used by server wide commands
+	Saver *persistence.Snapshotter // nil when persistence is disabled
+	AOF   *persistence.AOF         // nil when appendonly is off
+	// nil for no auth and no limits
+	Config *config.Live
+	Stats  *Stats // nil to skip the stats and the slowlog
+	// remote address, shown in the slowlog
+	ClientAddr string
+	// stops the server, nil when SHUTDOWN is not available
+	Shutdown func(mode int) error
+	// in-process caller, never asked for a password
+	Trusted bool
+	// unique id of the connection, reported by HELLO
+	ClientID int64
+
+	authenticated bool
+	proto         int    // negotiated RESP version, 0 until HELLO means 2
+	clientName    string // set by HELLO SETNAME
 }
 
-type RESP struct{}
+// RESP versions a client can negotiate with HELLO
+const (
+	RESP2 = 2
+	RESP3 = 3
+)
+
+func (r *RESP) protoVersion() int {
+	if r.proto == 0 {
+		return RESP2
+	}
+	return r.proto
+}
@@ -2,171 +2,162 @@ package protocol
 
 import (
 	"bufio"
+	"io"
 	"strconv"
 	"strings"
 
 	"github.com/B-AJ-Amar/gokv/internal/common"
-	"github.com/B-AJ-Amar/gokv/internal/store"
 )
 
-func getExpireType(arg string) int8 {
-	switch arg {
-	case "EX":
-		return store.ExpireEX
-	case "PX":
-		return store.ExpirePX
-	case "EXAT":
-		return store.ExpireEXAT
-	case "PXAT":
-		return store.ExpirePXAT
-	case "KEEPTTL":
-		return store.ExpireKEEPTTL
-	default:
-		return store.ExpireNone
-	}
-}
-
+// Parse reads and validates one request, see ReadArgs and NewRequest for the
+// two kinds of errors it returns
 func (r *RESP) Parse(reader *bufio.Reader) (*RESPReq, error) {
-	req := RESPReq{}
-	msg, err := reader.ReadString('\n')
-	argLen := 0
+	args, err := r.ReadArgs(reader)
 	if err != nil {
-		return nil, common.ErrInvalidFormat
+		return nil, err
 	}
-	msg = strings.TrimRight(msg, "\r\n")
+	return NewRequest(args)
+}
 
-	if msg[0] == '*' {
-		req.argsLen, err = strconv.Atoi(msg[1:])
-		if err != nil {
-			return nil, common.ErrParseLen
-		}
-	} else {
-		return nil, common.ErrInvalidFormat
+// ReadArgs reads the arguments of one request off the wire. its errors are
+// fatal: after malformed framing or a failed read the stream can't be
+// resynced, so the connection has to be closed.
+func (r *RESP) ReadArgs(reader *bufio.Reader) ([]string, error) {
+	return readArgs(reader, r.maxBulkLen())
+}
+
+func (r *RESP) maxBulkLen() int64 {
+	if r.Config == nil {
+		return common.DefaultProtoMaxBulkLen
 	}
+	return r.Config.Get().ProtoMaxBulkLen
+}
 
-	for i := 0; i < req.argsLen; i++ {
-		// "$N" len of the next arg
-		msg, err := reader.ReadString('\n')
+// readArgs reads one command off the wire, either a RESP array of bulk
+// strings or an inline command. bulk strings are read by length so they may
+// hold any byte, CR and LF included.
+func readArgs(reader *bufio.Reader, maxBulkLen int64) ([]string, error) {
+	argsLen := 0
+	for argsLen == 0 {
+		line, err := readLine(reader)
 		if err != nil {
-			return nil, common.ErrInvalidFormat
+			return nil, err
 		}
-		msg = strings.TrimRight(msg, "\r\n")
-		if msg[0] == '$' {
-			argLen, err = strconv.Atoi(msg[1:])
+		if len(line) > 0 && line[0] == '*' {
+			argsLen, err = strconv.Atoi(line[1:])
 			if err != nil {
 				return nil, common.ErrParseLen
 			}
-		} else {
-			return nil, common.ErrInvalidFormat
+			if argsLen < 0 || argsLen > common.MaxMultibulkLen {
+				return nil, common.ErrInvalidMultibulkLen
+			}
+			// like redis, an empty array is skipped
+			continue
 		}
-		// the arg
-		msgArg, err := reader.ReadString('\n')
+		args, err := splitInline(line)
 		if err != nil {
-			return nil, common.ErrInvalidFormat
+			return nil, err
 		}
-		msgArg = strings.TrimRight(msgArg, "\r\n")
-
-		if argLen != len(msgArg) {
-			return nil, common.ErrWrongArgLen
+		// like redis, empty lines are skipped
+		if len(args) > 0 {
+			return args, nil
 		}
-		req.args = append(req.args, msgArg)
-
 	}
-	cmd := strings.ToLower(req.args[0])
 
-	switch cmd {
-	case "set":
-		if len(req.args) < 3 || len(req.args) > 7 {
-			return nil, common.ErrWrongNumberArgs
-		}
-		if len(req.args) > 3 {
-			i := 3
-			for i < len(req.args) {
-				arg := strings.ToUpper(req.args[i])
-				if arg == "EX" || arg == "PX" || arg == "EXAT" || arg == "PXAT" {
-					req.setArgs.ExpType = getExpireType(arg)
-					if i+1 >= len(req.args) {
-						return nil, common.ErrWrongNumberArgs
-					}
-					val, err := strconv.Atoi(req.args[i+1])
-					if err != nil {
-						return nil, common.ErrInvalidExpireTime
-					}
-					req.setArgs.ExpVal = val
-					i += 2
-				} else if arg == "NX" {
-					req.setArgs.NX_XX = 1
-					i++
-				} else if arg == "XX" {
-					req.setArgs.NX_XX = 2
-					i++
-				} else if arg == "KEEPTTL" {
-					req.setArgs.KeepTTL = true
-					i++
-				} else if arg == "GET" {
-					req.setArgs.Get = true
-					i++
-				} else {
-					// Unknown flag or argument type
-					return nil, common.ErrWrongNumberArgs
-				}
-			}
-		}
-	case "get":
-		if len(req.args) != 2 {
-			return nil, common.ErrWrongNumberArgs
-		}
-	case "del":
-		if len(req.args) < 2 {
-			return nil, common.ErrWrongNumberArgs
-		}
-	case "exists":
-		if len(req.args) < 2 {
-			return nil, common.ErrWrongNumberArgs
-		}
-	case "incr":
-		if len(req.args) != 2 {
-			return nil, common.ErrWrongNumberArgs
-		}
-	case "incrby":
-		if len(req.args) != 3 {
-			return nil, common.ErrWrongNumberArgs
-		}
-		if _, err := strconv.Atoi(req.args[2]); err != nil {
-			return nil, common.ErrInvalidIncrement
-		}
-	case "decr":
-		if len(req.args) != 2 {
-			return nil, common.ErrWrongNumberArgs
-		}
-	case "decrby":
-		if len(req.args) != 3 {
-			return nil, common.ErrWrongNumberArgs
+	args := make([]string, 0, min(argsLen, 64))
+	for i := 0; i < argsLen; i++ {
+		// "$N" len of the next arg
+		msg, err := readLine(reader)
+		if err != nil {
+			return nil, err
 		}
-		if _, err := strconv.Atoi(req.args[2]); err != nil {
-			return nil, common.ErrInvalidDecrement
+		if len(msg) == 0 || msg[0] != '$' {
+			return nil, common.ErrInvalidFormat
 		}
-	case "ttl":
-		if len(req.args) != 2 {
-			return nil, common.ErrWrongNumberArgs
+		argLen, err := strconv.ParseInt(msg[1:], 10, 64)
+		if err != nil {
+			return nil, common.ErrParseLen
 		}
-	case "expire":
-		if len(req.args) != 3 {
-			return nil, common.ErrWrongNumberArgs
+		if argLen < 0 || argLen > maxBulkLen {
+			return nil, common.ErrInvalidBulkLen
 		}
-		if _, err := strconv.Atoi(req.args[2]); err != nil {
-			return nil, common.ErrInvalidExpireTime
+		arg, err := readBulk(reader, argLen)
+		if err != nil {
+			return nil, err
 		}
-	case "ping":
-		if len(req.args) != 1 {
-			return nil, common.ErrWrongNumberArgs
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// readLine reads a CRLF (or bare LF) terminated line of at most
+// maxInlineLen bytes and strips the line ending
+func readLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxInlineLen {
+			return "", common.ErrInlineTooBig
+		}
+		if err == nil {
+			break
+		}
+		if err != bufio.ErrBufferFull {
+			return "", common.ErrInvalidFormat
 		}
-	default:
-		// Unknown command, protocol error
-		return nil, common.ErrUnknownCommand
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// bulkChunk bounds what is allocated ahead of the data actually received,
+// so a client can not make us reserve proto-max-bulk-len with a header alone
+const bulkChunk = 1 << 20
+
+// readBulk reads the n bytes of a bulk string and its trailing CRLF
+func readBulk(reader *bufio.Reader, n int64) (string, error) {
+	if n <= bulkChunk {
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(reader, buf); err != nil || buf[n] != '\r' || buf[n+1] != '\n' {
+			return "", common.ErrWrongArgLen
+		}
+		return string(buf[:n]), nil
 	}
 
-	req.cmd = cmd
+	// big values grow with the data that actually arrives
+	var b strings.Builder
+	b.Grow(bulkChunk)
+	if _, err := io.CopyN(&b, reader, n); err != nil {
+		return "", common.ErrWrongArgLen
+	}
+	var crlf [2]byte
+	if _, err := io.ReadFull(reader, crlf[:]); err != nil || crlf != [2]byte{'\r', '\n'} {
+		return "", common.ErrWrongArgLen
+	}
+	return b.String(), nil
+}
 
+// NewRequest validates a command given as its raw arguments (name first)
+// against the command table, it is shared by the network parser, the AOF
+// replay and the embedded API. its errors are command errors (unknown
+// command, arity, syntax, range): the request was read completely, so the
+// client just gets the error and the connection keeps serving.
+func NewRequest(args []string) (*RESPReq, error) {
+	if len(args) == 0 {
+		return nil, common.ErrInvalidFormat
+	}
+	c := lookupCommand(args[0])
+	if c == nil {
+		return nil, common.ErrUnknownCommand
+	}
+	if err := c.checkArity(len(args)); err != nil {
+		return nil, err
+	}
+	req := RESPReq{cmd: c.name, argsLen: len(args), args: args}
+	if c.validate != nil {
+		if err := c.validate(&req); err != nil {
+			return nil, err
+		}
+	}
 	return &req, nil
 }
@@ -4,7 +4,11 @@ import (
 	"testing"
 
 	"bufio"
+	"errors"
+	"strconv"
 	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
 )
 
 func TestParseSetxNX(t *testing.T) {
@@ -194,3 +198,151 @@ func TestParseRESPCommands(t *testing.T) {
 		}
 	}
 }
+
+// encodeArgs is what a client sends for args
+func encodeArgs(args []string) string {
+	msg := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		msg += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	return msg
+}
+
+func TestParseBinarySafe(t *testing.T) {
+	values := []string{
+		"",
+		"line\nbreak",
+		"\r\n\r\n",
+		"$3\r\nfoo\r\n",
+		"\x00\xff\x1f\x8b",
+		strings.Repeat("ab\r\n", 1<<20), // 4mb
+	}
+	for _, v := range values {
+		reader := bufio.NewReader(strings.NewReader(encodeArgs([]string{"SET", "k", v})))
+		req, err := (&RESP{}).Parse(reader)
+		if err != nil {
+			t.Fatalf("Parse failed for a %d bytes value: %v", len(v), err)
+		}
+		if req.args[2] != v {
+			t.Fatalf("Expected the value to round trip, got %d bytes instead of %d", len(req.args[2]), len(v))
+		}
+	}
+}
+
+func TestParseBulkErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"*1\r\n$-1\r\n", common.ErrInvalidBulkLen},
+		{"*-1\r\n", common.ErrInvalidMultibulkLen},
+		{"*1\r\n$" + strconv.Itoa(common.DefaultProtoMaxBulkLen+1) + "\r\n", common.ErrInvalidBulkLen},
+		{"*" + strconv.Itoa(common.MaxMultibulkLen+1) + "\r\n", common.ErrInvalidMultibulkLen},
+		{"*1\r\n$4\r\nPING\r\n", nil},
+		{"*1\r\n$4\r\nPINGxx", common.ErrWrongArgLen},   // no CRLF after the data
+		{"*1\r\n$5\r\nPING\r\n", common.ErrWrongArgLen}, // shorter than announced
+		{"*1\r\n$x\r\n", common.ErrParseLen},
+	}
+	for _, tt := range tests {
+		_, err := readArgs(bufio.NewReader(strings.NewReader(tt.input)), common.DefaultProtoMaxBulkLen)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Expected %v for %q, got %v", tt.want, tt.input, err)
+		}
+	}
+}
+
+// FuzzParseArgs checks that any list of arguments survives an encode/parse
+// round trip, whatever bytes they hold
+func FuzzParseArgs(f *testing.F) {
+	f.Add("SET", "key", "value")
+	f.Add("GET", "", "\r\n")
+	f.Add("\n", "$1\r\n", "*2\r\n")
+	f.Fuzz(func(t *testing.T, a, b, c string) {
+		args := []string{a, b, c}
+		got, err := readArgs(bufio.NewReader(strings.NewReader(encodeArgs(args))), common.DefaultProtoMaxBulkLen)
+		if err != nil {
+			t.Fatalf("readArgs failed for %q: %v", args, err)
+		}
+		if len(got) != len(args) {
+			t.Fatalf("Expected %d args, got %d", len(args), len(got))
+		}
+		for i := range args {
+			if got[i] != args[i] {
+				t.Fatalf("Expected arg[%d] %q, got %q", i, args[i], got[i])
+			}
+		}
+	})
+}
+
+// FuzzParse feeds arbitrary bytes to the parser, it must fail cleanly and
+// never panic or hang
+func FuzzParse(f *testing.F) {
+	f.Add([]byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"))
+	f.Add([]byte("*1\r\n$-1\r\n"))
+	f.Add([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$2\r\n\r\n\r\n"))
+	f.Add([]byte("$5\r\nhello\r\n"))
+	f.Add([]byte("SET k \"a\\x41 b\" 'c\\'d'\r\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := bufio.NewReader(strings.NewReader(string(data)))
+		(&RESP{}).Parse(reader)
+	})
+}
+
+func TestParseInline(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"PING\r\n", []string{"PING"}},
+		{"PING\n", []string{"PING"}},
+		{"\r\n\r\n  \r\nPING\r\n", []string{"PING"}},           // empty lines are skipped
+		{"*0\r\n*0\r\n*1\r\n$4\r\nPING\r\n", []string{"PING"}}, // so are empty arrays
+		{"set  key   value\r\n", []string{"set", "key", "value"}},
+		{`SET k "hello world"` + "\r\n", []string{"SET", "k", "hello world"}},
+		{`SET k "a\"b\\c\n\x41\xff"` + "\r\n", []string{"SET", "k", "a\"b\\c\nA\xff"}},
+		{`SET k 'it\'s "raw" \n'` + "\r\n", []string{"SET", "k", `it's "raw" \n`}},
+		{`SET k ""` + "\r\n", []string{"SET", "k", ""}},
+	}
+	for _, tt := range tests {
+		args, err := readArgs(bufio.NewReader(strings.NewReader(tt.input)), common.DefaultProtoMaxBulkLen)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.input, err)
+			continue
+		}
+		if strings.Join(args, "|") != strings.Join(tt.want, "|") || len(args) != len(tt.want) {
+			t.Errorf("Expected %q for %q, got %q", tt.want, tt.input, args)
+		}
+	}
+}
+
+func TestParseInlineErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{`SET k "unterminated` + "\r\n", common.ErrUnbalancedQuotes},
+		{`SET k 'unterminated` + "\r\n", common.ErrUnbalancedQuotes},
+		{`SET k "a"b` + "\r\n", common.ErrUnbalancedQuotes},
+		{strings.Repeat("x", maxInlineLen+1) + "\r\n", common.ErrInlineTooBig},
+		{"PING", common.ErrInvalidFormat}, // no line ending
+	}
+	for _, tt := range tests {
+		_, err := readArgs(bufio.NewReader(strings.NewReader(tt.input)), common.DefaultProtoMaxBulkLen)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Expected %v for %.40q, got %v", tt.want, tt.input, err)
+		}
+	}
+}
+
+func TestParseInlineThenRESP(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("GET k\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"))
+	for i := 0; i < 2; i++ {
+		req, err := (&RESP{}).Parse(reader)
+		if err != nil {
+			t.Fatalf("Parse %d failed: %v", i, err)
+		}
+		if req.cmd != "get" || req.args[1] != "k" {
+			t.Fatalf("Expected GET k, got %v", req.args)
+		}
+	}
+}
@@ -1,142 +1,86 @@
 package protocol
 
 import (
-	"strconv"
+	"time"
 
 	"github.com/B-AJ-Amar/gokv/internal/common"
 	"github.com/B-AJ-Amar/gokv/internal/store"
 )
 
 func (r *RESP) Process(req *RESPReq, dbIndex *int, mem *store.InMemoryStore) (*RESPRes, error) {
-	response := RESPRes{}
-	switch req.cmd {
-	case "get":
-		res, err := mem.Get(req.args[1])
-		if err == nil && res == nil {
-			response.msgType = NotExistsRes
-		} else {
-			response.msgType = BulkStrRes
-			response.message = string(res)
-		}
-	case "set":
-		if req.argsLen == 3 {
-			mem.Set(req.args[1], []byte(req.args[2]))
-			response.msgType = SimpleRes
-			response.message = "OK"
-		} else {
-			counter, oldRet, err := mem.Setx(req.args[1], []byte(req.args[2]), req.setArgs)
-			if err != nil {
-				response.msgType = ErrorRes
-				response.message = "ERR syntax error"
-			} else if counter == 0 {
-				response.msgType = NotExistsRes
-			} else if oldRet != nil {
-				response.msgType = BulkStrRes
-				response.message = string(oldRet)
-			} else {
-				response.msgType = SimpleRes
-				response.message = "OK"
-			}
-		}
-
-	case "del":
-		deleted := mem.Del(req.args[1:])
-		response.msgType = IntRes
-		response.message = strconv.Itoa(deleted)
-	case "exists":
-		exists := mem.Exists(req.args[1:])
-		response.msgType = IntRes
-		response.message = strconv.Itoa(exists)
-	case "incr":
-		newVal, err := mem.Incrby(req.args[1], 1)
-		if err != nil {
-			response.msgType = ErrorRes
-			response.message = "ERR value is not an integer or out of range"
-		} else {
-			response.msgType = IntRes
-			response.message = strconv.Itoa(newVal)
-		}
-	case "incrby":
-		by, _ := strconv.Atoi(req.args[2])
-		newVal, err := mem.Incrby(req.args[1], by)
-		if err != nil {
-			response.msgType = ErrorRes
-			response.message = "ERR value is not an integer or out of range"
-		} else {
-			response.msgType = IntRes
-			response.message = strconv.Itoa(newVal)
-		}
-	case "decr":
-		newVal, err := mem.Decrby(req.args[1], 1)
-		if err != nil {
-			response.msgType = ErrorRes
-			response.message = "ERR value is not an integer or out of range"
-		} else {
-			response.msgType = IntRes
-			response.message = strconv.Itoa(newVal)
-		}
-	case "decrby":
-		by, _ := strconv.Atoi(req.args[2])
-		newVal, err := mem.Decrby(req.args[1], by)
-		if err != nil {
-			response.msgType = ErrorRes
-			response.message = "ERR value is not an integer or out of range"
-		} else {
-			response.msgType = IntRes
-			response.message = strconv.Itoa(newVal)
-		}
+	c := lookupCommand(req.cmd)
+	if c == nil {
+		return errorReply(common.ErrUnknownCommand), nil
+	}
+	if r.AOF != nil && c.flags&flagWrite != 0 {
+		defer r.AOF.WriteBegin(*dbIndex, c.keys(req.args))()
+	}
 
-	case "ttl":
-		ttl, err := mem.TTL(req.args[1])
-		if err != nil {
-			response.msgType = ErrorRes
-			response.message = "ERR key does not exist"
-		} else {
-			response.msgType = IntRes
-			response.message = strconv.Itoa(ttl)
-		}
-	case "expire":
-		seconds, err := strconv.Atoi(req.args[2])
-		if err != nil || seconds < 0 {
-			return nil, common.ErrInvalidExpireTime
-		}
+	defer r.recordCommand(req, time.Now())
 
-		expired, err := mem.Expire(req.args[1], seconds)
-		if err != nil {
-			response.msgType = ErrorRes
-			response.message = "ERR key does not exist"
-		} else {
-			response.msgType = IntRes
-			response.message = strconv.Itoa(expired)
-		}
+	if err := r.checkAccess(c, mem); err != nil {
+		return errorReply(err), nil
+	}
 
-	case "persist":
-		persisted, err := mem.Persist(req.args[1])
-		if err != nil {
-			response.msgType = ErrorRes
-			response.message = "ERR key does not exist"
-		} else {
-			response.msgType = IntRes
-			response.message = strconv.Itoa(persisted)
-		}
+	res, err := c.run(r, req, dbIndex, mem)
+	if err != nil {
+		return nil, err
+	}
+	if c.flags&flagWrite != 0 && res.msgType != ErrorRes {
+		r.propagate(req, *dbIndex, mem, res)
+	}
+	return res, nil
+}
 
-	case "select":
-		newDBIndex, err := strconv.Atoi(req.args[1])
-		if err != nil || newDBIndex < 0 || newDBIndex > common.MaxDBIndex {
-			return nil, common.ErrNotIntOROutOfRange
-		}
+// Exec runs a command given as its raw arguments, for the callers that do
+// not read it off the wire like the AOF replay and the embedded API
+func (r *RESP) Exec(args []string, dbIndex *int) (*RESPRes, error) {
+	req, err := NewRequest(args)
+	if err != nil {
+		return nil, err
+	}
+	return r.Process(req, dbIndex, r.DBs[*dbIndex])
+}
 
-		*dbIndex = newDBIndex
+// Run is Exec for a client connection: command errors, from the validation
+// or from the command itself, are turned into error replies
+func (r *RESP) Run(args []string, dbIndex *int) *RESPRes {
+	res, err := r.Exec(args, dbIndex)
+	if err != nil {
+		return errorReply(err)
+	}
+	return res
+}
 
-		response.msgType = SimpleRes
-		response.message = "OK"
-	case "ping":
-		response.msgType = SimpleRes
-		response.message = "PONG"
-	default:
-		response.msgType = ErrorRes
-		response.message = "ERR unknown command"
+// checkAccess enforces requirepass and maxmemory before a command runs,
+// evicting keys first when the maxmemory-policy allows it
+func (r *RESP) checkAccess(c *command, mem *store.InMemoryStore) error {
+	if r.Config == nil {
+		return nil
+	}
+	cfg := r.Config.Get()
+	if cfg.RequirePass != "" && !r.authenticated && !r.Trusted && c.flags&flagNoAuth == 0 {
+		return common.ErrNoAuth
+	}
+	if cfg.MaxMemory > 0 && c.flags&flagDenyOOM != 0 && !store.Evict(r.DBs, cfg.MaxMemPolicy, cfg.MaxMemory) {
+		return common.ErrOOM
 	}
+	return nil
+}
 
-	return &response, nil
+// recordCommand counts the command and adds it to the slowlog when it took
+// longer than slowlog-log-slower-than
+func (r *RESP) recordCommand(req *RESPReq, start time.Time) {
+	if r.Stats == nil {
+		return
+	}
+	r.Stats.TotalCommands.Add(1)
+	if r.Config == nil {
+		return
+	}
+	cfg := r.Config.Get()
+	took := time.Since(start)
+	if cfg.SlowlogSlower >= 0 && took.Microseconds() >= cfg.SlowlogSlower {
+		r.Stats.Slowlog.Add(req.args, took, r.ClientAddr, cfg.SlowlogMaxLen)
+	}
 }
@@ -1,9 +1,16 @@
 package protocol
 
 import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/config"
+	"github.com/B-AJ-Amar/gokv/internal/persistence"
 	"github.com/B-AJ-Amar/gokv/internal/store"
 )
 
@@ -115,3 +122,338 @@ func TestProcessUnknownCommand(t *testing.T) {
 		t.Errorf("Unknown command response incorrect: got type %d, msg %q", res.msgType, res.message)
 	}
 }
+
+func TestProcessSaveAndLastSave(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(2)
+	dbs[1].Set("k", []byte("v"))
+	resp := &RESP{DBs: dbs, Saver: persistence.NewSnapshotter(filepath.Join(t.TempDir(), "dump.gkv"))}
+	idx := 0
+
+	res, err := resp.Process(&RESPReq{cmd: "save", argsLen: 1, args: []string{"save"}}, &idx, dbs[0])
+	if err != nil {
+		t.Fatalf("Process SAVE failed: %v", err)
+	}
+	if res.msgType != SimpleRes || res.message != "OK" {
+		t.Errorf("SAVE response incorrect: got type %d, msg %q", res.msgType, res.message)
+	}
+
+	res, err = resp.Process(&RESPReq{cmd: "lastsave", argsLen: 1, args: []string{"lastsave"}}, &idx, dbs[0])
+	if err != nil {
+		t.Fatalf("Process LASTSAVE failed: %v", err)
+	}
+	if res.msgType != IntRes || res.num != resp.Saver.LastSave() {
+		t.Errorf("LASTSAVE response incorrect: got type %d, value %d", res.msgType, res.num)
+	}
+}
+
+func TestProcessSaveWithoutPersistence(t *testing.T) {
+	mem := store.NewInMemoryStore()
+	resp := &RESP{}
+	idx := 0
+	_, err := resp.Process(&RESPReq{cmd: "bgsave", argsLen: 1, args: []string{"bgsave"}}, &idx, &mem)
+	if err != common.ErrPersistenceDisabled {
+		t.Errorf("Expected persistence disabled error, got %v", err)
+	}
+}
+
+func TestProcessAppendsWritesToAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := persistence.OpenAOF(path, persistence.FsyncAlways)
+	if err != nil {
+		t.Fatalf("OpenAOF failed: %v", err)
+	}
+	dbs := store.NewInMemoryStoreArray(2)
+	resp := &RESP{DBs: dbs, AOF: aof}
+	idx := 0
+
+	for _, args := range [][]string{
+		{"set", "k", "v", "EX", "100"},
+		{"get", "k"},
+		{"set", "k", "v2", "NX"}, // not applied
+		{"select", "1"},
+		{"incr", "ctr"},
+		{"expire", "missing", "10"}, // not applied
+		{"mset", "a", "1", "b", "2"},
+		{"msetnx", "b", "3", "c", "4"}, // not applied
+		{"setex", "e", "100", "v"},
+		{"getex", "e", "PERSIST"},
+		{"getdel", "e"},
+		{"getdel", "e"},     // not applied
+		{"setnx", "a", "2"}, // not applied
+		{"incrbyfloat", "f", "0.1"},
+		{"incrbyfloat", "f", "0.2"},
+		{"hset", "h", "f", "v"},
+		{"hsetnx", "h", "f", "v"}, // not applied
+		{"hdel", "h", "missing"},  // not applied
+		{"hincrbyfloat", "h", "n", "1e1"},
+		{"rpush", "q", "a", "b"},
+		{"blpop", "missing", "q", "0"},
+		{"blmove", "q", "r", "LEFT", "RIGHT", "0"},
+		{"lpop", "q"},       // not applied
+		{"brpop", "q", "0"}, // not applied, it would block
+		{"sadd", "s", "1"},
+		{"sadd", "s", "1"}, // not applied
+		{"spop", "s"},
+		{"spop", "s"}, // not applied
+		{"zadd", "z", "1", "a", "2", "b"},
+		{"zadd", "z", "NX", "INCR", "1", "a"}, // not applied
+		{"zincrby", "z", "0.5", "a"},
+		{"zrem", "z", "missing"}, // not applied
+		{"bzpopmin", "missing", "z", "0"},
+		{"zpopmax", "z"},
+		{"zpopmax", "z"}, // not applied
+	} {
+		req, err := NewRequest(args)
+		if err != nil {
+			t.Fatalf("NewRequest %q failed: %v", args, err)
+		}
+		if _, err := resp.Process(req, &idx, dbs[idx]); err != nil {
+			t.Fatalf("Process %q failed: %v", args, err)
+		}
+	}
+	aof.Close()
+
+	reopened, _ := persistence.OpenAOF(path, persistence.FsyncNo)
+	defer reopened.Close()
+	got := [][]string{}
+	reopened.Load(func(args []string) error {
+		got = append(got, args)
+		return nil
+	})
+
+	exp := strconv.FormatInt(dbs[0].PExpireTime("k"), 10)
+	want := [][]string{
+		{"SELECT", "0"}, {"set", "k", "v", "PXAT", exp},
+		{"SELECT", "1"}, {"incr", "ctr"},
+		{"mset", "a", "1", "b", "2"},
+		{"set", "e", "v", "PXAT", "<setex deadline>"},
+		{"persist", "e"},
+		{"del", "e"},
+		{"set", "f", "0.1", "KEEPTTL"},
+		{"set", "f", "0.30000000000000004", "KEEPTTL"},
+		{"hset", "h", "f", "v"},
+		{"hset", "h", "n", "10"},
+		{"rpush", "q", "a", "b"},
+		{"lpop", "q"},
+		{"lmove", "q", "r", "LEFT", "RIGHT"},
+		{"sadd", "s", "1"},
+		{"srem", "s", "1"},
+		{"zadd", "z", "1", "a", "2", "b"},
+		{"zadd", "z", "1.5", "a"},
+		{"zpopmin", "z"},
+		{"zpopmax", "z"},
+	}
+	// the deadline of SETEX depends on the clock, check it apart
+	if len(got) == len(want) && len(got[5]) == 5 {
+		at, _ := strconv.ParseInt(got[5][4], 10, 64)
+		if left := at - time.Now().UnixMilli(); left < 99_000 || left > 100_000 {
+			t.Errorf("Expected SETEX to be appended with a deadline 100s ahead, got %dms", left)
+		}
+		got[5][4] = "<setex deadline>"
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected AOF %q, got %q", want, got)
+	}
+}
+
+func TestProcessRequirePass(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	cfg := config.Default()
+	cfg.RequirePass = "secret"
+	resp := &RESP{DBs: dbs, Config: config.NewLive(cfg)}
+	idx := 0
+
+	run := func(args ...string) *RESPRes {
+		req, err := NewRequest(args)
+		if err != nil {
+			t.Fatalf("NewRequest %q failed: %v", args, err)
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			t.Fatalf("Process %q failed: %v", args, err)
+		}
+		return res
+	}
+
+	if res := run("get", "k"); res.msgType != ErrorRes || res.message != common.ErrNoAuth.Error() {
+		t.Errorf("Expected NOAUTH before AUTH, got %q", res.message)
+	}
+	if res := run("auth", "wrong"); res.msgType != ErrorRes || res.message != common.ErrWrongPass.Error() {
+		t.Errorf("Expected WRONGPASS, got %q", res.message)
+	}
+	if res := run("auth", "default", "secret"); res.msgType != SimpleRes {
+		t.Errorf("Expected AUTH to succeed, got %q", res.message)
+	}
+	if res := run("get", "k"); res.msgType != NotExistsRes {
+		t.Errorf("Expected GET to run after AUTH, got %q", res.message)
+	}
+}
+
+func TestProcessMaxMemory(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	cfg := config.Default()
+	cfg.MaxMemory = 1024
+	resp := &RESP{DBs: dbs, Config: config.NewLive(cfg)}
+	idx := 0
+
+	for i := 0; i < 64; i++ {
+		req, _ := NewRequest([]string{"set", "key" + strconv.Itoa(i), "value"})
+		res, _ := resp.Process(req, &idx, dbs[0])
+		if res.msgType == ErrorRes {
+			if res.message != common.ErrOOM.Error() {
+				t.Fatalf("Expected OOM error, got %q", res.message)
+			}
+			// deleting is still allowed and frees memory
+			del, _ := NewRequest([]string{"del", "key0", "key1", "key2"})
+			if res, _ := resp.Process(del, &idx, dbs[0]); res.msgType != IntRes {
+				t.Errorf("Expected DEL to be allowed over maxmemory, got %q", res.message)
+			}
+			return
+		}
+	}
+	t.Errorf("Expected SET to be refused once maxmemory is reached")
+}
+
+func TestProcessConfigGetSet(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	resp := &RESP{DBs: dbs, Config: config.NewLive(config.Default()), Stats: &Stats{}}
+	idx := 0
+	run := func(args ...string) *RESPRes {
+		req, err := NewRequest(args)
+		if err != nil {
+			t.Fatalf("NewRequest %q failed: %v", args, err)
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			t.Fatalf("Process %q failed: %v", args, err)
+		}
+		return res
+	}
+
+	if res := run("config", "set", "timeout", "15", "slowlog-max-len", "4"); res.msgType != SimpleRes {
+		t.Fatalf("CONFIG SET failed: %q", res.message)
+	}
+	res := run("config", "get", "timeout")
+	if got := wire(resp, res); got != "*2\r\n$7\r\ntimeout\r\n$2\r\n15\r\n" {
+		t.Errorf("CONFIG GET response incorrect: %q", got)
+	}
+	if res := run("config", "set", "port", "1"); res.msgType != ErrorRes {
+		t.Errorf("Expected CONFIG SET on an immutable param to fail, got %q", res.message)
+	}
+	if _, err := NewRequest([]string{"config", "set", "timeout"}); err == nil {
+		t.Errorf("Expected CONFIG SET without a value to be rejected")
+	}
+
+	// log everything, the slowlog keeps slowlog-max-len entries
+	run("config", "set", "slowlog-log-slower-than", "0")
+	for i := 0; i < 10; i++ {
+		run("ping")
+	}
+	if res := run("slowlog", "len"); res.num != 4 {
+		t.Errorf("Expected 4 slowlog entries, got %d", res.num)
+	}
+	run("slowlog", "reset")
+	run("config", "resetstat")
+	// only the RESETSTAT itself is counted
+	if n := resp.Stats.TotalCommands.Load(); n != 1 {
+		t.Errorf("Expected RESETSTAT to clear the command counter, got %d", n)
+	}
+}
+
+func TestProcessInfo(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(3)
+	resp := &RESP{DBs: dbs, Config: config.NewLive(config.Default()), Stats: &Stats{}}
+	idx := 0
+	run := func(args ...string) string {
+		req, err := NewRequest(args)
+		if err != nil {
+			t.Fatalf("NewRequest %q failed: %v", args, err)
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			t.Fatalf("Process %q failed: %v", args, err)
+		}
+		return res.message
+	}
+
+	run("config", "resetstat")
+	run("set", "a", "1")
+	run("set", "b", "2", "EX", "100")
+	run("select", "2")
+	run("set", "c", "3")
+	if got := run("info", "keyspace"); got != "# Keyspace\r\ndb0:keys=2,expires=1\r\ndb2:keys=1,expires=0\r\n" {
+		t.Errorf("Unexpected INFO keyspace %q", got)
+	}
+	stats := run("info", "STATS")
+	for _, line := range []string{"total_commands_processed:6", "expired_keys:0", "evicted_keys:0"} {
+		if !strings.Contains(stats, line+"\r\n") {
+			t.Errorf("Expected %q in INFO stats, got %q", line, stats)
+		}
+	}
+	if strings.Contains(stats, "# Keyspace") {
+		t.Errorf("Expected INFO stats to leave the other sections out")
+	}
+	all := run("info")
+	for _, section := range []string{"# Stats", "# Memory", "# Keyspace"} {
+		if !strings.Contains(all, section) {
+			t.Errorf("Expected %q in INFO, got %q", section, all)
+		}
+	}
+}
+
+func TestProcessHello(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	cfg := config.Default()
+	cfg.RequirePass = "secret"
+	resp := &RESP{DBs: dbs, Config: config.NewLive(cfg), ClientID: 7}
+	idx := 0
+	run := func(args ...string) *RESPRes {
+		req, err := NewRequest(args)
+		if err != nil {
+			t.Fatalf("NewRequest %q failed: %v", args, err)
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			t.Fatalf("Process %q failed: %v", args, err)
+		}
+		return res
+	}
+
+	if res := run("hello", "4"); res.message != common.ErrNoProto.Error() {
+		t.Errorf("Expected NOPROTO, got %q", res.message)
+	}
+	if res := run("hello", "3"); res.message != common.ErrHelloNoAuth.Error() {
+		t.Errorf("Expected NOAUTH without AUTH, got %q", res.message)
+	}
+	if resp.protoVersion() != RESP2 {
+		t.Errorf("Expected a failed HELLO to keep RESP2")
+	}
+	if res := run("hello", "3", "AUTH", "default", "wrong"); res.message != common.ErrWrongPass.Error() {
+		t.Errorf("Expected WRONGPASS, got %q", res.message)
+	}
+
+	hello := wire(resp, run("hello", "3", "auth", "default", "secret", "setname", "worker"))
+	if !strings.HasPrefix(hello, "%7\r\n$6\r\nserver\r\n$4\r\ngokv\r\n") {
+		t.Fatalf("Expected a RESP3 map, got %q", hello)
+	}
+	if !strings.Contains(hello, "$5\r\nproto\r\n:3\r\n$2\r\nid\r\n:7\r\n") {
+		t.Errorf("Expected proto 3 and id 7 in %q", hello)
+	}
+	if resp.protoVersion() != RESP3 || resp.clientName != "worker" {
+		t.Errorf("Expected RESP3 and the name worker, got %d and %q", resp.protoVersion(), resp.clientName)
+	}
+
+	// RESP3 nulls and maps
+	got := wire(resp, run("get", "missing")) + wire(resp, run("config", "get", "timeout"))
+	if got != "_\r\n%1\r\n$7\r\ntimeout\r\n$1\r\n0\r\n" {
+		t.Errorf("Unexpected RESP3 replies %q", got)
+	}
+
+	run("hello", "2")
+	if got := wire(resp, run("hello")); !strings.HasPrefix(got, "*14\r\n") {
+		t.Errorf("Expected HELLO in RESP2 to reply with a flat array, got %q", got)
+	}
+	if _, err := NewRequest([]string{"hello", "3", "AUTH", "default"}); err == nil {
+		t.Errorf("Expected HELLO AUTH without a password to be rejected")
+	}
+}
@@ -0,0 +1,147 @@
+package protocol
+
+import (
+	"strconv"
+
+	"github.com/B-AJ-Amar/gokv/internal/logger"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// propagate appends a successful write command to the AOF. commands that did
+// not change anything are skipped, and relative expire times are turned into
+// absolute ones so replaying the log later gives the same deadlines.
+func (r *RESP) propagate(req *RESPReq, dbIndex int, mem *store.InMemoryStore, res *RESPRes) {
+	if r.AOF == nil {
+		return
+	}
+	args := propagatedArgs(req, mem, res)
+	if args == nil {
+		return
+	}
+	if err := r.AOF.Append(dbIndex, args); err != nil {
+		logger.Warningf("aof append failed: %v", err)
+	}
+}
+
+func propagatedArgs(req *RESPReq, mem *store.InMemoryStore, res *RESPRes) []string {
+	key := ""
+	if len(req.args) > 1 {
+		key = req.args[1]
+	}
+
+	switch req.cmd {
+	case "set":
+		if res.msgType == NotExistsRes {
+			return nil // NX/XX condition not met
+		}
+		if req.argsLen == 3 {
+			return req.args
+		}
+		return withAbsoluteExpire([]string{"set", key, req.args[2]}, key, mem)
+	case "incrbyfloat":
+		// the result, not the increment, so a replay can't round differently
+		return []string{"set", key, res.message, "KEEPTTL"}
+	case "hincrbyfloat":
+		return []string{"hset", key, req.args[2], res.message}
+	case "blpop", "brpop":
+		// the list that was served, with the non blocking pop
+		if res.msgType != ArrayRes {
+			return nil
+		}
+		return []string{req.cmd[1:], res.items[0].message}
+	case "blmove":
+		if res.msgType != BulkStrRes {
+			return nil
+		}
+		return append([]string{"lmove"}, req.args[1:5]...)
+	case "lpop", "rpop", "lmove":
+		if res.msgType == NotExistsRes || res.msgType == NullArrayRes {
+			return nil
+		}
+	case "spop":
+		// the members picked, not a new random draw on replay
+		var popped []string
+		switch res.msgType {
+		case BulkStrRes:
+			popped = []string{res.message}
+		case SetRes:
+			for _, item := range res.items {
+				popped = append(popped, item.message)
+			}
+		}
+		if len(popped) == 0 {
+			return nil
+		}
+		return append([]string{"srem", key}, popped...)
+	case "zadd", "zincrby":
+		// INCR replays as the score it reached, like incrbyfloat
+		if res.msgType == NotExistsRes {
+			return nil
+		}
+		if res.msgType == DoubleRes {
+			return []string{"zadd", key, formatDouble(res.double), req.args[len(req.args)-1]}
+		}
+	case "zpopmin", "zpopmax":
+		if len(res.items) == 0 {
+			return nil
+		}
+	case "bzpopmin", "bzpopmax":
+		if res.msgType != ArrayRes {
+			return nil
+		}
+		return []string{req.cmd[1:], res.items[0].message}
+	case "linsert":
+		if res.num <= 0 {
+			return nil
+		}
+	case "setex", "psetex":
+		return withAbsoluteExpire([]string{"set", key, req.args[3]}, key, mem)
+	case "expire", "pexpireat":
+		if res.num == 0 {
+			return nil
+		}
+		return absoluteExpire(key, mem)
+	case "getex":
+		switch {
+		case res.msgType == NotExistsRes || req.setArgs.ExpType == store.ExpireNone:
+			return nil
+		case req.setArgs.ExpType == store.ExpirePERSIST:
+			return []string{"persist", key}
+		}
+		return absoluteExpire(key, mem)
+	case "getdel":
+		if res.msgType == NotExistsRes {
+			return nil
+		}
+		return []string{"del", key}
+	case "del", "persist", "msetnx", "setnx", "hsetnx", "hdel", "lrem", "sadd", "srem", "smove", "zrem":
+		if res.num == 0 {
+			return nil
+		}
+	}
+	return req.args
+}
+
+// absoluteExpire replays the ttl key has now
+func absoluteExpire(key string, mem *store.InMemoryStore) []string {
+	switch exp := mem.PExpireTime(key); exp {
+	case -1:
+		return []string{"persist", key}
+	case -2:
+		// already in the past, the key is gone
+		return []string{"del", key}
+	default:
+		return []string{"pexpireat", key, strconv.FormatInt(exp, 10)}
+	}
+}
+
+func withAbsoluteExpire(args []string, key string, mem *store.InMemoryStore) []string {
+	switch exp := mem.PExpireTime(key); exp {
+	case -1:
+		return args
+	case -2:
+		return []string{"del", key}
+	default:
+		return append(args, "PXAT", strconv.FormatInt(exp, 10))
+	}
+}
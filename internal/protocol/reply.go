@@ -0,0 +1,187 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+// a reply is a tree of typed values: scalars are leaves, arrays, sets and
+// maps hold other replies. Send encodes the tree for the RESP version of the
+// connection.
+const (
+	SimpleRes    = iota // +MESSAGE\r\n
+	ErrorRes            // -ERROR\r\n
+	BulkStrRes          // $n\r\nXXX\r\n
+	NotExistsRes        // null bulk string $-1\r\n, _\r\n in RESP3
+	IntRes              // :1\r\n
+	ArrayRes            // *n\r\n followed by the items
+	NullArrayRes        // *-1\r\n, _\r\n in RESP3
+	MapRes              // %n\r\n followed by keys and values, a flat array in RESP2
+	SetRes              // ~n\r\n followed by the items, an array in RESP2
+	DoubleRes           // ,1.5\r\n, a bulk string in RESP2
+	BoolRes             // #t\r\n, :1 or :0 in RESP2
+	BigNumberRes        // (12345\r\n, a bulk string in RESP2
+	VerbatimRes         // =n\r\ntxt:XXX\r\n, a bulk string in RESP2
+	NoRes               // nothing is sent, like after a successful SHUTDOWN
+	BlockedRes          // a blocking command found no data, see blockedReply
+)
+
+type RESPRes struct {
+	msgType int
+	message string        // text of strings and errors, digits of big numbers
+	num     int64         // IntRes, BoolRes is 1 or 0
+	double  float64       // DoubleRes
+	format  string        // VerbatimRes, like txt or mkd
+	items   []*RESPRes    // ArrayRes and SetRes, MapRes alternates keys and values
+	err     error         // ErrorRes, the error the reply was made of
+	keys    []string      // BlockedRes, the keys to wait on
+	timeout time.Duration // BlockedRes, 0 waits forever
+}
+
+// Kind is the reply type, one of the *Res constants
+func (res *RESPRes) Kind() int {
+	return res.msgType
+}
+
+// Message is the text of a string, error or big number reply
+func (res *RESPRes) Message() string {
+	return res.message
+}
+
+// Err is the error of an error reply, a *common.Error for the replies of a
+// known class, nil for the other kinds of reply
+func (res *RESPRes) Err() error {
+	return res.err
+}
+
+// Int is the value of an integer reply, 1 or 0 for a boolean
+func (res *RESPRes) Int() int64 {
+	return res.num
+}
+
+// Double is the value of a double reply
+func (res *RESPRes) Double() float64 {
+	return res.double
+}
+
+// Items are the children of an array, set or map reply
+func (res *RESPRes) Items() []*RESPRes {
+	return res.items
+}
+
+// BlockedKeys are the keys a blocked command waits on
+func (res *RESPRes) BlockedKeys() []string {
+	return res.keys
+}
+
+// BlockTimeout is how long a blocked command waits, 0 for ever
+func (res *RESPRes) BlockTimeout() time.Duration {
+	return res.timeout
+}
+
+func simpleReply(s string) *RESPRes {
+	return &RESPRes{msgType: SimpleRes, message: s}
+}
+
+func okReply() *RESPRes {
+	return simpleReply("OK")
+}
+
+func errorReply(err error) *RESPRes {
+	return &RESPRes{msgType: ErrorRes, message: errorLine(err), err: err}
+}
+
+// errorLine is the text of an error reply, which must start with the code of
+// the error: errors wrapped with some context get their code moved to the
+// front, the ones without a code are ERR
+func errorLine(err error) string {
+	var e *common.Error
+	if !errors.As(err, &e) {
+		return string(common.CodeErr) + " " + err.Error()
+	}
+	msg := err.Error()
+	if strings.HasPrefix(msg, string(e.Code)+" ") {
+		return msg
+	}
+	return string(e.Code) + " " + strings.Replace(msg, e.Error(), e.Msg, 1)
+}
+
+func bulkReply(s string) *RESPRes {
+	return &RESPRes{msgType: BulkStrRes, message: s}
+}
+
+func nullReply() *RESPRes {
+	return &RESPRes{msgType: NotExistsRes}
+}
+
+func intReply(n int64) *RESPRes {
+	return &RESPRes{msgType: IntRes, num: n}
+}
+
+func arrayReply(items ...*RESPRes) *RESPRes {
+	if items == nil {
+		items = []*RESPRes{}
+	}
+	return &RESPRes{msgType: ArrayRes, items: items}
+}
+
+func nullArrayReply() *RESPRes {
+	return &RESPRes{msgType: NullArrayRes}
+}
+
+func bulkArrayReply(items []string) *RESPRes {
+	return arrayReply(bulkStrings(items)...)
+}
+
+func bulkStrings(items []string) []*RESPRes {
+	res := make([]*RESPRes, len(items))
+	for i, item := range items {
+		res[i] = bulkReply(item)
+	}
+	return res
+}
+
+// mapReply takes the keys and values alternated
+func mapReply(pairs ...*RESPRes) *RESPRes {
+	return &RESPRes{msgType: MapRes, items: pairs}
+}
+
+func setReply(items ...*RESPRes) *RESPRes {
+	return &RESPRes{msgType: SetRes, items: items}
+}
+
+func doubleReply(f float64) *RESPRes {
+	return &RESPRes{msgType: DoubleRes, double: f}
+}
+
+func boolReply(b bool) *RESPRes {
+	res := &RESPRes{msgType: BoolRes}
+	if b {
+		res.num = 1
+	}
+	return res
+}
+
+// bigNumberReply takes the decimal digits of an integer of any size
+func bigNumberReply(digits string) *RESPRes {
+	return &RESPRes{msgType: BigNumberRes, message: digits}
+}
+
+// verbatimReply tags s with a three letters format like txt or mkd
+func verbatimReply(format, s string) *RESPRes {
+	return &RESPRes{msgType: VerbatimRes, format: format, message: s}
+}
+
+func noReply() *RESPRes {
+	return &RESPRes{msgType: NoRes}
+}
+
+// blockedReply asks the connection to run the command again once one of
+// keys gets data, or to reply the timeout after timeout. a caller that can't
+// wait, like the embedded API, sends it as is: the null array of a timeout.
+func blockedReply(keys []string, timeout time.Duration) *RESPRes {
+	return &RESPRes{msgType: BlockedRes, keys: keys, timeout: timeout}
+}
@@ -2,37 +2,133 @@ package protocol
 
 import (
 	"bufio"
-	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/B-AJ-Amar/gokv/internal/common"
 )
 
+// Send encodes a reply into writer, walking the tree without building the
+// whole message in memory. the caller flushes writer once the pipelined
+// commands it has already read are all answered.
 func (r *RESP) Send(writer *bufio.Writer, res *RESPRes) error {
+	return r.encode(writer, res, r.protoVersion())
+}
+
+func (r *RESP) encode(w *bufio.Writer, res *RESPRes, proto int) error {
 	switch res.msgType {
 	case SimpleRes:
-		fmt.Fprintf(writer, "+%s\r\n", res.message)
+		writeLine(w, '+', res.message)
 	case ErrorRes:
-		fmt.Fprintf(writer, "-%s\r\n", res.message)
+		// error texts may quote client input, a line break would end the
+		// reply early and let the rest pass for another one
+		writeLine(w, '-', errorLineReplacer.Replace(res.message))
 	case BulkStrRes:
-		fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(res.message), res.message)
+		writeBulk(w, res.message)
 	case NotExistsRes:
-		fmt.Fprintf(writer, "$-1\r\n")
+		if proto == RESP3 {
+			w.WriteString("_\r\n")
+		} else {
+			w.WriteString("$-1\r\n")
+		}
 	case IntRes:
-		fmt.Fprintf(writer, ":%s\r\n", res.message)
-	case SpecialRes:
-		writer.WriteString(res.message)
+		writeInt(w, ':', res.num)
+	case NullArrayRes, BlockedRes:
+		if proto == RESP3 {
+			w.WriteString("_\r\n")
+		} else {
+			w.WriteString("*-1\r\n")
+		}
+	case ArrayRes, SetRes, MapRes:
+		switch {
+		case proto != RESP3:
+			writeInt(w, '*', int64(len(res.items)))
+		case res.msgType == SetRes:
+			writeInt(w, '~', int64(len(res.items)))
+		case res.msgType == MapRes:
+			writeInt(w, '%', int64(len(res.items)/2))
+		default:
+			writeInt(w, '*', int64(len(res.items)))
+		}
+		for _, item := range res.items {
+			if err := r.encode(w, item, proto); err != nil {
+				return err
+			}
+		}
+	case DoubleRes:
+		// a bulk string in RESP2, like redis does for the scores
+		if proto == RESP3 {
+			writeLine(w, ',', formatDouble(res.double))
+		} else {
+			writeBulk(w, formatDouble(res.double))
+		}
+	case BoolRes:
+		if proto == RESP3 && res.num != 0 {
+			w.WriteString("#t\r\n")
+		} else if proto == RESP3 {
+			w.WriteString("#f\r\n")
+		} else {
+			writeInt(w, ':', res.num)
+		}
+	case BigNumberRes:
+		if proto == RESP3 {
+			writeLine(w, '(', res.message)
+		} else {
+			writeBulk(w, res.message)
+		}
+	case VerbatimRes:
+		if proto == RESP3 {
+			writeInt(w, '=', int64(len(res.format)+1+len(res.message)))
+			w.WriteString(res.format)
+			w.WriteByte(':')
+			w.WriteString(res.message)
+			w.WriteString("\r\n")
+		} else {
+			writeBulk(w, res.message)
+		}
+	case NoRes:
 	default:
 		return common.ErrUnknownCommand
 	}
-	writer.Flush()
 	return nil
+}
 
+// SendError writes and flushes an error, it is sent right before the
+// connection is closed
+func (r *RESP) SendError(writer *bufio.Writer, err error) error {
+	writeLine(writer, '-', errorLine(err))
+	return writer.Flush()
 }
-func (r *RESP) SendError(writer *bufio.Writer, msg string) error {
 
-	fmt.Fprintf(writer, "-%s\r\n", msg)
-	writer.Flush()
+var errorLineReplacer = strings.NewReplacer("\r", " ", "\n", " ")
 
-	return nil
+func writeLine(w *bufio.Writer, prefix byte, s string) {
+	w.WriteByte(prefix)
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
 
+func writeInt(w *bufio.Writer, prefix byte, n int64) {
+	w.WriteByte(prefix)
+	w.Write(strconv.AppendInt(w.AvailableBuffer(), n, 10))
+	w.WriteString("\r\n")
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	writeInt(w, '$', int64(len(s)))
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+func formatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
 }
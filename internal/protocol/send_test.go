@@ -0,0 +1,171 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// wire is what Send writes for res
+func wire(r *RESP, res *RESPRes) string {
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	r.Send(w, res)
+	w.Flush()
+	return out.String()
+}
+
+func TestSendBuffersReplies(t *testing.T) {
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	resp := &RESP{}
+	resp.Send(w, okReply())
+	resp.Send(w, intReply(1))
+	if out.Len() != 0 {
+		t.Fatalf("Expected Send to leave the replies buffered, got %q", out.String())
+	}
+	w.Flush()
+	if out.String() != "+OK\r\n:1\r\n" {
+		t.Fatalf("Expected both replies after Flush, got %q", out.String())
+	}
+}
+
+func TestSendReplyTree(t *testing.T) {
+	r2, r3 := &RESP{}, &RESP{proto: RESP3}
+	tests := []struct {
+		name   string
+		res    *RESPRes
+		v2, v3 string
+	}{
+		{"null", nullReply(), "$-1\r\n", "_\r\n"},
+		{"null array", nullArrayReply(), "*-1\r\n", "_\r\n"},
+		{"empty array", arrayReply(), "*0\r\n", "*0\r\n"},
+		{"nested array",
+			arrayReply(intReply(1), arrayReply(bulkReply("a"), nullReply()), errorReply(common.ErrSyntaxError)),
+			"*3\r\n:1\r\n*2\r\n$1\r\na\r\n$-1\r\n-ERR syntax error\r\n",
+			"*3\r\n:1\r\n*2\r\n$1\r\na\r\n_\r\n-ERR syntax error\r\n"},
+		{"map", mapReply(bulkReply("k"), intReply(-5)), "*2\r\n$1\r\nk\r\n:-5\r\n", "%1\r\n$1\r\nk\r\n:-5\r\n"},
+		{"set", setReply(intReply(1)), "*1\r\n:1\r\n", "~1\r\n:1\r\n"},
+		{"double", doubleReply(1.5), "$3\r\n1.5\r\n", ",1.5\r\n"},
+		{"inf", doubleReply(math.Inf(-1)), "$4\r\n-inf\r\n", ",-inf\r\n"},
+		{"bool", boolReply(true), ":1\r\n", "#t\r\n"},
+		{"false", boolReply(false), ":0\r\n", "#f\r\n"},
+		{"big number", bigNumberReply("123456789012345678901234567890"),
+			"$30\r\n123456789012345678901234567890\r\n", "(123456789012345678901234567890\r\n"},
+		{"verbatim", verbatimReply("txt", "hi"), "$2\r\nhi\r\n", "=6\r\ntxt:hi\r\n"},
+		{"binary bulk", bulkReply("a\r\nb"), "$4\r\na\r\nb\r\n", "$4\r\na\r\nb\r\n"},
+		{"no reply", noReply(), "", ""},
+	}
+	for _, tt := range tests {
+		if got := wire(r2, tt.res); got != tt.v2 {
+			t.Errorf("%s: expected %q in RESP2, got %q", tt.name, tt.v2, got)
+		}
+		if got := wire(r3, tt.res); got != tt.v3 {
+			t.Errorf("%s: expected %q in RESP3, got %q", tt.name, tt.v3, got)
+		}
+	}
+}
+
+// benchmarkPipeline answers a pipeline of n SET/GET pairs the way a client
+// connection does, the replies go to a loopback TCP connection so every
+// flush costs a real write syscall
+func benchmarkPipeline(b *testing.B, n int, flushEachReply bool) {
+	var input bytes.Buffer
+	for i := 0; i < n; i++ {
+		key := "key:" + strconv.Itoa(i)
+		input.WriteString(encodeArgs([]string{"SET", key, "value"}))
+		input.WriteString(encodeArgs([]string{"GET", key}))
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		client, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return
+		}
+		defer client.Close()
+		io.Copy(io.Discard, client)
+	}()
+	conn, err := ln.Accept()
+	if err != nil {
+		b.Fatalf("Accept failed: %v", err)
+	}
+	defer conn.Close()
+
+	dbs := store.NewInMemoryStoreArray(1)
+	resp := &RESP{DBs: dbs}
+	b.SetBytes(int64(input.Len()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := bufio.NewReader(bytes.NewReader(input.Bytes()))
+		w := bufio.NewWriterSize(conn, 64*1024)
+		dbIndex := 0
+		for j := 0; j < 2*n; j++ {
+			req, err := resp.Parse(r)
+			if err != nil {
+				b.Fatalf("Parse failed: %v", err)
+			}
+			res, _ := resp.Process(req, &dbIndex, dbs[dbIndex])
+			resp.Send(w, res)
+			if flushEachReply {
+				w.Flush()
+			}
+		}
+		w.Flush()
+	}
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	for _, n := range []int{1, 100, 10000} {
+		b.Run("flush-each-reply/"+strconv.Itoa(n), func(b *testing.B) { benchmarkPipeline(b, n, true) })
+		b.Run("batched/"+strconv.Itoa(n), func(b *testing.B) { benchmarkPipeline(b, n, false) })
+	}
+}
+
+func TestSendErrorCodes(t *testing.T) {
+	r := &RESP{}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{common.ErrSyntaxError, "-ERR syntax error\r\n"},
+		{common.ErrWrongType, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{common.ErrNoAuth, "-NOAUTH Authentication required.\r\n"},
+		{common.NewError(common.CodeMoved, "3999 127.0.0.1:6381"), "-MOVED 3999 127.0.0.1:6381\r\n"},
+		// the code of a wrapped error goes first
+		{fmt.Errorf("CONFIG SET failed - 'x': %w", common.ErrUnknownConfigParam), "-ERR CONFIG SET failed - 'x': unknown config parameter\r\n"},
+		{fmt.Errorf("saving: %w", common.ErrOOM), "-OOM saving: command not allowed when used memory > 'maxmemory'.\r\n"},
+		// errors without a code are ERR
+		{io.ErrUnexpectedEOF, "-ERR unexpected EOF\r\n"},
+		// a line break can't end the reply early
+		{fmt.Errorf("%w 'x\r\n+INJECTED'", common.ErrUnknownConfigParam), "-ERR unknown config parameter 'x  +INJECTED'\r\n"},
+	}
+	for _, tt := range tests {
+		res := errorReply(tt.err)
+		if got := wire(r, res); got != tt.want {
+			t.Errorf("errorReply(%v): expected %q, got %q", tt.err, tt.want, got)
+		}
+		if res.Err() != tt.err {
+			t.Errorf("errorReply(%v): lost the error, got %v", tt.err, res.Err())
+		}
+	}
+
+	if !errors.Is(common.ErrHelloNoAuth, common.CodeNoAuth) || errors.Is(common.ErrOOM, common.CodeErr) {
+		t.Errorf("Expected errors.Is to match the code of an error")
+	}
+	if common.CodeOf(fmt.Errorf("x: %w", common.ErrWrongType)) != common.CodeWrongType || common.CodeOf(io.EOF) != common.CodeErr {
+		t.Errorf("Unexpected CodeOf")
+	}
+}
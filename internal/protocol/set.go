@@ -0,0 +1,199 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// SRANDMEMBER key [count]
+func validateSrandmember(req *RESPReq) error {
+	if len(req.args) > 3 {
+		return common.ErrSyntaxError
+	}
+	if len(req.args) == 3 {
+		if _, err := strconv.Atoi(req.args[2]); err != nil {
+			return common.ErrNotIntOROutOfRange
+		}
+	}
+	return nil
+}
+
+// SINTERCARD numkeys key [key ...] [LIMIT limit]
+func validateSintercard(req *RESPReq) error {
+	_, _, err := sintercardArgs(req.args)
+	return err
+}
+
+func sintercardKeys(args []string) []string {
+	keys, _, _ := sintercardArgs(args)
+	return keys
+}
+
+func sintercardArgs(args []string) (keys []string, limit int, err error) {
+	numKeys, err := strconv.Atoi(args[1])
+	if err != nil || numKeys < 1 {
+		return nil, 0, common.ErrNumKeysNotPositive
+	}
+	if numKeys > len(args)-2 {
+		return nil, 0, common.ErrNumKeysTooMany
+	}
+	keys = args[2 : 2+numKeys]
+	for i := 2 + numKeys; i < len(args); i += 2 {
+		if !strings.EqualFold(args[i], "LIMIT") || i+1 >= len(args) {
+			return nil, 0, common.ErrSyntaxError
+		}
+		if limit, err = strconv.Atoi(args[i+1]); err != nil || limit < 0 {
+			return nil, 0, common.ErrLimitNegative
+		}
+	}
+	return keys, limit, nil
+}
+
+func (r *RESP) saddCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.SAdd(req.args[1], req.args[2:])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) sremCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.SRem(req.args[1], req.args[2:])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) sismemberCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	is, err := mem.SIsMember(req.args[1], req.args[2])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(is)), nil
+}
+
+func (r *RESP) smismemberCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	are, err := mem.SMIsMember(req.args[1], req.args[2:])
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*RESPRes, len(are))
+	for i, is := range are {
+		items[i] = intReply(int64(is))
+	}
+	return arrayReply(items...), nil
+}
+
+func (r *RESP) smembersCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	members, err := mem.SMembers(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return setReply(bulkStrings(members)...), nil
+}
+
+func (r *RESP) scardCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.SCard(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+// spopCommand replies a member or null, a set of members with count
+func (r *RESP) spopCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	count := 1
+	if len(req.args) == 3 {
+		count, _ = strconv.Atoi(req.args[2])
+	}
+	popped, err := mem.SPop(req.args[1], count)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(req.args) == 3:
+		return setReply(bulkStrings(popped)...), nil
+	case len(popped) == 0:
+		return nullReply(), nil
+	}
+	return bulkReply(popped[0]), nil
+}
+
+func (r *RESP) srandmemberCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	count := 1
+	if len(req.args) == 3 {
+		count, _ = strconv.Atoi(req.args[2])
+	}
+	members, err := mem.SRandMember(req.args[1], count)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(req.args) == 3:
+		return bulkArrayReply(members), nil
+	case len(members) == 0:
+		return nullReply(), nil
+	}
+	return bulkReply(members[0]), nil
+}
+
+func (r *RESP) smoveCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.SMove(req.args[1], req.args[2], req.args[3])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+// setAlgebraCommand backs SINTER, SUNION and SDIFF
+func (r *RESP) setAlgebraCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	op := mem.SInter
+	switch req.cmd {
+	case "sunion":
+		op = mem.SUnion
+	case "sdiff":
+		op = mem.SDiff
+	}
+	members, err := op(req.args[1:])
+	if err != nil {
+		return nil, err
+	}
+	return setReply(bulkStrings(members)...), nil
+}
+
+// setAlgebraStoreCommand backs SINTERSTORE, SUNIONSTORE and SDIFFSTORE
+func (r *RESP) setAlgebraStoreCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	op := mem.SInterStore
+	switch req.cmd {
+	case "sunionstore":
+		op = mem.SUnionStore
+	case "sdiffstore":
+		op = mem.SDiffStore
+	}
+	n, err := op(req.args[1], req.args[2:])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) sintercardCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	keys, limit, _ := sintercardArgs(req.args)
+	n, err := mem.SInterCard(keys, limit)
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) sscanCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	cursor, members, err := mem.SScan(req.args[1], req.scan.cursor, req.scan.match, req.scan.count)
+	if err != nil {
+		return nil, err
+	}
+	return arrayReply(bulkReply(strconv.FormatUint(cursor, 10)), bulkArrayReply(members)), nil
+}
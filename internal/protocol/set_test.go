@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func TestProcessSetFamily(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	resp := &RESP{DBs: dbs}
+	idx := 0
+	run := func(args ...string) string {
+		req, err := NewRequest(args)
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		return wire(resp, res)
+	}
+
+	// integer members keep the sets ordered, so the replies are stable
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"sadd", "a", "3", "1", "2", "1"}, ":3\r\n"},
+		{[]string{"sadd", "b", "2", "3", "4"}, ":3\r\n"},
+		{[]string{"smembers", "a"}, "*3\r\n$1\r\n1\r\n$1\r\n2\r\n$1\r\n3\r\n"},
+		{[]string{"scard", "a"}, ":3\r\n"},
+		{[]string{"sismember", "a", "2"}, ":1\r\n"},
+		{[]string{"smismember", "a", "1", "9"}, "*2\r\n:1\r\n:0\r\n"},
+		{[]string{"sinter", "a", "b"}, "*2\r\n$1\r\n2\r\n$1\r\n3\r\n"},
+		{[]string{"sinter", "a", "missing"}, "*0\r\n"},
+		{[]string{"sdiff", "a", "b"}, "*1\r\n$1\r\n1\r\n"},
+		{[]string{"sunionstore", "u", "a", "b"}, ":4\r\n"},
+		{[]string{"sintercard", "2", "a", "b"}, ":2\r\n"},
+		{[]string{"sintercard", "2", "a", "b", "LIMIT", "1"}, ":1\r\n"},
+		{[]string{"sintercard", "0", "a"}, "-ERR numkeys should be greater than 0\r\n"},
+		{[]string{"sintercard", "3", "a", "b"}, "-ERR Number of keys can't be greater than number of args\r\n"},
+		{[]string{"sintercard", "1", "a", "LIMIT", "-1"}, "-ERR LIMIT can't be negative\r\n"},
+		{[]string{"sintercard", "1", "a", "b"}, "-ERR syntax error\r\n"},
+		{[]string{"smove", "a", "b", "1"}, ":1\r\n"},
+		{[]string{"smove", "a", "b", "1"}, ":0\r\n"},
+		{[]string{"srem", "a", "2", "3"}, ":2\r\n"},
+		{[]string{"exists", "a"}, ":0\r\n"},
+		{[]string{"sdiffstore", "d", "missing", "b"}, ":0\r\n"},
+		{[]string{"spop", "missing"}, "$-1\r\n"},
+		{[]string{"spop", "missing", "2"}, "*0\r\n"},
+		{[]string{"spop", "b", "-1"}, "-ERR value is out of range, must be positive\r\n"},
+		{[]string{"srandmember", "missing"}, "$-1\r\n"},
+		{[]string{"srandmember", "b", "1", "2"}, "-ERR syntax error\r\n"},
+		{[]string{"sscan", "b", "0", "MATCH", "4"}, "*2\r\n$1\r\n0\r\n*1\r\n$1\r\n4\r\n"},
+		{[]string{"sscan", "b", "0", "NOVALUES"}, "-ERR syntax error\r\n"},
+		{[]string{"type", "b"}, "+set\r\n"},
+		{[]string{"type", "missing"}, "+none\r\n"},
+		{[]string{"set", "s", "v"}, "+OK\r\n"},
+		{[]string{"type", "s"}, "+string\r\n"},
+		{[]string{"sadd", "s", "v"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"sunion", "b", "s"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		// the destination of a STORE is overwritten whatever it held
+		{[]string{"sinterstore", "s", "b", "u"}, ":4\r\n"},
+		{[]string{"type", "s"}, "+set\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args...); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", strings.Join(tt.args, " "), tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the server wide counters, shared by every connection
+type Stats struct {
+	TotalCommands       atomic.Int64
+	TotalConnections    atomic.Int64
+	RejectedConnections atomic.Int64
+	Slowlog             Slowlog
+}
+
+// Reset clears the counters for CONFIG RESETSTAT, like redis it leaves the
+// slowlog alone
+func (s *Stats) Reset() {
+	s.TotalCommands.Store(0)
+	s.TotalConnections.Store(0)
+	s.RejectedConnections.Store(0)
+}
+
+// the slowlog does not keep huge commands around
+const (
+	slowlogMaxArgs   = 32
+	slowlogMaxArgLen = 128
+)
+
+type SlowlogEntry struct {
+	ID       int64
+	Time     int64 // unix seconds
+	Duration int64 // microseconds
+	Args     []string
+	Client   string
+}
+
+type Slowlog struct {
+	mu      sync.Mutex
+	nextID  int64
+	entries []SlowlogEntry // newest first
+}
+
+func (s *Slowlog) Add(args []string, took time.Duration, client string, maxLen int) {
+	n := min(len(args), slowlogMaxArgs)
+	kept := make([]string, 0, n)
+	for i, arg := range args[:n] {
+		if i == slowlogMaxArgs-1 && len(args) > slowlogMaxArgs {
+			kept = append(kept, "... ("+strconv.Itoa(len(args)-slowlogMaxArgs+1)+" more arguments)")
+			break
+		}
+		if len(arg) > slowlogMaxArgLen {
+			arg = arg[:slowlogMaxArgLen] + "... (" + strconv.Itoa(len(arg)-slowlogMaxArgLen) + " more bytes)"
+		}
+		kept = append(kept, arg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := SlowlogEntry{
+		ID:       s.nextID,
+		Time:     time.Now().Unix(),
+		Duration: took.Microseconds(),
+		Args:     kept,
+		Client:   client,
+	}
+	s.nextID++
+	s.entries = append([]SlowlogEntry{entry}, s.entries...)
+	if len(s.entries) > maxLen {
+		s.entries = s.entries[:maxLen]
+	}
+}
+
+// Get returns up to count of the newest entries, all of them when count < 0
+func (s *Slowlog) Get(count int) []SlowlogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if count < 0 || count > len(s.entries) {
+		count = len(s.entries)
+	}
+	return append([]SlowlogEntry{}, s.entries[:count]...)
+}
+
+func (s *Slowlog) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *Slowlog) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = nil
+}
@@ -0,0 +1,317 @@
+package protocol
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func getExpireType(arg string) int8 {
+	switch arg {
+	case "EX":
+		return store.ExpireEX
+	case "PX":
+		return store.ExpirePX
+	case "EXAT":
+		return store.ExpireEXAT
+	case "PXAT":
+		return store.ExpirePXAT
+	case "KEEPTTL":
+		return store.ExpireKEEPTTL
+	default:
+		return store.ExpireNone
+	}
+}
+
+// validExpire reports whether val is a positive time for the expire option
+// typ whose deadline in unix ms fits an int64, as redis checks it
+func validExpire(typ int8, val int) bool {
+	ms := int64(val)
+	if ms <= 0 {
+		return false
+	}
+	if typ == store.ExpireEX || typ == store.ExpireEXAT {
+		if ms > math.MaxInt64/1000 {
+			return false
+		}
+		ms *= 1000
+	}
+	if typ == store.ExpireEX || typ == store.ExpirePX {
+		return ms <= math.MaxInt64-time.Now().UnixMilli()
+	}
+	return true
+}
+
+// validateSet parses the SET options into req.setArgs
+func validateSet(req *RESPReq) error {
+	if len(req.args) > 7 {
+		return common.ErrWrongNumberArgs
+	}
+	i := 3
+	for i < len(req.args) {
+		arg := strings.ToUpper(req.args[i])
+		if arg == "EX" || arg == "PX" || arg == "EXAT" || arg == "PXAT" {
+			req.setArgs.ExpType = getExpireType(arg)
+			if i+1 >= len(req.args) {
+				return common.ErrWrongNumberArgs
+			}
+			val, err := strconv.Atoi(req.args[i+1])
+			if err != nil || !validExpire(req.setArgs.ExpType, val) {
+				return common.ErrInvalidExpireTime
+			}
+			req.setArgs.ExpVal = val
+			i += 2
+		} else if arg == "NX" {
+			req.setArgs.NX_XX = 1
+			i++
+		} else if arg == "XX" {
+			req.setArgs.NX_XX = 2
+			i++
+		} else if arg == "KEEPTTL" {
+			req.setArgs.KeepTTL = true
+			i++
+		} else if arg == "GET" {
+			req.setArgs.Get = true
+			i++
+		} else {
+			// Unknown flag or argument type
+			return common.ErrWrongNumberArgs
+		}
+	}
+	return nil
+}
+
+// GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds |
+// PXAT unix-time-milliseconds | PERSIST]
+func validateGetex(req *RESPReq) error {
+	for i := 2; i < len(req.args); {
+		if req.setArgs.ExpType != store.ExpireNone {
+			return common.ErrSyntaxError
+		}
+		arg := strings.ToUpper(req.args[i])
+		switch arg {
+		case "EX", "PX", "EXAT", "PXAT":
+			if i+1 >= len(req.args) {
+				return common.ErrSyntaxError
+			}
+			val, err := strconv.Atoi(req.args[i+1])
+			if err != nil {
+				return common.ErrNotIntOROutOfRange
+			}
+			req.setArgs.ExpType = getExpireType(arg)
+			if !validExpire(req.setArgs.ExpType, val) {
+				return common.ErrInvalidExpireTime
+			}
+			req.setArgs.ExpVal = val
+			i += 2
+		case "PERSIST":
+			req.setArgs.ExpType = store.ExpirePERSIST
+			i++
+		default:
+			return common.ErrSyntaxError
+		}
+	}
+	return nil
+}
+
+// SETEX key seconds value and PSETEX key milliseconds value are SET with EX
+// or PX
+func validateSetex(req *RESPReq) error {
+	val, err := strconv.Atoi(req.args[2])
+	if err != nil {
+		return common.ErrNotIntOROutOfRange
+	}
+	req.setArgs.ExpType = store.ExpireEX
+	if req.cmd == "psetex" {
+		req.setArgs.ExpType = store.ExpirePX
+	}
+	if !validExpire(req.setArgs.ExpType, val) {
+		return common.ErrInvalidExpireTime
+	}
+	req.setArgs.ExpVal = val
+	return nil
+}
+
+// SETRANGE key offset value
+func validateSetrange(req *RESPReq) error {
+	offset, err := strconv.Atoi(req.args[2])
+	if err != nil {
+		return common.ErrNotIntOROutOfRange
+	}
+	if offset < 0 {
+		return common.ErrOffsetOutOfRange
+	}
+	return nil
+}
+
+func (r *RESP) getCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	value, err := mem.Get(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nullReply(), nil
+	}
+	return bulkReply(string(value)), nil
+}
+
+func (r *RESP) setCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	if req.argsLen == 3 {
+		mem.Set(req.args[1], []byte(req.args[2]))
+		return okReply(), nil
+	}
+	counter, oldRet, err := mem.Setx(req.args[1], []byte(req.args[2]), req.setArgs)
+	switch {
+	case err != nil:
+		return nil, err
+	case counter == 0:
+		return nullReply(), nil
+	case oldRet != nil:
+		return bulkReply(string(oldRet)), nil
+	}
+	return okReply(), nil
+}
+
+func (r *RESP) setnxCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	set, _, _ := mem.Setx(req.args[1], []byte(req.args[2]), store.SetArgs{NX_XX: 1})
+	return intReply(int64(set)), nil
+}
+
+// setexCommand backs SETEX and PSETEX
+func (r *RESP) setexCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	mem.Setx(req.args[1], []byte(req.args[3]), req.setArgs)
+	return okReply(), nil
+}
+
+func (r *RESP) getdelCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	value, err := mem.GetDel(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nullReply(), nil
+	}
+	return bulkReply(string(value)), nil
+}
+
+func (r *RESP) getexCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	value, err := mem.GetEx(req.args[1], req.setArgs)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nullReply(), nil
+	}
+	return bulkReply(string(value)), nil
+}
+
+func (r *RESP) appendCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.StrLen(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	if int64(n+len(req.args[2])) > r.maxBulkLen() {
+		return nil, common.ErrStringTooLong
+	}
+	n, err = mem.Append(req.args[1], []byte(req.args[2]))
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) strlenCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.StrLen(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) getrangeCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	start, _ := strconv.Atoi(req.args[2])
+	end, _ := strconv.Atoi(req.args[3])
+	value, err := mem.GetRange(req.args[1], start, end)
+	if err != nil {
+		return nil, err
+	}
+	return bulkReply(string(value)), nil
+}
+
+func (r *RESP) setrangeCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	offset, _ := strconv.Atoi(req.args[2])
+	if len(req.args[3]) > 0 && int64(offset)+int64(len(req.args[3])) > r.maxBulkLen() {
+		return nil, common.ErrStringTooLong
+	}
+	n, err := mem.SetRange(req.args[1], offset, []byte(req.args[3]))
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) mgetCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	values := mem.MGet(req.args[1:])
+	items := make([]*RESPRes, len(values))
+	for i, value := range values {
+		if value == nil {
+			items[i] = nullReply()
+		} else {
+			items[i] = bulkReply(string(value))
+		}
+	}
+	return arrayReply(items...), nil
+}
+
+// msetCommand backs MSET and MSETNX
+func (r *RESP) msetCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n := (len(req.args) - 1) / 2
+	keys := make([]string, n)
+	values := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		keys[i] = req.args[1+2*i]
+		values[i] = []byte(req.args[2+2*i])
+	}
+	if req.cmd == "msetnx" {
+		return intReply(int64(mem.MSetNX(keys, values))), nil
+	}
+	mem.MSet(keys, values)
+	return okReply(), nil
+}
+
+// incrCommand backs INCR, INCRBY, DECR and DECRBY
+func (r *RESP) incrCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	by := int64(1)
+	if len(req.args) == 3 {
+		by, _ = strconv.ParseInt(req.args[2], 10, 64)
+	}
+	var n int64
+	var err error
+	if req.cmd == "incr" || req.cmd == "incrby" {
+		n, err = mem.Incrby(req.args[1], by)
+	} else {
+		n, err = mem.Decrby(req.args[1], by)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return intReply(n), nil
+}
+
+func validateIncrbyfloat(req *RESPReq) error {
+	_, err := store.ParseFloat([]byte(req.args[2]))
+	return err
+}
+
+func (r *RESP) incrbyfloatCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	by, _ := store.ParseFloat([]byte(req.args[2]))
+	value, err := mem.IncrbyFloat(req.args[1], by)
+	if err != nil {
+		return nil, err
+	}
+	return bulkReply(string(value)), nil
+}
@@ -0,0 +1,114 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func TestProcessMultiKeyStrings(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	resp := &RESP{DBs: dbs}
+	idx := 0
+	run := func(args ...string) string {
+		req, err := NewRequest(args)
+		if err != nil {
+			t.Fatalf("NewRequest %q failed: %v", args, err)
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			t.Fatalf("Process %q failed: %v", args, err)
+		}
+		return wire(resp, res)
+	}
+
+	if got := run("mset", "a", "1", "b", "2"); got != "+OK\r\n" {
+		t.Fatalf("Unexpected MSET reply %q", got)
+	}
+	if got := run("mget", "a", "missing", "b"); got != "*3\r\n$1\r\n1\r\n$-1\r\n$1\r\n2\r\n" {
+		t.Errorf("Unexpected MGET reply %q", got)
+	}
+	if got := run("msetnx", "b", "3", "c", "4"); got != ":0\r\n" {
+		t.Errorf("Expected MSETNX to refuse an existing key, got %q", got)
+	}
+	if got := run("msetnx", "c", "3", "d", "4"); got != ":1\r\n" {
+		t.Errorf("Expected MSETNX to set new keys, got %q", got)
+	}
+	if got := run("mget", "b", "c", "d"); got != "*3\r\n$1\r\n2\r\n$1\r\n3\r\n$1\r\n4\r\n" {
+		t.Errorf("Unexpected MGET reply %q", got)
+	}
+}
+
+func TestProcessStringFamily(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	resp := &RESP{DBs: dbs}
+	idx := 0
+	run := func(args ...string) string {
+		req, err := NewRequest(args)
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		return wire(resp, res)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"append", "k", "Hello"}, ":5\r\n"},
+		{[]string{"append", "k", " World"}, ":11\r\n"},
+		{[]string{"strlen", "k"}, ":11\r\n"},
+		{[]string{"strlen", "missing"}, ":0\r\n"},
+		{[]string{"getrange", "k", "-5", "-1"}, "$5\r\nWorld\r\n"},
+		{[]string{"getrange", "k", "a", "1"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"setrange", "k", "6", "Redis"}, ":11\r\n"},
+		{[]string{"setrange", "k", "-1", "x"}, "-ERR offset is out of range\r\n"},
+		{[]string{"setrange", "k", "536870912", "x"}, "-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n"},
+		{[]string{"setrange", "pad", "2", "x"}, ":3\r\n"},
+		{[]string{"get", "pad"}, "$3\r\n\x00\x00x\r\n"},
+		{[]string{"getex", "k", "EX", "100"}, "$11\r\nHello Redis\r\n"},
+		{[]string{"ttl", "k"}, ":100\r\n"},
+		{[]string{"getex", "k", "PERSIST"}, "$11\r\nHello Redis\r\n"},
+		{[]string{"ttl", "k"}, ":-1\r\n"},
+		{[]string{"getex", "k", "EX", "0"}, "-ERR invalid expire time\r\n"},
+		{[]string{"getex", "k", "EX", "9999999999999999"}, "-ERR invalid expire time\r\n"},
+		{[]string{"getex", "k", "PX", "9223372036854775807"}, "-ERR invalid expire time\r\n"},
+		{[]string{"getex", "k", "EX", "1", "PERSIST"}, "-ERR syntax error\r\n"},
+		{[]string{"getdel", "k"}, "$11\r\nHello Redis\r\n"},
+		{[]string{"getdel", "k"}, "$-1\r\n"},
+		{[]string{"setnx", "n", "1"}, ":1\r\n"},
+		{[]string{"setnx", "n", "2"}, ":0\r\n"},
+		{[]string{"setex", "e", "100", "v"}, "+OK\r\n"},
+		{[]string{"ttl", "e"}, ":100\r\n"},
+		{[]string{"psetex", "e", "1500", "v2"}, "+OK\r\n"},
+		{[]string{"ttl", "e"}, ":1\r\n"},
+		{[]string{"setex", "e", "-1", "v"}, "-ERR invalid expire time\r\n"},
+		{[]string{"setex", "e", "9999999999999999", "v"}, "-ERR invalid expire time\r\n"},
+		{[]string{"set", "e", "v", "EX", "0"}, "-ERR invalid expire time\r\n"},
+		{[]string{"set", "e", "v", "EX", "-1"}, "-ERR invalid expire time\r\n"},
+		{[]string{"set", "e", "v", "EXAT", "9999999999999999"}, "-ERR invalid expire time\r\n"},
+		{[]string{"expire", "e", "9223372036854775807"}, "-ERR invalid expire time\r\n"},
+		{[]string{"expire", "e", "9223372036854775"}, "-ERR invalid expire time\r\n"},
+		{[]string{"ttl", "e"}, ":1\r\n"},
+		{[]string{"setex", "e", "x", "v"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"incr", "c"}, ":1\r\n"},
+		{[]string{"incrby", "c", "9223372036854775806"}, ":9223372036854775807\r\n"},
+		{[]string{"incr", "c"}, "-ERR increment or decrement would overflow\r\n"},
+		{[]string{"get", "c"}, "$19\r\n9223372036854775807\r\n"},
+		{[]string{"decrby", "d", "5"}, ":-5\r\n"},
+		{[]string{"incr", "pad"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"incrbyfloat", "f", "10.50"}, "$4\r\n10.5\r\n"},
+		{[]string{"incrbyfloat", "f", "0.1"}, "$4\r\n10.6\r\n"},
+		{[]string{"incrbyfloat", "f", "x"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"incrbyfloat", "pad", "1"}, "-ERR value is not a valid float\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args...); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.args, tt.want, got)
+		}
+	}
+}
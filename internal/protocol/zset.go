@@ -0,0 +1,443 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func parseScore(s string) (float64, error) {
+	return store.ParseFloat([]byte(s))
+}
+
+// parseScoreRange reads the min and max of a score range, each is a score,
+// -inf or +inf, excluded when it starts with (
+func parseScoreRange(lo, hi string) (store.ScoreRange, error) {
+	var r store.ScoreRange
+	var err error
+	if r.Min, r.MinEx, err = parseScoreBound(lo); err != nil {
+		return r, err
+	}
+	if r.Max, r.MaxEx, err = parseScoreBound(hi); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+func parseScoreBound(s string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	f, err := parseScore(strings.TrimPrefix(s, "("))
+	if err != nil {
+		return 0, false, common.ErrMinMaxNotFloat
+	}
+	return f, exclusive, nil
+}
+
+// parseLexRange reads the min and max of a lex range, each is - or +, or a
+// member after [ to include it or ( to exclude it
+func parseLexRange(lo, hi string) (store.LexRange, error) {
+	var r store.LexRange
+	var err error
+	if r.Min, err = parseLexBound(lo); err != nil {
+		return r, err
+	}
+	if r.Max, err = parseLexBound(hi); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+func parseLexBound(s string) (store.LexBound, error) {
+	switch {
+	case s == "-":
+		return store.LexBound{Inf: -1}, nil
+	case s == "+":
+		return store.LexBound{Inf: 1}, nil
+	case strings.HasPrefix(s, "["):
+		return store.LexBound{Member: s[1:]}, nil
+	case strings.HasPrefix(s, "("):
+		return store.LexBound{Member: s[1:], Exclusive: true}, nil
+	}
+	return store.LexBound{}, common.ErrMinMaxNotLex
+}
+
+// ZADD key [NX | XX] [GT | LT] [CH] [INCR] score member [score member ...]
+func validateZadd(req *RESPReq) error {
+	_, _, _, _, err := zaddOptions(req.args)
+	return err
+}
+
+func zaddOptions(args []string) (flags store.ZAddArgs, ch, incr bool, members []store.ScoredMember, err error) {
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			flags.NX = true
+		case "XX":
+			flags.XX = true
+		case "GT":
+			flags.GT = true
+		case "LT":
+			flags.LT = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break options
+		}
+	}
+	pairs := args[i:]
+	switch {
+	case len(pairs) == 0 || len(pairs)%2 != 0:
+		err = common.ErrSyntaxError
+	case flags.NX && flags.XX:
+		err = common.ErrZAddXXAndNX
+	case (flags.GT && flags.LT) || ((flags.GT || flags.LT) && flags.NX):
+		err = common.ErrZAddGTLTAndNX
+	case incr && len(pairs) > 2:
+		err = common.ErrZAddIncrPair
+	}
+	if err != nil {
+		return flags, false, false, nil, err
+	}
+	members = make([]store.ScoredMember, 0, len(pairs)/2)
+	for j := 0; j < len(pairs); j += 2 {
+		score, err := parseScore(pairs[j])
+		if err != nil {
+			return flags, false, false, nil, err
+		}
+		members = append(members, store.ScoredMember{Member: pairs[j+1], Score: score})
+	}
+	return flags, ch, incr, members, nil
+}
+
+// ZINCRBY key increment member
+func validateZincrby(req *RESPReq) error {
+	_, err := parseScore(req.args[2])
+	return err
+}
+
+// ZCOUNT key min max
+func validateZcount(req *RESPReq) error {
+	_, err := parseScoreRange(req.args[2], req.args[3])
+	return err
+}
+
+// ZRANGE key start stop [BYSCORE | BYLEX] [REV] [LIMIT offset count]
+// [WITHSCORES], and ZRANGESTORE dst src ... without WITHSCORES
+func validateZrange(req *RESPReq) error {
+	_, _, err := zrangeQuery(req)
+	return err
+}
+
+// zrangeQuery reads the arguments of ZRANGE, or ZRANGESTORE whose range
+// comes one argument later
+func zrangeQuery(req *RESPReq) (store.ZRangeQuery, bool, error) {
+	at := 2
+	if req.cmd == "zrangestore" {
+		at = 3
+	}
+	q := store.ZRangeQuery{Count: -1}
+	withScores, limit := false, false
+	for i := at + 2; i < len(req.args); i++ {
+		switch opt := strings.ToUpper(req.args[i]); {
+		case opt == "BYSCORE" && q.By == store.ZRangeByRank:
+			q.By = store.ZRangeByScore
+		case opt == "BYLEX" && q.By == store.ZRangeByRank:
+			q.By = store.ZRangeByLex
+		case opt == "REV":
+			q.Rev = true
+		case opt == "WITHSCORES" && req.cmd == "zrange":
+			withScores = true
+		case opt == "LIMIT" && i+2 < len(req.args):
+			var err1, err2 error
+			q.Offset, err1 = strconv.Atoi(req.args[i+1])
+			q.Count, err2 = strconv.Atoi(req.args[i+2])
+			if err1 != nil || err2 != nil {
+				return q, false, common.ErrNotIntOROutOfRange
+			}
+			limit = true
+			i += 2
+		default:
+			return q, false, common.ErrSyntaxError
+		}
+	}
+	if limit && q.By == store.ZRangeByRank {
+		return q, false, common.ErrLimitWithoutBy
+	}
+	if withScores && q.By == store.ZRangeByLex {
+		return q, false, common.ErrWithScoresByLex
+	}
+
+	lo, hi := req.args[at], req.args[at+1]
+	var err error
+	switch q.By {
+	case store.ZRangeByRank:
+		var err1, err2 error
+		q.Start, err1 = strconv.Atoi(lo)
+		q.Stop, err2 = strconv.Atoi(hi)
+		if err1 != nil || err2 != nil {
+			err = common.ErrNotIntOROutOfRange
+		}
+	case store.ZRangeByScore:
+		// a reversed range is written from max to min
+		if q.Rev {
+			lo, hi = hi, lo
+		}
+		q.Score, err = parseScoreRange(lo, hi)
+	case store.ZRangeByLex:
+		if q.Rev {
+			lo, hi = hi, lo
+		}
+		q.Lex, err = parseLexRange(lo, hi)
+	}
+	return q, withScores, err
+}
+
+// ZUNIONSTORE and ZINTERSTORE destination numkeys key [key ...]
+// [WEIGHTS weight [weight ...]] [AGGREGATE SUM | MIN | MAX]
+func validateZstore(req *RESPReq) error {
+	_, _, _, err := zstoreArgs(req.args)
+	return err
+}
+
+func zstoreKeys(args []string) []string {
+	keys, _, _, _ := zstoreArgs(args)
+	return keys
+}
+
+func zstoreArgs(args []string) (keys []string, weights []float64, agg store.ZAggregate, err error) {
+	numKeys, err := strconv.Atoi(args[2])
+	if err != nil {
+		return nil, nil, 0, common.ErrNotIntOROutOfRange
+	}
+	if numKeys < 1 {
+		return nil, nil, 0, common.ErrZStoreNoKeys
+	}
+	if numKeys > len(args)-3 {
+		return nil, nil, 0, common.ErrSyntaxError
+	}
+	keys = args[3 : 3+numKeys]
+	for i := 3 + numKeys; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "WEIGHTS" && i+numKeys < len(args):
+			weights = make([]float64, numKeys)
+			for j := range weights {
+				i++
+				if weights[j], err = parseScore(args[i]); err != nil {
+					return nil, nil, 0, common.ErrWeightNotFloat
+				}
+			}
+		case opt == "AGGREGATE" && i+1 < len(args):
+			i++
+			switch strings.ToUpper(args[i]) {
+			case "SUM":
+				agg = store.ZAggregateSum
+			case "MIN":
+				agg = store.ZAggregateMin
+			case "MAX":
+				agg = store.ZAggregateMax
+			default:
+				return nil, nil, 0, common.ErrSyntaxError
+			}
+		default:
+			return nil, nil, 0, common.ErrSyntaxError
+		}
+	}
+	return keys, weights, agg, nil
+}
+
+// scoredReply is a list of members, with their scores when asked: flat in
+// RESP2, member score pairs in RESP3
+func (r *RESP) scoredReply(members []store.ScoredMember, withScores bool) *RESPRes {
+	items := make([]*RESPRes, 0, len(members))
+	for _, m := range members {
+		switch {
+		case !withScores:
+			items = append(items, bulkReply(m.Member))
+		case r.protoVersion() == RESP3:
+			items = append(items, arrayReply(bulkReply(m.Member), doubleReply(m.Score)))
+		default:
+			items = append(items, bulkReply(m.Member), doubleReply(m.Score))
+		}
+	}
+	return arrayReply(items...)
+}
+
+func (r *RESP) zaddCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	flags, ch, incr, members, _ := zaddOptions(req.args)
+	if incr {
+		score, applied, err := mem.ZAddIncr(req.args[1], flags, members[0].Member, members[0].Score)
+		if err != nil {
+			return nil, err
+		}
+		if !applied {
+			return nullReply(), nil
+		}
+		return doubleReply(score), nil
+	}
+	added, updated, err := mem.ZAdd(req.args[1], flags, members)
+	if err != nil {
+		return nil, err
+	}
+	if ch {
+		return intReply(int64(added + updated)), nil
+	}
+	return intReply(int64(added)), nil
+}
+
+func (r *RESP) zincrbyCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	incr, _ := parseScore(req.args[2])
+	score, err := mem.ZIncrBy(req.args[1], req.args[3], incr)
+	if err != nil {
+		return nil, err
+	}
+	return doubleReply(score), nil
+}
+
+func (r *RESP) zremCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.ZRem(req.args[1], req.args[2:])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) zscoreCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	score, ok, err := mem.ZScore(req.args[1], req.args[2])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nullReply(), nil
+	}
+	return doubleReply(score), nil
+}
+
+func (r *RESP) zmscoreCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	scores, found, err := mem.ZMScore(req.args[1], req.args[2:])
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*RESPRes, len(scores))
+	for i, score := range scores {
+		items[i] = nullReply()
+		if found[i] {
+			items[i] = doubleReply(score)
+		}
+	}
+	return arrayReply(items...), nil
+}
+
+func (r *RESP) zcardCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	n, err := mem.ZCard(req.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) zcountCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	rng, _ := parseScoreRange(req.args[2], req.args[3])
+	n, err := mem.ZCount(req.args[1], rng)
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+// zrankCommand backs ZRANK and ZREVRANK
+func (r *RESP) zrankCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	rank, ok, err := mem.ZRank(req.args[1], req.args[2], req.cmd == "zrevrank")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nullReply(), nil
+	}
+	return intReply(int64(rank)), nil
+}
+
+func (r *RESP) zrangeCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	q, withScores, _ := zrangeQuery(req)
+	members, err := mem.ZRange(req.args[1], q)
+	if err != nil {
+		return nil, err
+	}
+	return r.scoredReply(members, withScores), nil
+}
+
+func (r *RESP) zrangestoreCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	q, _, _ := zrangeQuery(req)
+	n, err := mem.ZRangeStore(req.args[1], req.args[2], q)
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+// zpopCommand backs ZPOPMIN and ZPOPMAX: a member and its score, several
+// with count
+func (r *RESP) zpopCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	count := 1
+	if len(req.args) == 3 {
+		count, _ = strconv.Atoi(req.args[2])
+	}
+	pop := mem.ZPopMin
+	if req.cmd == "zpopmax" {
+		pop = mem.ZPopMax
+	}
+	popped, err := pop(req.args[1], count)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.args) == 2 && len(popped) == 1 {
+		return arrayReply(bulkReply(popped[0].Member), doubleReply(popped[0].Score)), nil
+	}
+	return r.scoredReply(popped, true), nil
+}
+
+// blockingZpopCommand backs BZPOPMIN and BZPOPMAX: the key, the member and
+// its score popped from the first non empty sorted set
+func (r *RESP) blockingZpopCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	keys := req.args[1 : len(req.args)-1]
+	key, popped, err := mem.ZPopFirst(keys, req.cmd == "bzpopmax")
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		timeout, _ := parseTimeout(req.args[len(req.args)-1])
+		return blockedReply(keys, timeout), nil
+	}
+	return arrayReply(bulkReply(key), bulkReply(popped.Member), doubleReply(popped.Score)), nil
+}
+
+// zstoreCommand backs ZUNIONSTORE and ZINTERSTORE
+func (r *RESP) zstoreCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	keys, weights, agg, _ := zstoreArgs(req.args)
+	op := mem.ZUnionStore
+	if req.cmd == "zinterstore" {
+		op = mem.ZInterStore
+	}
+	n, err := op(req.args[1], keys, weights, agg)
+	if err != nil {
+		return nil, err
+	}
+	return intReply(int64(n)), nil
+}
+
+func (r *RESP) zscanCommand(req *RESPReq, _ *int, mem *store.InMemoryStore) (*RESPRes, error) {
+	cursor, members, err := mem.ZScan(req.args[1], req.scan.cursor, req.scan.match, req.scan.count)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]string, 0, 2*len(members))
+	for _, m := range members {
+		items = append(items, m.Member, formatDouble(m.Score))
+	}
+	return arrayReply(bulkReply(strconv.FormatUint(cursor, 10)), bulkArrayReply(items)), nil
+}
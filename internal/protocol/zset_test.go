@@ -0,0 +1,99 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+func TestProcessSortedSetFamily(t *testing.T) {
+	dbs := store.NewInMemoryStoreArray(1)
+	resp := &RESP{DBs: dbs}
+	idx := 0
+	run := func(args ...string) string {
+		req, err := NewRequest(args)
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		res, err := resp.Process(req, &idx, dbs[idx])
+		if err != nil {
+			return wire(resp, errorReply(err))
+		}
+		return wire(resp, res)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"zadd", "z", "1", "a", "2", "b", "3", "c"}, ":3\r\n"},
+		{[]string{"zadd", "z", "CH", "5", "a", "4", "d"}, ":2\r\n"},
+		{[]string{"zadd", "z", "XX", "INCR", "1", "missing"}, "$-1\r\n"},
+		{[]string{"zadd", "z", "INCR", "0.5", "b"}, "$3\r\n2.5\r\n"},
+		{[]string{"zadd", "z", "NX", "XX", "1", "a"}, "-ERR XX and NX options at the same time are not compatible\r\n"},
+		{[]string{"zadd", "z", "GT", "NX", "1", "a"}, "-ERR GT, LT, and/or NX options at the same time are not compatible\r\n"},
+		{[]string{"zadd", "z", "INCR", "1", "a", "2", "b"}, "-ERR INCR option supports a single increment-element pair\r\n"},
+		{[]string{"zadd", "z", "1", "a", "2"}, "-ERR syntax error\r\n"},
+		{[]string{"zadd", "z", "nan", "a"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"zscore", "z", "a"}, "$1\r\n5\r\n"},
+		{[]string{"zscore", "z", "missing"}, "$-1\r\n"},
+		{[]string{"zmscore", "z", "c", "missing"}, "*2\r\n$1\r\n3\r\n$-1\r\n"},
+		{[]string{"zincrby", "z", "-inf", "d"}, "$4\r\n-inf\r\n"},
+		{[]string{"zincrby", "z", "x", "d"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"zcard", "z"}, ":4\r\n"},
+		{[]string{"zcount", "z", "(2.5", "+inf"}, ":2\r\n"},
+		{[]string{"zcount", "z", "a", "1"}, "-ERR min or max is not a float\r\n"},
+		{[]string{"zrank", "z", "c"}, ":2\r\n"},
+		{[]string{"zrevrank", "z", "c"}, ":1\r\n"},
+		{[]string{"zrank", "z", "missing"}, "$-1\r\n"},
+		{[]string{"zrange", "z", "0", "1"}, "*2\r\n$1\r\nd\r\n$1\r\nb\r\n"},
+		{[]string{"zrange", "z", "-1", "-1", "WITHSCORES"}, "*2\r\n$1\r\na\r\n$1\r\n5\r\n"},
+		{[]string{"zrange", "z", "(3", "+inf", "BYSCORE"}, "*1\r\n$1\r\na\r\n"},
+		{[]string{"zrange", "z", "+inf", "-inf", "BYSCORE", "REV", "LIMIT", "1", "2"}, "*2\r\n$1\r\nc\r\n$1\r\nb\r\n"},
+		{[]string{"zrange", "z", "0", "-1", "LIMIT", "0", "1"}, "-ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX\r\n"},
+		{[]string{"zrange", "z", "-", "+", "BYLEX", "WITHSCORES"}, "-ERR syntax error, WITHSCORES not supported in combination with BYLEX\r\n"},
+		{[]string{"zrange", "z", "a", "b", "BYLEX"}, "-ERR min or max not valid string range item\r\n"},
+		{[]string{"zrange", "z", "0", "1", "BYSCORE", "BYLEX"}, "-ERR syntax error\r\n"},
+		{[]string{"zadd", "lex", "0", "a", "0", "b", "0", "c"}, ":3\r\n"},
+		{[]string{"zrange", "lex", "[b", "+", "BYLEX"}, "*2\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{[]string{"zrange", "lex", "(b", "-", "BYLEX", "REV"}, "*1\r\n$1\r\na\r\n"},
+		{[]string{"zrangestore", "dst", "z", "1", "2"}, ":2\r\n"},
+		{[]string{"zrange", "dst", "0", "-1"}, "*2\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{[]string{"zrangestore", "dst", "z", "0", "-1", "WITHSCORES"}, "-ERR syntax error\r\n"},
+		{[]string{"zunionstore", "u", "2", "dst", "lex", "WEIGHTS", "2", "1", "AGGREGATE", "MAX"}, ":3\r\n"},
+		{[]string{"zrange", "u", "0", "-1", "WITHSCORES"}, "*6\r\n$1\r\na\r\n$1\r\n0\r\n$1\r\nb\r\n$1\r\n5\r\n$1\r\nc\r\n$1\r\n6\r\n"},
+		{[]string{"zinterstore", "i", "2", "z", "dst"}, ":2\r\n"},
+		{[]string{"zscore", "i", "c"}, "$1\r\n6\r\n"},
+		{[]string{"zinterstore", "i", "0", "z"}, "-ERR at least 1 input key is needed for ZUNIONSTORE/ZINTERSTORE\r\n"},
+		{[]string{"zinterstore", "i", "2", "z"}, "-ERR syntax error\r\n"},
+		{[]string{"zunionstore", "u", "1", "z", "WEIGHTS", "x"}, "-ERR weight value is not a float\r\n"},
+		{[]string{"zunionstore", "u", "1", "z", "AGGREGATE", "AVG"}, "-ERR syntax error\r\n"},
+		{[]string{"zscan", "lex", "0", "MATCH", "b"}, "*2\r\n$1\r\n0\r\n*2\r\n$1\r\nb\r\n$1\r\n0\r\n"},
+		{[]string{"zpopmin", "z"}, "*2\r\n$1\r\nd\r\n$4\r\n-inf\r\n"},
+		{[]string{"zpopmax", "z", "2"}, "*4\r\n$1\r\na\r\n$1\r\n5\r\n$1\r\nc\r\n$1\r\n3\r\n"},
+		{[]string{"zpopmin", "missing"}, "*0\r\n"},
+		{[]string{"zpopmin", "z", "-1"}, "-ERR value is out of range, must be positive\r\n"},
+		{[]string{"bzpopmin", "missing", "z", "0"}, "*3\r\n$1\r\nz\r\n$1\r\nb\r\n$3\r\n2.5\r\n"},
+		{[]string{"exists", "z"}, ":0\r\n"},
+		{[]string{"zrem", "lex", "a", "missing"}, ":1\r\n"},
+		{[]string{"type", "lex"}, "+zset\r\n"},
+		{[]string{"set", "s", "v"}, "+OK\r\n"},
+		{[]string{"zadd", "s", "1", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"zunionstore", "u", "2", "lex", "s"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args...); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", strings.Join(tt.args, " "), tt.want, got)
+		}
+	}
+
+	// RESP3 pairs members with their scores, sent as doubles
+	resp.proto = RESP3
+	if got := run("zrange", "lex", "0", "0", "WITHSCORES"); got != "*1\r\n*2\r\n$1\r\nb\r\n,0\r\n" {
+		t.Errorf("Unexpected RESP3 ZRANGE %q", got)
+	}
+	if got := run("zpopmax", "lex", "1"); got != "*1\r\n*2\r\n$1\r\nc\r\n,0\r\n" {
+		t.Errorf("Unexpected RESP3 ZPOPMAX %q", got)
+	}
+}
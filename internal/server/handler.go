@@ -3,34 +3,149 @@ package server
 import (
 	"bufio"
 	"net"
+	"time"
 
+	"github.com/B-AJ-Amar/gokv/internal/common"
 	"github.com/B-AJ-Amar/gokv/internal/protocol"
-	"github.com/B-AJ-Amar/gokv/internal/store"
 )
 
-func HandleConnection(conn net.Conn, mem *[]*store.InMemoryStore) {
+// maxPendingOutput is the size of the reply buffer of a connection: replies
+// to pipelined commands are written in one go once every command already
+// received is processed, or as soon as this much output is waiting
+const maxPendingOutput = 64 * 1024
+
+// flushingReader flushes the pending replies before it reads from the
+// connection. bufio.Reader only reads from it once its buffer is drained, so
+// a pipeline is answered with as few writes as possible and a client never
+// waits on replies stuck in our buffer.
+type flushingReader struct {
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+func (f *flushingReader) Read(p []byte) (int, error) {
+	if err := f.w.Flush(); err != nil {
+		return 0, err
+	}
+	return f.conn.Read(p)
+}
+
+func (s *Server) HandleConnection(conn net.Conn) {
+	defer s.untrackConn(conn)
 	defer conn.Close()
 
-	r := bufio.NewReader(conn)
-	w := bufio.NewWriter(conn)
+	w := bufio.NewWriterSize(conn, maxPendingOutput)
+	r := bufio.NewReader(&flushingReader{conn: conn, w: w})
 	dbIndex := 0
+	resp := protocol.RESP{
+		DBs:        s.memory,
+		Saver:      s.saver,
+		AOF:        s.aof,
+		Config:     s.cfg,
+		Stats:      s.stats,
+		ClientAddr: conn.RemoteAddr().String(),
+		ClientID:   s.lastID.Add(1),
+		Shutdown:   s.shutdownCommand,
+	}
+
+	if s.clients.Add(1) > int64(s.cfg.Get().MaxClients) {
+		s.clients.Add(-1)
+		s.stats.RejectedConnections.Add(1)
+		resp.SendError(w, common.ErrMaxClients)
+		return
+	}
+	defer s.clients.Add(-1)
+	s.stats.TotalConnections.Add(1)
 
 	for {
-		resp := protocol.RESP{}
-		req, err := resp.Parse(r)
-		if err != nil {
-			resp.SendError(w, err.Error())
+		if timeout := s.cfg.Get().Timeout; timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(time.Duration(timeout) * time.Second))
+		} else {
+			conn.SetReadDeadline(time.Time{})
+		}
+		// checked after the deadline is set: shutdown sets closing before it
+		// expires the deadlines, so a read can not block past a shutdown
+		if s.closing.Load() {
+			resp.SendError(w, common.ErrShuttingDown)
 			return
 		}
-
-		res, err := resp.Process(req, &dbIndex, (*mem)[dbIndex])
+		args, err := resp.ReadArgs(r)
 		if err != nil {
-			resp.SendError(w, err.Error())
+			// malformed framing or a dead connection, there is no way to
+			// find where the next command starts
+			if s.closing.Load() {
+				err = common.ErrShuttingDown
+			}
+			resp.SendError(w, err)
 			return
 		}
 
+		// command errors are just replied, the connection keeps serving the
+		// commands pipelined after it
+		res := resp.Run(args, &dbIndex)
+		if res.Kind() == protocol.BlockedRes {
+			if res, err = s.block(&resp, conn, r, w, args, &dbIndex, res); err != nil {
+				resp.SendError(w, err)
+				return
+			}
+		}
 		resp.Send(w, res)
+	}
+}
+
+// block waits for data on the keys of a blocking command that found none
+// and runs it again on every wakeup, until it is served or its timeout
+// passes. an error means the connection must be closed: the server is
+// shutting down or the client went away, which is noticed by peeking at the
+// connection so that a dead client never pops an element.
+func (s *Server) block(resp *protocol.RESP, conn net.Conn, r *bufio.Reader, w *bufio.Writer, args []string, dbIndex *int, res *protocol.RESPRes) (*protocol.RESPRes, error) {
+	// the replies of the commands pipelined before this one can't wait
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	watcher := s.memory[*dbIndex].Watch(res.BlockedKeys())
+	defer watcher.Stop()
 
+	var timeout <-chan time.Time
+	if d := res.BlockTimeout(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
 	}
 
+	peeked := make(chan error, 1)
+	conn.SetReadDeadline(time.Time{})
+	go func() {
+		_, err := r.Peek(1)
+		peeked <- err
+	}()
+	gone := peeked
+	defer func() {
+		if gone != nil {
+			// stop the peek, the next read sets its own deadline again
+			conn.SetReadDeadline(time.Now())
+			<-peeked
+		}
+	}()
+
+	// data may have arrived between the first try and Watch
+	for res = resp.Run(args, dbIndex); res.Kind() == protocol.BlockedRes; res = resp.Run(args, dbIndex) {
+		select {
+		case <-watcher.C:
+		case <-timeout:
+			return res, nil
+		case <-s.quit:
+			return nil, common.ErrShuttingDown
+		case err := <-gone:
+			gone = nil
+			if s.closing.Load() {
+				return nil, common.ErrShuttingDown
+			}
+			if err != nil {
+				return nil, err
+			}
+			// more commands were pipelined, they wait for this one
+		}
+	}
+	return res, nil
 }
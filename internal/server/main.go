@@ -1,30 +1,31 @@
 package server
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/B-AJ-Amar/gokv/internal/common"
-	"github.com/B-AJ-Amar/gokv/internal/store"
+	"github.com/B-AJ-Amar/gokv/internal/config"
+	"github.com/B-AJ-Amar/gokv/internal/protocol"
 )
 
-func RunServer() {
-	memory := store.NewInMemoryStoreArray(common.MaxDBIndex)
-	port := 6379
-	fmt.Println("Launching server...")
-	fmt.Println("Listen on port")
-	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+// RunServer serves until SIGINT/SIGTERM or SHUTDOWN and returns once the
+// server is fully stopped
+func RunServer(cfg *config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s, err := NewServer(cfg)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
-	defer ln.Close()
-	for {
-		conn, err := ln.Accept()
-		if err != nil {
-			log.Fatal(err)
-		}
-		go HandleConnection(conn, &memory)
+	fmt.Println("Launching server...")
+	if err := s.Start(ctx); err != nil {
+		// nothing was served, release the AOF without saving
+		s.Shutdown(protocol.ShutdownNoSave)
+		return err
 	}
+	return s.Wait()
 }
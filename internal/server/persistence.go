@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/B-AJ-Amar/gokv/internal/logger"
+	"github.com/B-AJ-Amar/gokv/internal/persistence"
+	"github.com/B-AJ-Amar/gokv/internal/protocol"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// loadData restores the databases at startup. like redis, when the AOF is
+// enabled it is the source of truth and the snapshot is ignored.
+func loadData(memory []*store.InMemoryStore, saver *persistence.Snapshotter, aof *persistence.AOF) error {
+	if aof != nil {
+		n, err := replayAOF(memory, aof)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", aof.Path(), err)
+		}
+		logger.Noticef("Replayed %d commands from %s", n, aof.Path())
+		return nil
+	}
+	if saver == nil {
+		return nil
+	}
+
+	loaded, err := saver.Load(memory)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", saver.Path(), err)
+	}
+	logger.Noticef("Loaded %d keys from %s", loaded, saver.Path())
+	return nil
+}
+
+// replayAOF runs every logged command through the regular request path,
+// with a RESP that has no AOF attached so nothing gets appended again
+func replayAOF(memory []*store.InMemoryStore, aof *persistence.AOF) (int, error) {
+	resp := protocol.RESP{DBs: memory}
+	dbIndex := 0
+	return aof.Load(func(args []string) error {
+		_, err := resp.Exec(args, &dbIndex)
+		return err
+	})
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/config"
+	"github.com/B-AJ-Amar/gokv/internal/logger"
+	"github.com/B-AJ-Amar/gokv/internal/persistence"
+	"github.com/B-AJ-Amar/gokv/internal/protocol"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// how long Shutdown waits for in-flight commands before closing the
+// remaining connections the hard way
+const drainTimeout = 5 * time.Second
+
+// bounds of the pause after a failed Accept, it doubles on every failure
+const (
+	acceptMinDelay = 5 * time.Millisecond
+	acceptMaxDelay = time.Second
+)
+
+// Server holds everything the connection handlers share. the lifecycle is
+// NewServer -> Start -> Wait, and it stops when the context given to Start
+// is cancelled, on SHUTDOWN, or on Shutdown.
+type Server struct {
+	cfg     *config.Live
+	memory  []*store.InMemoryStore
+	saver   *persistence.Snapshotter
+	aof     *persistence.AOF
+	stats   *protocol.Stats
+	expirer *store.ActiveExpirer
+	clients atomic.Int64
+	lastID  atomic.Int64 // id of the last accepted client
+
+	ln      net.Listener
+	connsMu sync.Mutex
+	conns   map[net.Conn]struct{}
+	handler sync.WaitGroup
+
+	started  atomic.Bool
+	closing  atomic.Bool
+	quit     chan struct{} // closed with closing, wakes up blocked clients
+	stopOnce sync.Once
+	stopCh   chan int // shutdown mode requested by SHUTDOWN
+	done     chan struct{}
+	err      error // result of the shutdown, valid once done is closed
+}
+
+// NewServer validates cfg and loads the persisted data, it does not listen yet
+func NewServer(cfg *config.Config) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	logger.SetLevel(level)
+
+	s := &Server{
+		cfg:    config.NewLive(cfg),
+		memory: store.NewInMemoryStoreArray(cfg.Databases),
+		stats:  &protocol.Stats{},
+		conns:  make(map[net.Conn]struct{}),
+		stopCh: make(chan int, 1),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if cfg.DBFilename != "" {
+		s.saver = persistence.NewSnapshotter(cfg.SnapshotPath())
+	}
+	if cfg.AppendOnly {
+		aof, err := persistence.OpenAOF(cfg.AOFPath(), cfg.AppendFsync)
+		if err != nil {
+			return nil, err
+		}
+		s.aof = aof
+	}
+	s.cfg.OnChange(s.applyConfig)
+	if err := loadData(s.memory, s.saver, s.aof); err != nil {
+		if s.aof != nil {
+			s.aof.Close()
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start listens on the configured address and serves clients in the
+// background until ctx is cancelled or the server is shut down
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Get().Addr())
+	if err != nil {
+		return err
+	}
+	s.StartListener(ctx, ln)
+	return nil
+}
+
+// StartListener is Start on a listener created by the caller, the server
+// takes ownership of ln and closes it on shutdown
+func (s *Server) StartListener(ctx context.Context, ln net.Listener) {
+	s.ln = ln
+	s.expirer = store.StartActiveExpire(s.memory)
+	s.started.Store(true)
+	logger.Noticef("Listen on %s", ln.Addr())
+
+	go s.acceptLoop()
+	go func() {
+		mode := protocol.ShutdownDefault
+		select {
+		case <-ctx.Done():
+		case mode = <-s.stopCh:
+		}
+		s.shutdown(mode)
+	}()
+}
+
+// Addr is the address the server listens on, useful with port 0. it is nil
+// until the server is started
+func (s *Server) Addr() net.Addr {
+	if !s.started.Load() {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Local returns the client state of an in-process caller: it shares the
+// databases, persistence, limits and stats of the server without AUTH
+func (s *Server) Local() *protocol.RESP {
+	return &protocol.RESP{
+		DBs:        s.memory,
+		Saver:      s.saver,
+		AOF:        s.aof,
+		Config:     s.cfg,
+		Stats:      s.stats,
+		ClientAddr: "local",
+		Trusted:    true,
+	}
+}
+
+// Wait blocks until the server is fully stopped and returns the error of the
+// final save, if any
+func (s *Server) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// Shutdown stops the server as if a client sent SHUTDOWN with mode, and
+// waits for it to be done
+func (s *Server) Shutdown(mode int) error {
+	if !s.started.Load() {
+		// nothing is serving, just persist and release the files
+		s.shutdown(mode)
+		return s.Wait()
+	}
+	s.requestShutdown(mode)
+	return s.Wait()
+}
+
+func (s *Server) requestShutdown(mode int) {
+	select {
+	case s.stopCh <- mode:
+	default: // a shutdown is already on its way
+	}
+}
+
+// acceptLoop backs off like net/http when Accept fails, out of file
+// descriptors it would otherwise spin and flood the log
+func (s *Server) acceptLoop() {
+	var delay time.Duration
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if s.closing.Load() {
+				return
+			}
+			delay = min(max(2*delay, acceptMinDelay), acceptMaxDelay)
+			logger.Warningf("accept failed: %v, retrying in %s", err, delay)
+			select {
+			case <-time.After(delay):
+			case <-s.quit:
+				return
+			}
+			continue
+		}
+		delay = 0
+		if !s.trackConn(conn) {
+			conn.Close()
+			return
+		}
+		go s.HandleConnection(conn)
+	}
+}
+
+// trackConn registers a connection so shutdown can drain it, it returns
+// false once the server is closing
+func (s *Server) trackConn(conn net.Conn) bool {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	if s.closing.Load() {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.handler.Add(1)
+	return true
+}
+
+func (s *Server) untrackConn(conn net.Conn) {
+	s.connsMu.Lock()
+	delete(s.conns, conn)
+	s.connsMu.Unlock()
+	s.handler.Done()
+}
+
+// shutdown stops accepting, lets every connection finish the command it is
+// running, closes them, then persists and releases everything
+func (s *Server) shutdown(mode int) {
+	s.stopOnce.Do(func() {
+		logger.Noticef("Shutting down...")
+		s.connsMu.Lock()
+		s.closing.Store(true)
+		close(s.quit)
+		if s.ln != nil {
+			s.ln.Close()
+		}
+		// wake up the handlers blocked on a read or on BLPOP and friends, a
+		// handler in the middle of a command finishes it first and then sees
+		// closing
+		for conn := range s.conns {
+			conn.SetReadDeadline(time.Now())
+		}
+		s.connsMu.Unlock()
+
+		drained := make(chan struct{})
+		go func() {
+			s.handler.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-time.After(drainTimeout):
+			logger.Warningf("connections did not drain in %s, closing them", drainTimeout)
+			s.connsMu.Lock()
+			for conn := range s.conns {
+				conn.Close()
+			}
+			s.connsMu.Unlock()
+			<-drained
+		}
+
+		if s.expirer != nil {
+			s.expirer.Stop()
+		}
+		s.err = s.persistOnExit(mode)
+		logger.Noticef("GoKV is now ready to exit, bye bye...")
+		close(s.done)
+	})
+}
+
+func (s *Server) persistOnExit(mode int) error {
+	save := mode == protocol.ShutdownSave ||
+		(mode == protocol.ShutdownDefault && s.cfg.Get().SaveOnShutdown)
+	var err error
+	if save && s.saver != nil {
+		logger.Noticef("Saving the final snapshot before exiting")
+		err = s.saver.WaitSave(s.memory)
+	}
+	if s.aof != nil {
+		if aofErr := s.aof.Close(); aofErr != nil && err == nil {
+			err = aofErr
+		}
+	}
+	return err
+}
+
+// shutdownCommand backs the SHUTDOWN command. SAVE is done synchronously so
+// a failing save can abort the shutdown like redis does.
+func (s *Server) shutdownCommand(mode int) error {
+	if s.closing.Load() {
+		return common.ErrShuttingDown
+	}
+	if mode == protocol.ShutdownSave {
+		if s.saver == nil {
+			return common.ErrPersistenceDisabled
+		}
+		if err := s.saver.WaitSave(s.memory); err != nil {
+			return err
+		}
+		// already saved, don't do it twice on the way out
+		mode = protocol.ShutdownNoSave
+	}
+	s.requestShutdown(mode)
+	return nil
+}
+
+// applyConfig hot applies the settings changed by CONFIG SET that are not
+// simply read from the config on every use
+func (s *Server) applyConfig(old, cfg *config.Config) {
+	if old.LogLevel != cfg.LogLevel {
+		level, _ := logger.ParseLevel(cfg.LogLevel)
+		logger.SetLevel(level)
+	}
+	if old.AppendFsync != cfg.AppendFsync && s.aof != nil {
+		s.aof.SetPolicy(cfg.AppendFsync)
+	}
+}
@@ -0,0 +1,355 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/config"
+	"github.com/B-AJ-Amar/gokv/internal/protocol"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Port = 0
+	cfg.Bind = "127.0.0.1"
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "warning"
+	return cfg
+}
+
+func startServer(t *testing.T, ctx context.Context, cfg *config.Config) *Server {
+	t.Helper()
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return s
+}
+
+type testClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, s *Server) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *testClient) send(args ...string) {
+	msg := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		msg += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	c.conn.Write([]byte(msg))
+}
+
+func (c *testClient) readLine(t *testing.T) string {
+	t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+func TestServerStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := startServer(t, ctx, testConfig(t))
+
+	c := dial(t, s)
+	c.send("SET", "k", "v")
+	if got := c.readLine(t); got != "+OK" {
+		t.Fatalf("Expected +OK, got %q", got)
+	}
+
+	cancel()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+	// the idle client is told why it gets disconnected
+	if got := c.readLine(t); got != "-ERR Server is shutting down" {
+		t.Errorf("Expected a shutting down error, got %q", got)
+	}
+	if _, err := net.Dial("tcp", s.Addr().String()); err == nil {
+		t.Errorf("Expected the listener to be closed")
+	}
+}
+
+func TestShutdownCommandSave(t *testing.T) {
+	cfg := testConfig(t)
+	s := startServer(t, context.Background(), cfg)
+
+	c := dial(t, s)
+	c.send("SET", "k", "persisted")
+	c.readLine(t)
+	c.send("SHUTDOWN", "SAVE")
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Dir, cfg.DBFilename)); err != nil {
+		t.Fatalf("Expected a snapshot to be written: %v", err)
+	}
+
+	// a new server on the same dir gets the data back
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s2 := startServer(t, ctx, cfg)
+	c2 := dial(t, s2)
+	c2.send("GET", "k")
+	c2.readLine(t)
+	if got := c2.readLine(t); got != "persisted" {
+		t.Errorf("Expected the saved value, got %q", got)
+	}
+}
+
+func TestShutdownNoSave(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SaveOnShutdown = true
+	s := startServer(t, context.Background(), cfg)
+
+	c := dial(t, s)
+	c.send("SET", "k", "v")
+	c.readLine(t)
+	c.send("SHUTDOWN", "NOSAVE")
+	s.Wait()
+	if _, err := os.Stat(filepath.Join(cfg.Dir, cfg.DBFilename)); !os.IsNotExist(err) {
+		t.Errorf("Expected no snapshot with NOSAVE, got %v", err)
+	}
+}
+
+func TestShutdownDefaultFollowsConfig(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SaveOnShutdown = true
+	s := startServer(t, context.Background(), cfg)
+
+	if err := s.Shutdown(protocol.ShutdownDefault); err != nil {
+		t.Fatalf("Shutdown returned %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Dir, cfg.DBFilename)); err != nil {
+		t.Errorf("Expected save-on-shutdown to write a snapshot: %v", err)
+	}
+}
+
+func TestServerInlineCommands(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := startServer(t, ctx, testConfig(t))
+
+	c := dial(t, s)
+	c.conn.Write([]byte("PING\r\nSET greeting \"hello world\"\r\nGET greeting\r\n"))
+	for _, want := range []string{"+PONG", "+OK", "$11", "hello world"} {
+		if got := c.readLine(t); got != want {
+			t.Fatalf("Expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestServerPipelining(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := startServer(t, ctx, testConfig(t))
+
+	c := dial(t, s)
+	var pipeline strings.Builder
+	for i := 0; i < 1000; i++ {
+		pipeline.WriteString("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n")
+	}
+	// the start of a command whose end is not sent yet must not hold back
+	// the replies of the complete ones
+	pipeline.WriteString("*2\r\n$3\r\nGET\r\n")
+	c.conn.Write([]byte(pipeline.String()))
+	for i := 0; i < 1000; i++ {
+		if got := c.readLine(t); got != "+OK" {
+			t.Fatalf("Expected +OK for command %d, got %q", i, got)
+		}
+	}
+
+	c.conn.Write([]byte("$1\r\nk\r\n"))
+	if got := c.readLine(t); got != "$1" {
+		t.Fatalf("Expected $1, got %q", got)
+	}
+	if got := c.readLine(t); got != "v" {
+		t.Fatalf("Expected v, got %q", got)
+	}
+}
+
+func TestServerCommandErrorsKeepConnection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := startServer(t, ctx, testConfig(t))
+
+	c := dial(t, s)
+	// every error is replied in order and the commands after it still run
+	c.conn.Write([]byte("*1\r\n$3\r\nGET\r\n" +
+		"*3\r\n$6\r\nEXPIRE\r\n$1\r\nk\r\n$2\r\n-5\r\n" +
+		"*1\r\n$4\r\nNOPE\r\n" +
+		"*2\r\n$6\r\nSELECT\r\n$2\r\n99\r\n" +
+		"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"))
+	for _, want := range []string{
+		common.ErrWrongNumberArgs.Error(),
+		common.ErrInvalidExpireTime.Error(),
+		common.ErrUnknownCommand.Error(),
+		common.ErrDBIndexOutOfRange.Error(),
+	} {
+		if got := c.readLine(t); got != "-"+want {
+			t.Fatalf("Expected -%s, got %q", want, got)
+		}
+	}
+	if got := c.readLine(t); got != "+OK" {
+		t.Fatalf("Expected the SET after the errors to run, got %q", got)
+	}
+}
+
+func TestServerProtocolErrorClosesConnection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := startServer(t, ctx, testConfig(t))
+
+	c := dial(t, s)
+	c.conn.Write([]byte("*1\r\n$-5\r\nPING\r\n*1\r\n$4\r\nPING\r\n"))
+	if got := c.readLine(t); got != "-"+common.ErrInvalidBulkLen.Error() {
+		t.Fatalf("Expected a protocol error, got %q", got)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := c.r.ReadString('\n'); err != io.EOF {
+		t.Fatalf("Expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestServerBlockingPopWokenByPush(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := startServer(t, ctx, testConfig(t))
+
+	a := dial(t, s)
+	a.send("BLPOP", "q", "0")
+	// give the client time to block before the push
+	time.Sleep(50 * time.Millisecond)
+	b := dial(t, s)
+	b.send("RPUSH", "q", "v")
+	if got := b.readLine(t); got != ":1" {
+		t.Fatalf("Expected :1, got %q", got)
+	}
+	for _, want := range []string{"*2", "$1", "q", "$1", "v"} {
+		if got := a.readLine(t); got != want {
+			t.Fatalf("Expected %q, got %q", want, got)
+		}
+	}
+	b.send("LLEN", "q")
+	if got := b.readLine(t); got != ":0" {
+		t.Errorf("Expected the element to be popped, got %q", got)
+	}
+}
+
+func TestServerBlockingZPopWokenByZAdd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := startServer(t, ctx, testConfig(t))
+
+	a := dial(t, s)
+	a.send("BZPOPMIN", "z", "0")
+	time.Sleep(50 * time.Millisecond)
+	b := dial(t, s)
+	b.send("ZADD", "z", "2", "b", "1", "a")
+	if got := b.readLine(t); got != ":2" {
+		t.Fatalf("Expected :2, got %q", got)
+	}
+	for _, want := range []string{"*3", "$1", "z", "$1", "a", "$1", "1"} {
+		if got := a.readLine(t); got != want {
+			t.Fatalf("Expected %q, got %q", want, got)
+		}
+	}
+	b.send("ZCARD", "z")
+	if got := b.readLine(t); got != ":1" {
+		t.Errorf("Expected one member left, got %q", got)
+	}
+}
+
+func TestServerBlockingPopTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := startServer(t, ctx, testConfig(t))
+
+	c := dial(t, s)
+	c.send("BRPOP", "q", "0.05")
+	if got := c.readLine(t); got != "*-1" {
+		t.Fatalf("Expected a null array on timeout, got %q", got)
+	}
+	c.send("PING")
+	if got := c.readLine(t); got != "+PONG" {
+		t.Errorf("Expected the connection to keep serving, got %q", got)
+	}
+}
+
+func TestServerBlockedClientOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := startServer(t, ctx, testConfig(t))
+
+	c := dial(t, s)
+	c.send("BLPOP", "q", "0")
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+	if got := c.readLine(t); got != "-ERR Server is shutting down" {
+		t.Errorf("Expected a shutting down error, got %q", got)
+	}
+}
+
+// failingListener fails every Accept like a process out of file descriptors
+type failingListener struct {
+	net.Listener
+	accepts atomic.Int64
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	l.accepts.Add(1)
+	return nil, syscall.EMFILE
+}
+
+func TestServerAcceptBackoff(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	failing := &failingListener{Listener: ln}
+	s, err := NewServer(testConfig(t))
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.StartListener(ctx, failing)
+
+	// 5ms, 10ms, 20ms, 40ms... a handful of retries, not a busy loop
+	time.Sleep(100 * time.Millisecond)
+	if n := failing.accepts.Load(); n > 6 {
+		t.Errorf("Expected Accept to back off, called %d times", n)
+	}
+	cancel()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+}
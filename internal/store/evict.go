@@ -0,0 +1,166 @@
+package store
+
+import (
+	"container/heap"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// maxmemory-policy values
+const (
+	EvictNoEviction     = "noeviction"      // refuse writes once the limit is reached
+	EvictAllKeysRandom  = "allkeys-random"  // evict any key
+	EvictVolatileRandom = "volatile-random" // evict keys with a ttl
+	EvictVolatileTTL    = "volatile-ttl"    // evict the keys closest to their expire time first
+)
+
+func ValidEvictionPolicy(policy string) bool {
+	switch policy {
+	case EvictNoEviction, EvictAllKeysRandom, EvictVolatileRandom, EvictVolatileTTL:
+		return true
+	}
+	return false
+}
+
+var evictedKeys atomic.Uint64
+
+func EvictedKeys() uint64 {
+	return evictedKeys.Load()
+}
+
+func ResetEvictedKeys() {
+	evictedKeys.Store(0)
+}
+
+// evictRandomTries is how many random shards the random policies probe for
+// a key before walking every shard in order
+const evictRandomTries = 16
+
+// Evict removes keys according to policy until the databases use at most
+// limit bytes. it returns false when the limit could not be reached, the
+// caller must then refuse the write.
+func Evict(stores []*InMemoryStore, policy string, limit int64) bool {
+	if policy == EvictNoEviction || len(stores) == 0 {
+		return TotalUsedMemory(stores) <= limit
+	}
+
+	if policy == EvictVolatileTTL {
+		return evictNearestTTL(stores, limit)
+	}
+	for TotalUsedMemory(stores) > limit {
+		if !evictRandom(stores, policy) {
+			return false
+		}
+	}
+	return true
+}
+
+// evictNearestTTL evicts the keys of the whole keyspace that expire first
+// until the databases fit in limit, like the eviction pool of redis does with
+// its samples. the heads of the expiry heaps are read once and merged: after
+// an eviction only the shard it came from is looked at again, not every
+// shard for every key.
+func evictNearestTTL(stores []*InMemoryStore, limit int64) bool {
+	heads := &shardHeads{}
+	for _, s := range stores {
+		for _, sh := range s.shards {
+			if exp, ok := sh.nearestTTL(); ok {
+				*heads = append(*heads, shardHead{sh, exp})
+			}
+		}
+	}
+	heap.Init(heads)
+
+	for TotalUsedMemory(stores) > limit {
+		if heads.Len() == 0 {
+			return false
+		}
+		// the head may have changed since, it still is the nearest of its shard
+		sh := (*heads)[0].sh
+		sh.evict(EvictVolatileTTL)
+		if exp, ok := sh.nearestTTL(); ok {
+			(*heads)[0].exp = exp
+			heap.Fix(heads, 0)
+		} else {
+			heap.Pop(heads)
+		}
+	}
+	return true
+}
+
+func (sh *shard) nearestTTL() (int64, bool) {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	e, ok := sh.expires.peek()
+	return e.exp, ok
+}
+
+type shardHead struct {
+	sh  *shard
+	exp int64
+}
+
+// shardHeads is a min-heap of shards by the expire time of their head
+type shardHeads []shardHead
+
+func (h shardHeads) Len() int           { return len(h) }
+func (h shardHeads) Less(i, j int) bool { return h[i].exp < h[j].exp }
+func (h shardHeads) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *shardHeads) Push(x any)        { *h = append(*h, x.(shardHead)) }
+
+func (h *shardHeads) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// evictRandom evicts a key from a random shard, walking every shard before
+// giving up so a sparse keyspace is not mistaken for an empty one
+func evictRandom(stores []*InMemoryStore, policy string) bool {
+	for range evictRandomTries {
+		s := stores[rand.IntN(len(stores))]
+		if s.shards[rand.IntN(len(s.shards))].evict(policy) {
+			return true
+		}
+	}
+	for _, s := range stores {
+		for _, sh := range s.shards {
+			if sh.evict(policy) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evict removes the candidate of policy from the shard, if it has one
+func (sh *shard) evict(policy string) bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	key, ok := sh.evictionCandidate(policy)
+	if ok {
+		sh.remove(key)
+		evictedKeys.Add(1)
+	}
+	return ok
+}
+
+func (sh *shard) evictionCandidate(policy string) (string, bool) {
+	switch policy {
+	case EvictAllKeysRandom:
+		// map iteration starts at a random position
+		for key := range sh.data {
+			return key, true
+		}
+	case EvictVolatileRandom:
+		if n := sh.expires.Len(); n > 0 {
+			return sh.expires.entries[rand.IntN(n)].key, true
+		}
+	case EvictVolatileTTL:
+		if e, ok := sh.expires.peek(); ok {
+			return e.key, true
+		}
+	}
+	return "", false
+}
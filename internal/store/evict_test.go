@@ -0,0 +1,71 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestEvictPolicies(t *testing.T) {
+	tests := []struct {
+		policy       string
+		wantOK       bool
+		keepsPlain   bool
+		evictsNearer bool
+	}{
+		{EvictNoEviction, false, true, false},
+		{EvictAllKeysRandom, true, false, false},
+		{EvictVolatileRandom, true, true, false},
+		{EvictVolatileTTL, true, true, true},
+	}
+	for _, tt := range tests {
+		stores := NewInMemoryStoreArray(2)
+		for i := 0; i < 100; i++ {
+			stores[0].Set("plain:"+strconv.Itoa(i), []byte("v"))
+			stores[1].Setx("ttl:"+strconv.Itoa(i), []byte("v"), SetArgs{ExpType: ExpireEX, ExpVal: 1000 + i})
+		}
+		plainSize := stores[0].UsedMemory()
+		limit := plainSize + stores[1].UsedMemory()/2
+
+		ok := Evict(stores, tt.policy, limit)
+		if ok != tt.wantOK {
+			t.Errorf("%s: expected %v, got %v", tt.policy, tt.wantOK, ok)
+			continue
+		}
+		if ok && TotalUsedMemory(stores) > limit {
+			t.Errorf("%s: still over the limit", tt.policy)
+		}
+		if tt.keepsPlain && stores[0].UsedMemory() != plainSize {
+			t.Errorf("%s: keys without a ttl should not be evicted", tt.policy)
+		}
+		if tt.evictsNearer {
+			if v, _ := stores[1].Get("ttl:0"); v != nil {
+				t.Errorf("%s: expected the nearest ttl key to be evicted first", tt.policy)
+			}
+			if v, _ := stores[1].Get("ttl:99"); v == nil {
+				t.Errorf("%s: expected the farthest ttl key to survive", tt.policy)
+			}
+		}
+	}
+}
+
+func TestEvictNothingLeft(t *testing.T) {
+	stores := NewInMemoryStoreArray(1)
+	stores[0].Set("plain", []byte("v"))
+	if Evict(stores, EvictVolatileTTL, 0) {
+		t.Errorf("Expected eviction to fail without volatile keys")
+	}
+}
+
+// a single evictable key must be found whatever shard it landed in
+func TestEvictSparseKeyspace(t *testing.T) {
+	for _, policy := range []string{EvictAllKeysRandom, EvictVolatileRandom, EvictVolatileTTL} {
+		stores := NewInMemoryStoreArray(16)
+		stores[9].Setx("only", []byte("v"), SetArgs{ExpType: ExpireEX, ExpVal: 100})
+		if !Evict(stores, policy, 0) {
+			t.Errorf("%s: expected the only key to be evicted", policy)
+		}
+		if stores[9].UsedMemory() != 0 {
+			t.Errorf("%s: expected the key to be gone", policy)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// active expiration, modeled after the redis "activeExpireCycle":
+// every ActiveExpireInterval we walk every database and delete the keys that
+// are already due. instead of sampling random keys like redis does, each
+// shard pops due keys from its expiry index (see ttlheap.go) in batches of
+// ActiveExpireKeysPerLoop. a full batch means there may be more, so we keep
+// going on the same shard until the time budget of the cycle is used up.
+
+const (
+	ActiveExpireInterval      = 100 * time.Millisecond
+	ActiveExpireKeysPerLoop   = 20
+	ActiveExpireCycleBudget   = 25 * time.Millisecond // 25% of the interval
+	activeExpireMaxLoopsShard = 16
+)
+
+type expireStats struct {
+	expiredKeys        atomic.Uint64 // lazy + active expirations
+	expireCycles       atomic.Uint64
+	expireCyclesCapped atomic.Uint64 // cycles that hit the time budget
+	expireCycleTimeUs  atomic.Uint64
+}
+
+var stats expireStats
+
+// ExpireStats is a point in time copy of the expiration counters
+type ExpireStats struct {
+	ExpiredKeys        uint64
+	ExpireCycles       uint64
+	ExpireCyclesCapped uint64
+	ExpireCycleTimeUs  uint64
+}
+
+func GetExpireStats() ExpireStats {
+	return ExpireStats{
+		ExpiredKeys:        stats.expiredKeys.Load(),
+		ExpireCycles:       stats.expireCycles.Load(),
+		ExpireCyclesCapped: stats.expireCyclesCapped.Load(),
+		ExpireCycleTimeUs:  stats.expireCycleTimeUs.Load(),
+	}
+}
+
+func ResetExpireStats() {
+	stats.expiredKeys.Store(0)
+	stats.expireCycles.Store(0)
+	stats.expireCyclesCapped.Store(0)
+	stats.expireCycleTimeUs.Store(0)
+}
+
+type ActiveExpirer struct {
+	stores []*InMemoryStore
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// StartActiveExpire launches the background expire loop over all databases,
+// call Stop on the returned value to end it
+func StartActiveExpire(stores []*InMemoryStore) *ActiveExpirer {
+	e := &ActiveExpirer{
+		stores: stores,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *ActiveExpirer) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(ActiveExpireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			ActiveExpireCycle(e.stores, ActiveExpireCycleBudget)
+		}
+	}
+}
+
+func (e *ActiveExpirer) Stop() {
+	e.once.Do(func() {
+		close(e.stop)
+	})
+	<-e.done
+}
+
+// ActiveExpireCycle runs a single expire cycle and returns the number of
+// deleted keys
+func ActiveExpireCycle(stores []*InMemoryStore, budget time.Duration) int {
+	start := time.Now()
+	deadline := start.Add(budget)
+	expired := 0
+	capped := false
+
+	for _, s := range stores {
+		for _, sh := range s.shards {
+			n, ok := sh.activeExpire(deadline)
+			expired += n
+			if !ok {
+				capped = true
+				break
+			}
+		}
+		if capped {
+			break
+		}
+	}
+
+	stats.expireCycles.Add(1)
+	stats.expireCycleTimeUs.Add(uint64(time.Since(start).Microseconds()))
+	if capped {
+		stats.expireCyclesCapped.Add(1)
+	}
+	return expired
+}
+
+// activeExpire pops due keys off the shard index, it returns false when the
+// deadline was reached while the shard still had due keys
+func (sh *shard) activeExpire(deadline time.Time) (int, bool) {
+	expired := 0
+	for loop := 0; loop < activeExpireMaxLoopsShard; loop++ {
+		// the lock is released between batches so clients are not starved
+		sh.mu.Lock()
+		found := sh.expireDue(time.Now().UnixMilli(), ActiveExpireKeysPerLoop)
+		sh.mu.Unlock()
+		expired += found
+
+		if found < ActiveExpireKeysPerLoop {
+			return expired, true
+		}
+		if time.Now().After(deadline) {
+			return expired, false
+		}
+	}
+	return expired, true
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestActiveExpireCycleRemovesUnreadKeys(t *testing.T) {
+	ResetExpireStats()
+	stores := NewInMemoryStoreArray(2)
+	for i := 0; i < 200; i++ {
+		stores[i%2].Setx("tmp:"+strconv.Itoa(i), []byte("v"), SetArgs{ExpType: ExpirePX, ExpVal: 1})
+	}
+	stores[0].Set("keep", []byte("v"))
+	stores[1].Setx("later", []byte("v"), SetArgs{ExpType: ExpireEX, ExpVal: 100})
+	time.Sleep(5 * time.Millisecond)
+
+	// a generous budget lets a single cycle clean everything
+	expired := ActiveExpireCycle(stores, time.Second)
+	if expired != 200 {
+		t.Errorf("Expected 200 expired keys, got %d", expired)
+	}
+	for i, s := range stores {
+		for _, sh := range s.shards {
+			for key := range sh.data {
+				if key != "keep" && key != "later" {
+					t.Errorf("db %d: key %q should have been expired", i, key)
+				}
+			}
+		}
+	}
+
+	st := GetExpireStats()
+	if st.ExpiredKeys != 200 {
+		t.Errorf("Expected expired_keys 200, got %d", st.ExpiredKeys)
+	}
+	if st.ExpireCycles != 1 {
+		t.Errorf("Expected 1 expire cycle, got %d", st.ExpireCycles)
+	}
+}
+
+func TestActiveExpirerBackground(t *testing.T) {
+	stores := NewInMemoryStoreArray(1)
+	stores[0].Setx("tmp", []byte("v"), SetArgs{ExpType: ExpirePX, ExpVal: 10})
+
+	e := StartActiveExpire(stores)
+	defer e.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		sh := stores[0].getShard("tmp")
+		sh.mu.RLock()
+		_, ok := sh.data["tmp"]
+		sh.mu.RUnlock()
+		if !ok {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("Expected tmp to be removed by the background cycle")
+}
+
+func TestPersistDropsKeyFromExpires(t *testing.T) {
+	mem := NewInMemoryStore()
+	mem.Setx("k", []byte("v"), SetArgs{ExpType: ExpireEX, ExpVal: 10})
+	sh := mem.getShard("k")
+	if _, ok := sh.expires.pos["k"]; !ok {
+		t.Fatalf("Expected k to be tracked in expires")
+	}
+	mem.Persist("k")
+	if _, ok := sh.expires.pos["k"]; ok {
+		t.Errorf("Expected k to be untracked after PERSIST")
+	}
+	mem.Expire("k", 10)
+	mem.Set("k", []byte("v2"))
+	if _, ok := sh.expires.pos["k"]; ok {
+		t.Errorf("Expected plain SET to clear the ttl")
+	}
+}
+
+// a key past its ttl is gone even before the active cycle collects it
+func TestExpiredKeyIsMissing(t *testing.T) {
+	mem := NewInMemoryStore()
+	dead := SetArgs{ExpType: ExpirePXAT, ExpVal: int(time.Now().UnixMilli() - 1)}
+	mem.Setx("k", []byte("v"), dead)
+	if n := mem.Exists([]string{"k"}); n != 0 {
+		t.Errorf("Expected EXISTS to skip an expired key, got %d", n)
+	}
+	if n, _ := mem.Persist("k"); n != 0 {
+		t.Errorf("Expected PERSIST not to revive an expired key")
+	}
+	if n, _ := mem.Expire("k", 100); n != 0 {
+		t.Errorf("Expected EXPIRE not to revive an expired key")
+	}
+	if n, _ := mem.ExpireAt("k", time.Now().Add(time.Hour).UnixMilli()); n != 0 {
+		t.Errorf("Expected EXPIREAT not to revive an expired key")
+	}
+	if v, _ := mem.Get("k"); v != nil {
+		t.Errorf("Expected the key to stay missing, got %q", v)
+	}
+	mem.Setx("k", []byte("v"), dead)
+	if n := mem.Del([]string{"k"}); n != 0 {
+		t.Errorf("Expected DEL not to count an expired key, got %d", n)
+	}
+	mem.Set("plain", []byte("v"))
+	if n, _ := mem.Persist("plain"); n != 0 {
+		t.Errorf("Expected PERSIST on a key without ttl to return 0")
+	}
+}
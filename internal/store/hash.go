@@ -0,0 +1,297 @@
+package store
+
+import (
+	"maps"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+// rough overhead of one element of an object: map slot or node, headers
+const elementOverhead = 16
+
+// hash maps fields to values. values are replaced, never changed in place,
+// so they can be handed out without a copy.
+type hash struct {
+	fields map[string][]byte
+	size   int64
+	scan   scanOrder
+}
+
+func newHash() *hash {
+	return &hash{fields: make(map[string][]byte)}
+}
+
+func (h *hash) Type() ValueType { return TypeHash }
+func (h *hash) Len() int        { return len(h.fields) }
+func (h *hash) bytes() int64    { return h.size }
+
+func (h *hash) items() [][]byte {
+	items := make([][]byte, 0, 2*len(h.fields))
+	for f, v := range h.fields {
+		items = append(items, []byte(f), v)
+	}
+	return items
+}
+
+// set returns true when field is new
+func (h *hash) set(field string, value []byte) bool {
+	old, ok := h.fields[field]
+	if ok {
+		h.size -= int64(len(old))
+	} else {
+		h.size += int64(len(field) + elementOverhead)
+		h.scan.reset()
+	}
+	h.size += int64(len(value))
+	h.fields[field] = value
+	return !ok
+}
+
+func (h *hash) del(field string) bool {
+	old, ok := h.fields[field]
+	if ok {
+		h.size -= int64(len(field) + len(old) + elementOverhead)
+		delete(h.fields, field)
+		h.scan.reset()
+	}
+	return ok
+}
+
+// readHash runs fn on the hash at key under the read lock, fn is not called
+// when the key is missing
+func (s *InMemoryStore) readHash(key string, fn func(h *hash)) error {
+	sh := s.getShard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	record, ok, err := sh.peek(key, TypeHash, time.Now().UnixMilli())
+	if ok {
+		fn(record.obj.(*hash))
+	}
+	return err
+}
+
+// writeHash runs fn on the hash at key under the write lock, creating it
+// when create is set. the record is updated afterwards, and removed if fn
+// left the hash empty.
+func (s *InMemoryStore) writeHash(key string, create bool, fn func(h *hash) error) error {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	record, ok, err := sh.lookup(key, TypeHash, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if !create {
+			return nil
+		}
+		record = KVRecord{obj: newHash(), exp: -1}
+	}
+	err = fn(record.obj.(*hash))
+	if ok || record.obj.Len() > 0 {
+		sh.update(key, record)
+	}
+	return err
+}
+
+// HSet sets fields to values and returns how many fields are new
+func (s *InMemoryStore) HSet(key string, fields []string, values [][]byte) (int, error) {
+	added := 0
+	err := s.writeHash(key, true, func(h *hash) error {
+		for i, f := range fields {
+			if h.set(f, values[i]) {
+				added++
+			}
+		}
+		return nil
+	})
+	return added, err
+}
+
+// HSetNX sets field only when it does not exist, it returns 1 when it did
+func (s *InMemoryStore) HSetNX(key, field string, value []byte) (int, error) {
+	added := 0
+	err := s.writeHash(key, true, func(h *hash) error {
+		if _, ok := h.fields[field]; !ok {
+			h.set(field, value)
+			added = 1
+		}
+		return nil
+	})
+	return added, err
+}
+
+// HGet returns the value of field, nil when it or the key is missing
+func (s *InMemoryStore) HGet(key, field string) ([]byte, error) {
+	var value []byte
+	err := s.readHash(key, func(h *hash) {
+		value = h.fields[field]
+	})
+	return value, err
+}
+
+func (s *InMemoryStore) HMGet(key string, fields []string) ([][]byte, error) {
+	values := make([][]byte, len(fields))
+	err := s.readHash(key, func(h *hash) {
+		for i, f := range fields {
+			values[i] = h.fields[f]
+		}
+	})
+	return values, err
+}
+
+// HGetAll returns the fields and values of the hash alternated
+func (s *InMemoryStore) HGetAll(key string) ([][]byte, error) {
+	var items [][]byte
+	err := s.readHash(key, func(h *hash) {
+		items = h.items()
+	})
+	return items, err
+}
+
+// HDel removes fields and returns how many existed, the key is removed
+// with its last field
+func (s *InMemoryStore) HDel(key string, fields []string) (int, error) {
+	deleted := 0
+	err := s.writeHash(key, false, func(h *hash) error {
+		for _, f := range fields {
+			if h.del(f) {
+				deleted++
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func (s *InMemoryStore) HExists(key, field string) (int, error) {
+	exists := 0
+	err := s.readHash(key, func(h *hash) {
+		if _, ok := h.fields[field]; ok {
+			exists = 1
+		}
+	})
+	return exists, err
+}
+
+func (s *InMemoryStore) HLen(key string) (int, error) {
+	n := 0
+	err := s.readHash(key, func(h *hash) {
+		n = h.Len()
+	})
+	return n, err
+}
+
+func (s *InMemoryStore) HKeys(key string) ([]string, error) {
+	fields := []string{}
+	err := s.readHash(key, func(h *hash) {
+		fields = make([]string, 0, h.Len())
+		for f := range h.fields {
+			fields = append(fields, f)
+		}
+	})
+	return fields, err
+}
+
+func (s *InMemoryStore) HVals(key string) ([][]byte, error) {
+	values := [][]byte{}
+	err := s.readHash(key, func(h *hash) {
+		values = make([][]byte, 0, h.Len())
+		for _, v := range h.fields {
+			values = append(values, v)
+		}
+	})
+	return values, err
+}
+
+func (s *InMemoryStore) HStrLen(key, field string) (int, error) {
+	n := 0
+	err := s.readHash(key, func(h *hash) {
+		n = len(h.fields[field])
+	})
+	return n, err
+}
+
+// HIncrBy adds by to the integer in field, a missing field counts as 0
+func (s *InMemoryStore) HIncrBy(key, field string, by int64) (int64, error) {
+	var n int64
+	err := s.writeHash(key, true, func(h *hash) error {
+		if value, ok := h.fields[field]; ok {
+			var err error
+			if n, err = parseInt(value); err != nil {
+				return common.ErrHashNotInt
+			}
+		}
+		if (by > 0 && n > math.MaxInt64-by) || (by < 0 && n < math.MinInt64-by) {
+			return common.ErrIncrOverflow
+		}
+		n += by
+		h.set(field, strconv.AppendInt(nil, n, 10))
+		return nil
+	})
+	return n, err
+}
+
+// HIncrByFloat adds by to the number in field, a missing field counts as 0
+func (s *InMemoryStore) HIncrByFloat(key, field string, by float64) ([]byte, error) {
+	var result []byte
+	err := s.writeHash(key, true, func(h *hash) error {
+		f := 0.0
+		if value, ok := h.fields[field]; ok {
+			var err error
+			if f, err = ParseFloat(value); err != nil {
+				return common.ErrHashNotFloat
+			}
+		}
+		f += by
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return common.ErrIncrNaN
+		}
+		result = FormatFloat(f)
+		h.set(field, result)
+		return nil
+	})
+	return result, err
+}
+
+// HRandField returns random fields with their values. a positive count asks
+// for that many distinct fields at most, a negative one for exactly -count
+// fields that may repeat, like redis.
+func (s *InMemoryStore) HRandField(key string, count int) ([]string, [][]byte, error) {
+	var fields []string
+	var values [][]byte
+	err := s.readHash(key, func(h *hash) {
+		pick := func() string { return randomKey(h.fields) }
+		if count < 0 {
+			for range -count {
+				fields = append(fields, pick())
+			}
+		} else {
+			fields = randomDistinct(h.Len(), count, pick, maps.Keys(h.fields))
+		}
+		for _, f := range fields {
+			values = append(values, h.fields[f])
+		}
+	})
+	return fields, values, err
+}
+
+// HScan returns a batch of fields and values alternated, and the cursor of
+// the next batch, 0 once the scan is complete. see scanNames.
+func (s *InMemoryStore) HScan(key string, cursor uint64, match string, count int) (uint64, [][]byte, error) {
+	next := uint64(0)
+	items := [][]byte{}
+	err := s.readHash(key, func(h *hash) {
+		var fields []string
+		next, fields = scanNames(&h.scan, maps.Keys(h.fields), cursor, count, match)
+		for _, f := range fields {
+			items = append(items, []byte(f), h.fields[f])
+		}
+	})
+	return next, items, err
+}
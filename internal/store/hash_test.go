@@ -0,0 +1,194 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+func TestHashSetGetDel(t *testing.T) {
+	s := NewInMemoryStore()
+	if n, err := s.HSet("h", []string{"a", "b", "a"}, [][]byte{[]byte("1"), []byte("2"), []byte("3")}); n != 2 || err != nil {
+		t.Fatalf("Expected 2 new fields, got %d, %v", n, err)
+	}
+	if v, _ := s.HGet("h", "a"); string(v) != "3" {
+		t.Errorf("Expected the last value of a repeated field, got %q", v)
+	}
+	if n, _ := s.HLen("h"); n != 2 {
+		t.Errorf("Expected 2 fields, got %d", n)
+	}
+	if n, _ := s.HDel("h", []string{"a", "x"}); n != 1 {
+		t.Errorf("Expected 1 deleted field, got %d", n)
+	}
+	if n, _ := s.HDel("h", []string{"b"}); n != 1 || s.Exists([]string{"h"}) != 0 {
+		t.Errorf("Expected the hash to go away with its last field")
+	}
+	if s.UsedMemory() != 0 {
+		t.Errorf("Expected every byte to be released, %d left", s.UsedMemory())
+	}
+}
+
+func TestHashMemoryAccounting(t *testing.T) {
+	s := NewInMemoryStore()
+	s.HSet("h", []string{"f"}, [][]byte{[]byte("small")})
+	before := s.UsedMemory()
+	s.HSet("h", []string{"f"}, [][]byte{make([]byte, 1000)})
+	if grown := s.UsedMemory() - before; grown != 1000-5 {
+		t.Errorf("Expected an in place update to be accounted, grew by %d", grown)
+	}
+	s.Del([]string{"h"})
+	if s.UsedMemory() != 0 {
+		t.Errorf("Expected every byte to be released, %d left", s.UsedMemory())
+	}
+}
+
+func TestHashKeepsTTL(t *testing.T) {
+	s := NewInMemoryStore()
+	s.HSet("h", []string{"f"}, [][]byte{[]byte("v")})
+	s.Expire("h", 100)
+	s.HIncrBy("h", "n", 1)
+	if s.PExpireTime("h") == -1 {
+		t.Errorf("Expected writes to keep the ttl of the hash")
+	}
+
+	s.ExpireAt("h", time.Now().UnixMilli()-1)
+	if n, _ := s.HSet("h", []string{"f"}, [][]byte{[]byte("v")}); n != 1 || s.PExpireTime("h") != -1 {
+		t.Errorf("Expected an expired hash to start over")
+	}
+}
+
+func TestHashWrongType(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Set("str", []byte("v"))
+	s.HSet("h", []string{"f"}, [][]byte{[]byte("v")})
+
+	checks := map[string]error{}
+	_, checks["HSet"] = s.HSet("str", []string{"f"}, [][]byte{[]byte("v")})
+	_, checks["HGet"] = s.HGet("str", "f")
+	_, checks["HGetAll"] = s.HGetAll("str")
+	_, _, checks["HScan"] = s.HScan("str", 0, "", 10)
+	_, checks["Get"] = s.Get("h")
+	_, checks["Append"] = s.Append("h", []byte("x"))
+	_, checks["Incrby"] = s.Incrby("h", 1)
+	_, checks["GetDel"] = s.GetDel("h")
+	for name, err := range checks {
+		if !errors.Is(err, common.ErrWrongType) {
+			t.Errorf("%s: expected WRONGTYPE, got %v", name, err)
+		}
+	}
+	if v := s.MGet([]string{"h"}); v[0] != nil {
+		t.Errorf("Expected MGET to skip a hash, got %q", v[0])
+	}
+}
+
+func TestHRandField(t *testing.T) {
+	s := NewInMemoryStore()
+	s.HSet("h", []string{"a", "b", "c"}, [][]byte{[]byte("1"), []byte("2"), []byte("3")})
+
+	fields, values, _ := s.HRandField("h", 10)
+	slices.Sort(fields)
+	if !slices.Equal(fields, []string{"a", "b", "c"}) || len(values) != 3 {
+		t.Errorf("Expected every field once, got %q", fields)
+	}
+	if fields, _, _ := s.HRandField("h", 2); len(fields) != 2 || fields[0] == fields[1] {
+		t.Errorf("Expected 2 distinct fields, got %q", fields)
+	}
+	if fields, _, _ := s.HRandField("h", -7); len(fields) != 7 {
+		t.Errorf("Expected 7 fields with repeats, got %q", fields)
+	}
+	if fields, _, _ := s.HRandField("missing", -3); len(fields) != 0 {
+		t.Errorf("Expected nothing from a missing key, got %q", fields)
+	}
+
+	// a count small next to the hash is drawn field by field
+	for i := range 100 {
+		s.HSet("big", []string{fmt.Sprint("f", i)}, [][]byte{[]byte(fmt.Sprint("v", i))})
+	}
+	fields, values, _ = s.HRandField("big", 5)
+	seen := map[string]bool{}
+	for i, f := range fields {
+		if seen[f] || string(values[i]) != "v"+strings.TrimPrefix(f, "f") {
+			t.Errorf("Expected 5 distinct fields with their values, got %q %q", fields, values)
+		}
+		seen[f] = true
+	}
+	if len(fields) != 5 {
+		t.Errorf("Expected 5 fields, got %q", fields)
+	}
+}
+
+func TestHScanReturnsEveryField(t *testing.T) {
+	s := NewInMemoryStore()
+	for i := 0; i < 100; i++ {
+		s.HSet("h", []string{fmt.Sprint("f", i)}, [][]byte{[]byte("v")})
+	}
+
+	seen := map[string]int{}
+	cursor := uint64(0)
+	for calls := 0; ; calls++ {
+		next, items, err := s.HScan("h", cursor, "", 7)
+		if err != nil || calls > 100 {
+			t.Fatalf("HScan did not complete: %v", err)
+		}
+		for i := 0; i < len(items); i += 2 {
+			seen[string(items[i])]++
+		}
+		// fields removed or added during the scan don't disturb the rest
+		if calls == 3 {
+			s.HDel("h", []string{"f0"})
+			s.HSet("h", []string{"new"}, [][]byte{[]byte("v")})
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	for i := 1; i < 100; i++ {
+		if f := fmt.Sprint("f", i); seen[f] != 1 {
+			t.Errorf("Expected %s once, seen %d times", f, seen[f])
+		}
+	}
+
+	_, items, _ := s.HScan("h", 0, "f9?", 1000)
+	if len(items) != 20 {
+		t.Errorf("Expected f90 to f99 with their values, got %d items", len(items))
+	}
+	// the order cached by the previous scans follows the writes
+	s.HDel("h", []string{"f90"})
+	s.HSet("h", []string{"f9x"}, [][]byte{[]byte("v")})
+	if _, items, _ := s.HScan("h", 0, "f9?", 1000); len(items) != 20 || slices.ContainsFunc(items, func(f []byte) bool { return string(f) == "f90" }) {
+		t.Errorf("Expected f91 to f9x after the writes, got %q", items)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"h*llo", "heeello", true},
+		{"h?llo", "hallo", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hello", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"*a*b", "xaybzb", true},
+		{"a*", "b", false},
+		{"a*?b", "ab", false},
+		{"*[xy]", "aay", true},
+		{"*a*a*a*a*a*a*a*a*a*a*b", strings.Repeat("a", 200), false},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q): expected %v", tt.pattern, tt.s, tt.want)
+		}
+	}
+}
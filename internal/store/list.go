@@ -0,0 +1,572 @@
+package store
+
+import (
+	"bytes"
+	"slices"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+// elements per node of a list, a full node is split in two on insert
+const listNodeSize = 128
+
+// list is a quicklist: a doubly linked list of nodes holding up to
+// listNodeSize elements each, pushes and pops at both ends are O(1) and
+// walking to an index skips whole nodes. elements are never changed in
+// place, like hash values they can be handed out without a copy.
+type list struct {
+	head, tail *listNode
+	n          int
+	size       int64
+}
+
+type listNode struct {
+	items      [][]byte
+	prev, next *listNode
+}
+
+func newList() *list {
+	return &list{}
+}
+
+func (l *list) Type() ValueType { return TypeList }
+func (l *list) Len() int        { return l.n }
+func (l *list) bytes() int64    { return l.size }
+
+func (l *list) items() [][]byte {
+	return l.slice(0, l.n-1)
+}
+
+func elementSize(v []byte) int64 {
+	return int64(len(v) + elementOverhead)
+}
+
+func (l *list) pushFront(v []byte) {
+	if l.head == nil || len(l.head.items) >= listNodeSize {
+		l.linkBefore(l.head, &listNode{})
+	}
+	// prepending reallocates, nodes are small enough for it not to matter
+	l.head.items = append([][]byte{v}, l.head.items...)
+	l.n++
+	l.size += elementSize(v)
+}
+
+func (l *list) pushBack(v []byte) {
+	if l.tail == nil || len(l.tail.items) >= listNodeSize {
+		l.linkBefore(nil, &listNode{})
+	}
+	l.tail.items = append(l.tail.items, v)
+	l.n++
+	l.size += elementSize(v)
+}
+
+func (l *list) popFront() []byte {
+	v := l.head.items[0]
+	l.head.items[0] = nil
+	l.head.items = l.head.items[1:]
+	l.dropped(l.head, v)
+	return v
+}
+
+func (l *list) popBack() []byte {
+	last := len(l.tail.items) - 1
+	v := l.tail.items[last]
+	l.tail.items[last] = nil
+	l.tail.items = l.tail.items[:last]
+	l.dropped(l.tail, v)
+	return v
+}
+
+// dropped accounts for v removed from node, unlinking the node once empty
+func (l *list) dropped(node *listNode, v []byte) {
+	l.n--
+	l.size -= elementSize(v)
+	if len(node.items) == 0 {
+		l.unlink(node)
+	}
+}
+
+// linkBefore inserts node before at, at the tail when at is nil
+func (l *list) linkBefore(at, node *listNode) {
+	node.next = at
+	if at == nil {
+		node.prev = l.tail
+		l.tail = node
+	} else {
+		node.prev = at.prev
+		at.prev = node
+	}
+	if node.prev == nil {
+		l.head = node
+	} else {
+		node.prev.next = node
+	}
+}
+
+func (l *list) unlink(node *listNode) {
+	if node.prev == nil {
+		l.head = node.next
+	} else {
+		node.prev.next = node.next
+	}
+	if node.next == nil {
+		l.tail = node.prev
+	} else {
+		node.next.prev = node.prev
+	}
+}
+
+// seek returns the node holding index i, 0 <= i < n, and the offset of the
+// element in it, walking from the nearest end
+func (l *list) seek(i int) (*listNode, int) {
+	if i < l.n/2 {
+		node := l.head
+		for i >= len(node.items) {
+			i -= len(node.items)
+			node = node.next
+		}
+		return node, i
+	}
+	node := l.tail
+	i = l.n - 1 - i
+	for i >= len(node.items) {
+		i -= len(node.items)
+		node = node.prev
+	}
+	return node, len(node.items) - 1 - i
+}
+
+func (l *list) get(i int) []byte {
+	node, off := l.seek(i)
+	return node.items[off]
+}
+
+func (l *list) set(i int, v []byte) {
+	node, off := l.seek(i)
+	l.size += elementSize(v) - elementSize(node.items[off])
+	node.items[off] = v
+}
+
+// insert puts v at index i, 0 <= i <= n, shifting the elements after it
+func (l *list) insert(i int, v []byte) {
+	switch i {
+	case 0:
+		l.pushFront(v)
+		return
+	case l.n:
+		l.pushBack(v)
+		return
+	}
+	node, off := l.seek(i)
+	if len(node.items) >= listNodeSize {
+		half := len(node.items) / 2
+		next := &listNode{items: append([][]byte{}, node.items[half:]...)}
+		clear(node.items[half:])
+		node.items = node.items[:half]
+		l.linkBefore(node.next, next)
+		if off >= half {
+			node, off = next, off-half
+		}
+	}
+	node.items = append(node.items, nil)
+	copy(node.items[off+1:], node.items[off:])
+	node.items[off] = v
+	l.n++
+	l.size += elementSize(v)
+}
+
+// slice copies the elements from start to stop, both included and already
+// clamped to the list
+func (l *list) slice(start, stop int) [][]byte {
+	if start > stop {
+		return [][]byte{}
+	}
+	out := make([][]byte, 0, stop-start+1)
+	node, off := l.seek(start)
+	for len(out) < cap(out) {
+		if off == len(node.items) {
+			node, off = node.next, 0
+			continue
+		}
+		out = append(out, node.items[off])
+		off++
+	}
+	return out
+}
+
+// removeIf removes the elements drop returns true for, walking from the
+// tail when reverse is set, and returns how many it removed. once drop
+// returns stop the walk ends.
+func (l *list) removeIf(reverse bool, drop func(v []byte) (remove, stop bool)) int {
+	removed := 0
+	stopped := false
+	node := l.head
+	if reverse {
+		node = l.tail
+	}
+	for node != nil && !stopped {
+		next := node.next
+		if reverse {
+			next = node.prev
+		}
+		kept := make([][]byte, 0, len(node.items))
+		for k := range node.items {
+			if reverse {
+				k = len(node.items) - 1 - k
+			}
+			v := node.items[k]
+			if !stopped {
+				var remove bool
+				if remove, stopped = drop(v); remove {
+					l.n--
+					l.size -= elementSize(v)
+					removed++
+					continue
+				}
+			}
+			kept = append(kept, v)
+		}
+		if reverse {
+			slices.Reverse(kept)
+		}
+		node.items = kept
+		if len(kept) == 0 {
+			l.unlink(node)
+		}
+		node = next
+	}
+	return removed
+}
+
+// listRange clamps the redis style start and stop indexes, negative ones
+// count from the end, to the n elements of a list. start > stop means an
+// empty range.
+func listRange(start, stop, n int) (int, int) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	return max(start, 0), min(stop, n-1)
+}
+
+// readList runs fn on the list at key under the read lock, fn is not called
+// when the key is missing
+func (s *InMemoryStore) readList(key string, fn func(l *list)) error {
+	sh := s.getShard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	record, ok, err := sh.peek(key, TypeList, time.Now().UnixMilli())
+	if ok {
+		fn(record.obj.(*list))
+	}
+	return err
+}
+
+// writeList is writeHash for lists. a list that grew wakes up the clients
+// blocked on key.
+func (s *InMemoryStore) writeList(key string, create bool, fn func(l *list) error) error {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	record, ok, err := sh.lookup(key, TypeList, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if !create {
+			return nil
+		}
+		record = KVRecord{obj: newList(), exp: -1}
+	}
+	l := record.obj.(*list)
+	before := l.n
+	err = fn(l)
+	if ok || l.n > 0 {
+		sh.update(key, record)
+	}
+	if l.n > before {
+		s.watchers.signal(key)
+	}
+	return err
+}
+
+// LPush inserts values at the head of the list at key, one after the other,
+// and returns the new length
+func (s *InMemoryStore) LPush(key string, values [][]byte) (int, error) {
+	return s.push(key, values, true)
+}
+
+// RPush appends values to the list at key and returns the new length
+func (s *InMemoryStore) RPush(key string, values [][]byte) (int, error) {
+	return s.push(key, values, false)
+}
+
+func (s *InMemoryStore) push(key string, values [][]byte, front bool) (int, error) {
+	n := 0
+	err := s.writeList(key, true, func(l *list) error {
+		for _, v := range values {
+			if front {
+				l.pushFront(v)
+			} else {
+				l.pushBack(v)
+			}
+		}
+		n = l.n
+		return nil
+	})
+	return n, err
+}
+
+// LPop removes and returns up to count elements from the head of the list,
+// nil when the key is missing
+func (s *InMemoryStore) LPop(key string, count int) ([][]byte, error) {
+	return s.pop(key, count, true)
+}
+
+// RPop is LPop from the tail
+func (s *InMemoryStore) RPop(key string, count int) ([][]byte, error) {
+	return s.pop(key, count, false)
+}
+
+func (s *InMemoryStore) pop(key string, count int, front bool) ([][]byte, error) {
+	var values [][]byte
+	err := s.writeList(key, false, func(l *list) error {
+		values = make([][]byte, 0, min(count, l.n))
+		for len(values) < count && l.n > 0 {
+			if front {
+				values = append(values, l.popFront())
+			} else {
+				values = append(values, l.popBack())
+			}
+		}
+		return nil
+	})
+	return values, err
+}
+
+// PopFirst pops one element from the first non empty list of keys, the
+// non blocking part of BLPOP and BRPOP. key is empty when every list is.
+func (s *InMemoryStore) PopFirst(keys []string, front bool) (string, []byte, error) {
+	for _, key := range keys {
+		values, err := s.pop(key, 1, front)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(values) > 0 {
+			return key, values[0], nil
+		}
+	}
+	return "", nil, nil
+}
+
+func (s *InMemoryStore) LLen(key string) (int, error) {
+	n := 0
+	err := s.readList(key, func(l *list) {
+		n = l.n
+	})
+	return n, err
+}
+
+// LRange returns the elements between start and stop, both included,
+// negative indexes count from the end
+func (s *InMemoryStore) LRange(key string, start, stop int) ([][]byte, error) {
+	values := [][]byte{}
+	err := s.readList(key, func(l *list) {
+		values = l.slice(listRange(start, stop, l.n))
+	})
+	return values, err
+}
+
+// LIndex returns the element at index, nil when out of range
+func (s *InMemoryStore) LIndex(key string, index int) ([]byte, error) {
+	var value []byte
+	err := s.readList(key, func(l *list) {
+		if index < 0 {
+			index += l.n
+		}
+		if index >= 0 && index < l.n {
+			value = l.get(index)
+		}
+	})
+	return value, err
+}
+
+// LSet replaces the element at index
+func (s *InMemoryStore) LSet(key string, index int, value []byte) error {
+	found := false
+	err := s.writeList(key, false, func(l *list) error {
+		found = true
+		if index < 0 {
+			index += l.n
+		}
+		if index < 0 || index >= l.n {
+			return common.ErrIndexOutOfRange
+		}
+		l.set(index, value)
+		return nil
+	})
+	if err == nil && !found {
+		return common.ErrNoSuchKey
+	}
+	return err
+}
+
+// LInsert inserts value before or after the first element equal to pivot
+// and returns the new length, -1 when pivot is not found and 0 when the key
+// is missing
+func (s *InMemoryStore) LInsert(key string, before bool, pivot, value []byte) (int, error) {
+	n := 0
+	err := s.writeList(key, false, func(l *list) error {
+		n = -1
+		i := 0
+		for node := l.head; node != nil; node = node.next {
+			for _, v := range node.items {
+				if bytes.Equal(v, pivot) {
+					if !before {
+						i++
+					}
+					l.insert(i, value)
+					n = l.n
+					return nil
+				}
+				i++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// LRem removes the elements equal to value and returns how many: the first
+// count ones from the head for a positive count, the last -count ones for a
+// negative count, all of them for 0
+func (s *InMemoryStore) LRem(key string, count int, value []byte) (int, error) {
+	removed := 0
+	err := s.writeList(key, false, func(l *list) error {
+		limit := count
+		if limit < 0 {
+			limit = -limit
+		}
+		removed = l.removeIf(count < 0, func(v []byte) (bool, bool) {
+			if !bytes.Equal(v, value) {
+				return false, false
+			}
+			limit--
+			return true, limit == 0
+		})
+		return nil
+	})
+	return removed, err
+}
+
+// LTrim keeps the elements between start and stop, both included, the key
+// is removed when the range is empty
+func (s *InMemoryStore) LTrim(key string, start, stop int) error {
+	return s.writeList(key, false, func(l *list) error {
+		start, stop = listRange(start, stop, l.n)
+		if start > stop {
+			start, stop = l.n, l.n-1
+		}
+		for range l.n - 1 - stop {
+			l.popBack()
+		}
+		for range start {
+			l.popFront()
+		}
+		return nil
+	})
+}
+
+// LPos returns the indexes of the elements equal to value, see the LPOS
+// command: rank picks the first match to return, negative to search from
+// the tail, count is the number of matches, 0 for all of them, and maxLen
+// the number of elements to compare, 0 for all
+func (s *InMemoryStore) LPos(key string, value []byte, rank, count, maxLen int) ([]int, error) {
+	matches := []int{}
+	err := s.readList(key, func(l *list) {
+		skip := rank - 1
+		if rank < 0 {
+			skip = -rank - 1
+		}
+		walked := 0
+		match := func(i int, v []byte) bool {
+			walked++
+			if bytes.Equal(v, value) {
+				if skip > 0 {
+					skip--
+				} else {
+					matches = append(matches, i)
+				}
+			}
+			return (count == 0 || len(matches) < count) && (maxLen == 0 || walked < maxLen)
+		}
+		if rank > 0 {
+			i := 0
+			for node := l.head; node != nil; node = node.next {
+				for _, v := range node.items {
+					if !match(i, v) {
+						return
+					}
+					i++
+				}
+			}
+			return
+		}
+		i := l.n - 1
+		for node := l.tail; node != nil; node = node.prev {
+			for j := len(node.items) - 1; j >= 0; j-- {
+				if !match(i, node.items[j]) {
+					return
+				}
+				i--
+			}
+		}
+	})
+	return matches, err
+}
+
+// LMove pops an element from one end of src and pushes it to one end of dst
+// in one atomic step, nil when src is missing. src and dst may be the same
+// list, rotating it.
+func (s *InMemoryStore) LMove(src, dst string, fromFront, toFront bool) ([]byte, error) {
+	unlock := s.lockKeys([]string{src, dst})
+	defer unlock()
+
+	nowMs := time.Now().UnixMilli()
+	srcShard, dstShard := s.getShard(src), s.getShard(dst)
+	from, ok, err := srcShard.lookup(src, TypeList, nowMs)
+	if !ok {
+		return nil, err
+	}
+	to, ok, err := dstShard.lookup(dst, TypeList, nowMs)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		to = KVRecord{obj: newList(), exp: -1}
+	}
+	if src == dst {
+		to = from
+	}
+
+	var v []byte
+	if fromFront {
+		v = from.obj.(*list).popFront()
+	} else {
+		v = from.obj.(*list).popBack()
+	}
+	if toFront {
+		to.obj.(*list).pushFront(v)
+	} else {
+		to.obj.(*list).pushBack(v)
+	}
+	srcShard.update(src, from)
+	dstShard.update(dst, to)
+	s.watchers.signal(dst)
+	return v, nil
+}
@@ -0,0 +1,233 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+func strs(values [][]byte) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// TestListMatchesSlice runs random operations on a list and on a plain
+// slice, with enough elements to span many nodes
+func TestListMatchesSlice(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	l := newList()
+	model := [][]byte{}
+	for op := 0; op < 20_000; op++ {
+		v := []byte(fmt.Sprint(rng.IntN(50)))
+		switch k := rng.IntN(8); {
+		case k == 0:
+			l.pushFront(v)
+			model = append([][]byte{v}, model...)
+		case k <= 2:
+			l.pushBack(v)
+			model = append(model, v)
+		case k == 3 && len(model) > 0:
+			if got := l.popFront(); !bytes.Equal(got, model[0]) {
+				t.Fatalf("op %d: popFront got %q, want %q", op, got, model[0])
+			}
+			model = model[1:]
+		case k == 4 && len(model) > 0:
+			if got := l.popBack(); !bytes.Equal(got, model[len(model)-1]) {
+				t.Fatalf("op %d: popBack got %q", op, got)
+			}
+			model = model[:len(model)-1]
+		case k == 5:
+			i := rng.IntN(len(model) + 1)
+			l.insert(i, v)
+			model = slices.Insert(model, i, v)
+		case k == 6 && len(model) > 0:
+			i := rng.IntN(len(model))
+			l.set(i, v)
+			model[i] = v
+		case k == 7 && rng.IntN(20) == 0:
+			limit := rng.IntN(3)
+			left := limit
+			l.removeIf(false, func(e []byte) (bool, bool) {
+				if !bytes.Equal(e, v) {
+					return false, false
+				}
+				left--
+				return true, left == 0
+			})
+			model = removeN(model, v, limit)
+		}
+		if l.Len() != len(model) {
+			t.Fatalf("op %d: length %d, want %d", op, l.Len(), len(model))
+		}
+	}
+	if !slices.Equal(strs(l.items()), strs(model)) {
+		t.Fatalf("list and model diverged")
+	}
+	for i := range model {
+		if !bytes.Equal(l.get(i), model[i]) {
+			t.Fatalf("get(%d) = %q, want %q", i, l.get(i), model[i])
+		}
+	}
+	size := int64(0)
+	for _, v := range model {
+		size += elementSize(v)
+	}
+	if l.bytes() != size {
+		t.Errorf("Expected %d bytes accounted, got %d", size, l.bytes())
+	}
+}
+
+// removeN is the model of removeIf with a limit: the first limit elements
+// equal to v, every one for 0
+func removeN(model [][]byte, v []byte, limit int) [][]byte {
+	out := [][]byte{}
+	removed := 0
+	for _, e := range model {
+		if bytes.Equal(e, v) && (limit == 0 || removed < limit) {
+			removed++
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestListCommands(t *testing.T) {
+	s := NewInMemoryStore()
+	if n, _ := s.RPush("l", [][]byte{[]byte("a"), []byte("b"), []byte("c")}); n != 3 {
+		t.Fatalf("Expected 3 elements, got %d", n)
+	}
+	s.LPush("l", [][]byte{[]byte("y"), []byte("x")})
+	if got, _ := s.LRange("l", 0, -1); !slices.Equal(strs(got), []string{"x", "y", "a", "b", "c"}) {
+		t.Fatalf("Unexpected LRANGE %q", got)
+	}
+	if got, _ := s.LRange("l", -2, 100); !slices.Equal(strs(got), []string{"b", "c"}) {
+		t.Errorf("Unexpected LRANGE -2 100 %q", got)
+	}
+	if v, _ := s.LIndex("l", -1); string(v) != "c" {
+		t.Errorf("Unexpected LINDEX -1 %q", v)
+	}
+	if err := s.LSet("l", 5, []byte("z")); !errors.Is(err, common.ErrIndexOutOfRange) {
+		t.Errorf("Expected an index error, got %v", err)
+	}
+	if err := s.LSet("missing", 0, []byte("z")); !errors.Is(err, common.ErrNoSuchKey) {
+		t.Errorf("Expected a no such key error, got %v", err)
+	}
+	if n, _ := s.LInsert("l", false, []byte("a"), []byte("a2")); n != 6 {
+		t.Errorf("Expected LINSERT to return 6, got %d", n)
+	}
+	if n, _ := s.LInsert("l", true, []byte("nope"), []byte("v")); n != -1 {
+		t.Errorf("Expected -1 for a missing pivot, got %d", n)
+	}
+	if v, _ := s.LPop("l", 2); !slices.Equal(strs(v), []string{"x", "y"}) {
+		t.Errorf("Unexpected LPOP %q", v)
+	}
+	if v, _ := s.RPop("l", 1); !slices.Equal(strs(v), []string{"c"}) {
+		t.Errorf("Unexpected RPOP %q", v)
+	}
+	if err := s.LTrim("l", 1, 0); err != nil || s.Exists([]string{"l"}) != 0 {
+		t.Errorf("Expected an empty LTRIM to remove the key, %v", err)
+	}
+	if v, _ := s.LPop("l", 1); v != nil {
+		t.Errorf("Expected nil from a missing list, got %q", v)
+	}
+	if s.UsedMemory() != 0 {
+		t.Errorf("Expected every byte to be released, %d left", s.UsedMemory())
+	}
+}
+
+func TestLRemLPos(t *testing.T) {
+	s := NewInMemoryStore()
+	s.RPush("l", [][]byte{[]byte("a"), []byte("b"), []byte("a"), []byte("c"), []byte("a")})
+	tests := []struct {
+		rank, count, maxLen int
+		want                []int
+	}{
+		{1, 0, 0, []int{0, 2, 4}},
+		{2, 1, 0, []int{2}},
+		{-1, 2, 0, []int{4, 2}},
+		{1, 0, 2, []int{0}},
+		{4, 0, 0, []int{}},
+	}
+	for _, tt := range tests {
+		if got, _ := s.LPos("l", []byte("a"), tt.rank, tt.count, tt.maxLen); !slices.Equal(got, tt.want) {
+			t.Errorf("LPos rank %d count %d maxlen %d: expected %v, got %v", tt.rank, tt.count, tt.maxLen, tt.want, got)
+		}
+	}
+
+	if n, _ := s.LRem("l", -1, []byte("a")); n != 1 {
+		t.Errorf("Expected 1 removal from the tail, got %d", n)
+	}
+	if got, _ := s.LRange("l", 0, -1); !slices.Equal(strs(got), []string{"a", "b", "a", "c"}) {
+		t.Errorf("Unexpected list after LREM -1: %q", got)
+	}
+	if n, _ := s.LRem("l", 0, []byte("a")); n != 2 {
+		t.Errorf("Expected every match removed, got %d", n)
+	}
+}
+
+func TestLMove(t *testing.T) {
+	s := NewInMemoryStore()
+	s.RPush("src", [][]byte{[]byte("a"), []byte("b")})
+	if v, _ := s.LMove("src", "src", true, false); string(v) != "a" {
+		t.Errorf("Expected a rotation to move a, got %q", v)
+	}
+	if got, _ := s.LRange("src", 0, -1); !slices.Equal(strs(got), []string{"b", "a"}) {
+		t.Errorf("Unexpected rotated list %q", got)
+	}
+
+	s.Set("str", []byte("v"))
+	if _, err := s.LMove("src", "str", true, true); !errors.Is(err, common.ErrWrongType) {
+		t.Errorf("Expected WRONGTYPE for the destination, got %v", err)
+	}
+	if n, _ := s.LLen("src"); n != 2 {
+		t.Errorf("Expected a failed LMOVE to change nothing, got %d elements", n)
+	}
+
+	s.LMove("src", "dst", false, true)
+	s.LMove("src", "dst", false, true)
+	if s.Exists([]string{"src"}) != 0 {
+		t.Errorf("Expected the emptied source to be removed")
+	}
+	if got, _ := s.LRange("dst", 0, -1); !slices.Equal(strs(got), []string{"b", "a"}) {
+		t.Errorf("Unexpected destination %q", got)
+	}
+	if v, err := s.LMove("src", "str", true, true); v != nil || err != nil {
+		t.Errorf("Expected nil for a missing source, got %q, %v", v, err)
+	}
+}
+
+func TestWatchSignalsPushes(t *testing.T) {
+	s := NewInMemoryStore()
+	w := s.Watch([]string{"a", "b"})
+	defer w.Stop()
+
+	s.RPush("other", [][]byte{[]byte("v")})
+	s.LPop("b", 1)
+	select {
+	case <-w.C:
+		t.Fatalf("Expected no signal for other keys and pops")
+	default:
+	}
+
+	s.RPush("other", [][]byte{[]byte("v")})
+	s.LMove("other", "b", true, true)
+	select {
+	case <-w.C:
+	default:
+		t.Fatalf("Expected LMOVE into a watched key to signal")
+	}
+
+	w.Stop()
+	if len(s.watchers.keys) != 0 {
+		t.Errorf("Expected Stop to unregister the watcher, %d keys left", len(s.watchers.keys))
+	}
+}
@@ -1,13 +1,16 @@
 package store
 
 import (
+	"math"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/B-AJ-Amar/gokv/internal/common"
 )
 
-// for this version we will just keep it simple (map)
+// every database is a set of lock-striped shards (see shard.go), so the
+// store can be used from one goroutine per client connection
 
 const (
 	ExpireNone = iota
@@ -16,6 +19,7 @@ const (
 	ExpireEXAT
 	ExpirePXAT
 	ExpireKEEPTTL
+	ExpirePERSIST // GETEX only, drops the ttl
 )
 
 type SetArgs struct {
@@ -28,33 +32,58 @@ type SetArgs struct {
 
 type KVRecord struct {
 	Value []byte
-	exp   int64 // exp = unix_time_now(ms) + ttl(ms)
+	obj   object // the value of every type but strings, see value.go
+	exp   int64  // exp = unix_time_now(ms) + ttl(ms)
+	size  int64  // bytes accounted for the record, set by put
 }
 
 type KVStore interface {
 	Set(Key string, Value []byte) int
 	Setx(key string, Value []byte, args SetArgs) (int, []byte, error)
 	Get(key string) ([]byte, error)
+	Append(key string, value []byte) (int, error)
+	StrLen(key string) (int, error)
+	GetRange(key string, start, end int) ([]byte, error)
+	SetRange(key string, offset int, value []byte) (int, error)
+	GetDel(key string) ([]byte, error)
+	GetEx(key string, args SetArgs) ([]byte, error)
+	MGet(keys []string) [][]byte
+	MSet(keys []string, values [][]byte)
+	MSetNX(keys []string, values [][]byte) int
 	Del(keys []string) int
 	Type(key string) string
-	Incrby(key string, by int) (int, error)
-	Decrby(key string, by int) (int, error)
+	Incrby(key string, by int64) (int64, error)
+	Decrby(key string, by int64) (int64, error)
+	IncrbyFloat(key string, by float64) ([]byte, error)
 	Exists(keys []string) int
 	TTL(key string) (int, error)
 	Expire(key string, seconds int) (int, error)
+	ExpireAt(key string, unixMs int64) (int, error)
+	PExpireTime(key string) int64
 	Persist(key string) (int, error)
 	GetAllKeys() []string
 	GetAllValues() [][]byte
 }
 
+var _ KVStore = (*InMemoryStore)(nil)
+
 type InMemoryStore struct {
-	data map[string]KVRecord
-	// TODO: add queue support
+	shards []*shard
+	used   *atomic.Int64
+	// clients blocked on keys of this database, see Watch
+	watchers *watchers
 }
 
 func NewInMemoryStore() InMemoryStore {
+	used := &atomic.Int64{}
+	shards := make([]*shard, shardCount)
+	for i := range shards {
+		shards[i] = newShard(used)
+	}
 	return InMemoryStore{
-		data: make(map[string]KVRecord),
+		shards:   shards,
+		used:     used,
+		watchers: newWatchers(),
 	}
 }
 
@@ -67,135 +96,428 @@ func NewInMemoryStoreArray(len int) []*InMemoryStore {
 	return stores
 }
 
-// TODO: add mutex for set and setx
 func (s *InMemoryStore) Set(key string, Value []byte) int {
-	s.data[key] = KVRecord{Value: Value, exp: -1}
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.put(key, KVRecord{Value: Value, exp: -1})
 	return 1
 }
 
 func (s *InMemoryStore) Setx(key string, Value []byte, args SetArgs) (int, []byte, error) {
-	expUnix := int64(-1)
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	// an expired key is missing for NX, XX and GET
+	nowMs := time.Now().UnixMilli()
+	sh.deleteIfExpired(key, nowMs)
+
 	oldValue := []byte{}
 	retOld := false
 
 	switch args.NX_XX {
 	case 1: // NX
-		if _, ok := s.data[key]; ok {
+		if _, ok := sh.data[key]; ok {
 			return 0, nil, nil
 		}
 		if args.Get {
 			return 0, nil, common.ErrSyntaxError
 		}
 	case 2: // XX
-		if _, ok := s.data[key]; !ok {
+		if _, ok := sh.data[key]; !ok {
 			return 0, nil, nil
 		}
 	}
 
-	nowMs := time.Now().UnixMilli()
-	switch args.ExpType {
-	case ExpireEX:
-		expUnix = nowMs + int64(args.ExpVal)*1000 // EX is seconds, convert to ms
-	case ExpirePX:
-		expUnix = nowMs + int64(args.ExpVal) // PX is ms
-	case ExpireEXAT:
-		expUnix = int64(args.ExpVal) * 1000 // EXAT is seconds, convert to ms
-	case ExpirePXAT:
-		expUnix = int64(args.ExpVal) // PXAT is ms
-	default:
-		expUnix = -1
-	}
+	expUnix := expireTime(args, nowMs)
 
 	if args.KeepTTL {
-		if record, ok := s.data[key]; ok {
+		if record, ok := sh.data[key]; ok {
 			expUnix = record.exp
 		}
 	}
 
 	if args.Get {
-		if record, ok := s.data[key]; ok {
+		if record, ok := sh.data[key]; ok {
+			if record.obj != nil {
+				return 0, nil, common.ErrWrongType
+			}
 			oldValue = record.Value
 			retOld = true
 		}
 	}
 
-	s.data[key] = KVRecord{Value: Value, exp: expUnix}
+	sh.put(key, KVRecord{Value: Value, exp: expUnix})
 	if retOld {
 		return 1, oldValue, nil
 	}
 	return 1, nil, nil
 }
 
+// expireTime is the absolute expire time in unix ms set by the EX, PX, EXAT
+// or PXAT option of args, -1 for none
+func expireTime(args SetArgs, nowMs int64) int64 {
+	switch args.ExpType {
+	case ExpireEX:
+		return nowMs + int64(args.ExpVal)*1000 // EX is seconds, convert to ms
+	case ExpirePX:
+		return nowMs + int64(args.ExpVal) // PX is ms
+	case ExpireEXAT:
+		return int64(args.ExpVal) * 1000 // EXAT is seconds, convert to ms
+	case ExpirePXAT:
+		return int64(args.ExpVal) // PXAT is ms
+	default:
+		return -1
+	}
+}
+
 func (s *InMemoryStore) Get(key string) ([]byte, error) {
-	if record, ok := s.data[key]; ok {
-		nowMs := time.Now().UnixMilli()
-		if record.exp != -1 && record.exp <= nowMs {
-			delete(s.data, key)
-			return nil, nil
+	sh := s.getShard(key)
+	sh.mu.RLock()
+	record, ok := sh.data[key]
+	sh.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+
+	nowMs := time.Now().UnixMilli()
+	if record.exp != -1 && record.exp <= nowMs {
+		sh.mu.Lock()
+		sh.deleteIfExpired(key, nowMs)
+		sh.mu.Unlock()
+		return nil, nil
+	}
+	if record.obj != nil {
+		return nil, common.ErrWrongType
+	}
+	return record.Value, nil
+}
+
+// MGet returns the values of keys at a single point in time, nil for the
+// missing and expired ones and for keys of another type
+func (s *InMemoryStore) MGet(keys []string) [][]byte {
+	unlock := s.lockKeys(keys)
+	defer unlock()
+
+	nowMs := time.Now().UnixMilli()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		sh := s.getShard(key)
+		if sh.deleteIfExpired(key, nowMs) {
+			continue
+		}
+		if record, ok := sh.data[key]; ok && record.obj == nil {
+			values[i] = record.Value
 		}
+	}
+	return values
+}
+
+// MSet sets every key to its value atomically, like SET it drops the ttl of
+// the keys. a key given twice keeps its last value.
+func (s *InMemoryStore) MSet(keys []string, values [][]byte) {
+	unlock := s.lockKeys(keys)
+	defer unlock()
+
+	for i, key := range keys {
+		s.getShard(key).put(key, KVRecord{Value: values[i], exp: -1})
+	}
+}
+
+// MSetNX is MSet when none of the keys exist, it returns 0 and sets nothing
+// otherwise
+func (s *InMemoryStore) MSetNX(keys []string, values [][]byte) int {
+	unlock := s.lockKeys(keys)
+	defer unlock()
+
+	nowMs := time.Now().UnixMilli()
+	for _, key := range keys {
+		sh := s.getShard(key)
+		if sh.deleteIfExpired(key, nowMs) {
+			continue
+		}
+		if _, ok := sh.data[key]; ok {
+			return 0
+		}
+	}
+	for i, key := range keys {
+		s.getShard(key).put(key, KVRecord{Value: values[i], exp: -1})
+	}
+	return 1
+}
+
+// Append appends value to the string at key, creating it when missing, and
+// returns the new length. the ttl is kept.
+func (s *InMemoryStore) Append(key string, value []byte) (int, error) {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	record, ok, err := sh.lookup(key, TypeString, time.Now().UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		record.exp = -1
+	}
+	// always copy, the old value may be shared with a reader
+	buf := make([]byte, 0, len(record.Value)+len(value))
+	buf = append(append(buf, record.Value...), value...)
+	record.Value = buf
+	sh.put(key, record)
+	return len(buf), nil
+}
+
+// StrLen is the length of the string at key, 0 when missing
+func (s *InMemoryStore) StrLen(key string) (int, error) {
+	value, err := s.Get(key)
+	return len(value), err
+}
+
+// GetRange returns the bytes of the string at key between start and end,
+// both included. negative offsets count from the end, like redis.
+func (s *InMemoryStore) GetRange(key string, start, end int) ([]byte, error) {
+	value, err := s.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	n := len(value)
+	if start < 0 {
+		start += n
+	}
+	if end < 0 {
+		end += n
+	}
+	start = max(start, 0)
+	end = min(end, n-1)
+	if n == 0 || start > end {
+		return []byte{}, nil
+	}
+	return value[start : end+1], nil
+}
+
+// SetRange overwrites the string at key from offset with value, padding it
+// with zero bytes when it is shorter than offset, and returns the new length.
+// an empty value changes nothing, the key is not even created.
+func (s *InMemoryStore) SetRange(key string, offset int, value []byte) (int, error) {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	record, ok, err := sh.lookup(key, TypeString, time.Now().UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	if len(value) == 0 {
+		return len(record.Value), nil
+	}
+	if !ok {
+		record.exp = -1
+	}
+	buf := make([]byte, max(len(record.Value), offset+len(value)))
+	copy(buf, record.Value)
+	copy(buf[offset:], value)
+	record.Value = buf
+	sh.put(key, record)
+	return len(buf), nil
+}
+
+// GetDel returns the value of key and deletes it, nil when missing
+func (s *InMemoryStore) GetDel(key string) ([]byte, error) {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	record, ok, err := sh.lookup(key, TypeString, time.Now().UnixMilli())
+	if !ok {
+		return nil, err
+	}
+	sh.remove(key)
+	return record.Value, nil
+}
+
+// GetEx returns the value of key and changes its ttl with the EX, PX, EXAT,
+// PXAT or PERSIST option of args, nil when missing
+func (s *InMemoryStore) GetEx(key string, args SetArgs) ([]byte, error) {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	nowMs := time.Now().UnixMilli()
+	record, ok, err := sh.lookup(key, TypeString, nowMs)
+	if !ok {
+		return nil, err
+	}
+	switch args.ExpType {
+	case ExpireNone, ExpireKEEPTTL:
 		return record.Value, nil
+	case ExpirePERSIST:
+		record.exp = -1
+	default:
+		record.exp = expireTime(args, nowMs)
 	}
-	return nil, nil
+	sh.put(key, record)
+	return record.Value, nil
 }
 
 func (s *InMemoryStore) Del(keys []string) int {
+	unlock := s.lockKeys(keys)
+	defer unlock()
+
+	nowMs := time.Now().UnixMilli()
 	deleted := 0
 	for _, key := range keys {
-		if _, ok := s.data[key]; ok {
-			delete(s.data, key)
+		sh := s.getShard(key)
+		if _, ok := sh.live(key, nowMs); ok {
+			sh.remove(key)
 			deleted++
 		}
 	}
 	return deleted
 }
+
+// Type returns the type name of the value at key, none when it is missing
+func (s *InMemoryStore) Type(key string) string {
+	sh := s.getShard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	record, ok := sh.data[key]
+	if !ok || (record.exp != -1 && record.exp <= time.Now().UnixMilli()) {
+		return "none"
+	}
+	return record.valueType().String()
+}
+
+// Exists counts the keys that are set, a key repeated is counted each time.
+// keys past their ttl that the active cycle did not collect yet don't count.
 func (s *InMemoryStore) Exists(keys []string) int {
+	nowMs := time.Now().UnixMilli()
 	exists := 0
 	for _, key := range keys {
-		if _, ok := s.data[key]; ok {
+		sh := s.getShard(key)
+		sh.mu.RLock()
+		if record, ok := sh.data[key]; ok && (record.exp == -1 || record.exp > nowMs) {
 			exists++
 		}
+		sh.mu.RUnlock()
 	}
 	return exists
 }
-func (s *InMemoryStore) Incrby(key string, by int) (int, error) {
-	rec := 0
-	if record, ok := s.data[key]; ok {
-		rec, err := strconv.Atoi(string(record.Value))
-		if err != nil {
-			return 0, common.ErrNotIntOROutOfRange
+
+// Incrby adds by to the integer at key, a missing key counts as 0, and
+// returns the new value. the ttl is kept.
+func (s *InMemoryStore) Incrby(key string, by int64) (int64, error) {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	record, ok, err := sh.lookup(key, TypeString, time.Now().UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		record.exp = -1
+	}
+	n := int64(0)
+	if ok {
+		var err error
+		if n, err = parseInt(record.Value); err != nil {
+			return 0, err
 		}
-		rec += by
-		record.Value = []byte(strconv.Itoa(rec))
-		return rec, nil
 	}
-	s.data[key] = KVRecord{Value: []byte(strconv.Itoa(by)), exp: -1}
-	return rec, nil
+	if (by > 0 && n > math.MaxInt64-by) || (by < 0 && n < math.MinInt64-by) {
+		return 0, common.ErrIncrOverflow
+	}
+	n += by
+	record.Value = strconv.AppendInt(nil, n, 10)
+	sh.put(key, record)
+	return n, nil
+}
 
+func (s *InMemoryStore) Decrby(key string, by int64) (int64, error) {
+	if by == math.MinInt64 {
+		return 0, common.ErrDecrOverflow
+	}
+	return s.Incrby(key, -by)
 }
-func (s *InMemoryStore) Decrby(key string, by int) (int, error) {
-	rec := 0
-	if record, ok := s.data[key]; ok {
-		rec, err := strconv.Atoi(string(record.Value))
-		if err != nil {
-			return 0, common.ErrNotIntOROutOfRange
+
+// IncrbyFloat adds by to the number at key, a missing key counts as 0, and
+// returns the new value formatted like redis does. the ttl is kept.
+func (s *InMemoryStore) IncrbyFloat(key string, by float64) ([]byte, error) {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	record, ok, err := sh.lookup(key, TypeString, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		record.exp = -1
+	}
+	f := 0.0
+	if ok {
+		var err error
+		if f, err = ParseFloat(record.Value); err != nil {
+			return nil, err
 		}
-		rec -= by
-		record.Value = []byte(strconv.Itoa(rec))
-		return rec, nil
 	}
-	s.data[key] = KVRecord{Value: []byte(strconv.Itoa(-by)), exp: -1}
-	return rec, nil
+	f += by
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, common.ErrIncrNaN
+	}
+	record.Value = FormatFloat(f)
+	sh.put(key, record)
+	return record.Value, nil
+}
+
+// parseInt reads an integer value, strictly: no sign other than a leading
+// minus, no spaces, no leading zeros, like redis
+func parseInt(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 20 || b[0] == '+' || (len(b) > 1 && b[0] == '0') || (len(b) > 2 && b[0] == '-' && b[1] == '0') {
+		return 0, common.ErrNotIntOROutOfRange
+	}
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, common.ErrNotIntOROutOfRange
+	}
+	return n, nil
+}
 
+// ParseFloat reads a float value or argument, NaN and surrounding spaces
+// are refused
+func ParseFloat(b []byte) (float64, error) {
+	if len(b) == 0 || b[0] == ' ' || b[len(b)-1] == ' ' {
+		return 0, common.ErrNotFloat
+	}
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, common.ErrNotFloat
+	}
+	return f, nil
+}
+
+// FormatFloat formats the result of INCRBYFLOAT: plain digits, no exponent
+// and no trailing zeros, so 5.0e3 + 2.0e2 is 5200
+func FormatFloat(f float64) []byte {
+	return strconv.AppendFloat(nil, f, 'f', -1, 64)
 }
 
 func (s *InMemoryStore) TTL(key string) (int, error) {
-	if record, ok := s.data[key]; ok {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if record, ok := sh.data[key]; ok {
 		if record.exp == -1 {
 			return -1, nil
 		}
 		nowMs := time.Now().UnixMilli()
 		if record.exp <= nowMs {
-			delete(s.data, key)
+			sh.expire(key)
 			return -2, nil
 		}
 		ttl := record.exp - nowMs
@@ -206,49 +528,132 @@ func (s *InMemoryStore) TTL(key string) (int, error) {
 }
 
 func (s *InMemoryStore) Expire(key string, seconds int) (int, error) {
-	if record, ok := s.data[key]; ok {
-		nowMs := time.Now().UnixMilli()
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	nowMs := time.Now().UnixMilli()
+	if record, ok := sh.live(key, nowMs); ok {
 		record.exp = nowMs + int64(seconds)*1000
-		s.data[key] = record
+		sh.put(key, record)
 		return 1, nil
 	}
 	return 0, nil
 }
 
+// ExpireAt sets an absolute expire time in unix ms
+func (s *InMemoryStore) ExpireAt(key string, unixMs int64) (int, error) {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if record, ok := sh.live(key, time.Now().UnixMilli()); ok {
+		record.exp = unixMs
+		sh.put(key, record)
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// PExpireTime returns the absolute expire time in unix ms,
+// -1 if the key has no ttl and -2 if it does not exist
+func (s *InMemoryStore) PExpireTime(key string) int64 {
+	sh := s.getShard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	if record, ok := sh.data[key]; ok {
+		if record.exp != -1 && record.exp <= time.Now().UnixMilli() {
+			return -2
+		}
+		return record.exp
+	}
+	return -2
+}
+
+// Persist drops the ttl of key, 0 when the key is missing or has none
 func (s *InMemoryStore) Persist(key string) (int, error) {
-	if record, ok := s.data[key]; ok {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if record, ok := sh.live(key, time.Now().UnixMilli()); ok && record.exp != -1 {
 		record.exp = -1
-		s.data[key] = record
+		sh.put(key, record)
 		return 1, nil
 	}
 	return 0, nil
 }
 
+// UsedMemory is an estimate of the bytes held by the records of the database
+func (s *InMemoryStore) UsedMemory() int64 {
+	return s.used.Load()
+}
+
+// TotalUsedMemory sums UsedMemory over a set of databases
+func TotalUsedMemory(stores []*InMemoryStore) int64 {
+	total := int64(0)
+	for _, s := range stores {
+		total += s.UsedMemory()
+	}
+	return total
+}
+
+// KeyCount returns how many keys the database holds, like DBSIZE it counts
+// the expired keys the active cycle did not collect yet
+func (s *InMemoryStore) KeyCount() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		n += len(sh.data)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// ExpiresCount returns how many keys of the database carry a ttl
+func (s *InMemoryStore) ExpiresCount() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		n += sh.expires.Len()
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
 func (s *InMemoryStore) GetAllKeys() []string {
 	nowMs := time.Now().UnixMilli()
-	keys := make([]string, 0, len(s.data))
-	for k, rec := range s.data {
-		if rec.exp != -1 && rec.exp <= nowMs {
-			delete(s.data, k)
-			continue
+	keys := []string{}
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for k, rec := range sh.data {
+			if rec.exp != -1 && rec.exp <= nowMs {
+				sh.expire(k)
+				continue
+			}
+			keys = append(keys, k)
 		}
-		keys = append(keys, k)
+		sh.mu.Unlock()
 	}
 	return keys
 }
 
 func (s *InMemoryStore) GetAllValues() [][]byte {
 	nowMs := time.Now().UnixMilli()
-	values := make([][]byte, 0, len(s.data))
-	for k, rec := range s.data {
-		if rec.exp != -1 && rec.exp <= nowMs {
-			delete(s.data, k)
-			continue
+	values := [][]byte{}
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for k, rec := range sh.data {
+			if rec.exp != -1 && rec.exp <= nowMs {
+				sh.expire(k)
+				continue
+			}
+			v := make([]byte, len(rec.Value))
+			copy(v, rec.Value)
+			values = append(values, v)
 		}
-		v := make([]byte, len(rec.Value))
-		copy(v, rec.Value)
-		values = append(values, v)
-		_ = k // keep lint happy if k unused in some builds
+		sh.mu.Unlock()
 	}
 	return values
 }
@@ -0,0 +1,274 @@
+package store
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+func TestMGetMSet(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Setx("ttl", []byte("old"), SetArgs{ExpType: ExpirePX, ExpVal: 10_000})
+	s.Setx("gone", []byte("x"), SetArgs{ExpType: ExpirePXAT, ExpVal: int(time.Now().UnixMilli() - 1)})
+
+	s.MSet([]string{"a", "ttl", "a"}, [][]byte{[]byte("1"), []byte("2"), []byte("3")})
+	got := s.MGet([]string{"a", "ttl", "missing", "gone"})
+	if string(got[0]) != "3" || string(got[1]) != "2" || got[2] != nil || got[3] != nil {
+		t.Fatalf("Unexpected MGet %q", got)
+	}
+	if exp := s.PExpireTime("ttl"); exp != -1 {
+		t.Errorf("Expected MSet to drop the ttl, got %d", exp)
+	}
+}
+
+func TestMSetNX(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Set("b", []byte("taken"))
+	if n := s.MSetNX([]string{"a", "b"}, [][]byte{[]byte("1"), []byte("2")}); n != 0 {
+		t.Fatalf("Expected MSetNX to fail on an existing key")
+	}
+	if v, _ := s.Get("a"); v != nil {
+		t.Fatalf("Expected MSetNX to set nothing, a is %q", v)
+	}
+
+	// an expired key counts as missing
+	s.Setx("b", []byte("old"), SetArgs{ExpType: ExpirePXAT, ExpVal: int(time.Now().UnixMilli() - 1)})
+	if n := s.MSetNX([]string{"a", "b"}, [][]byte{[]byte("1"), []byte("2")}); n != 1 {
+		t.Fatalf("Expected MSetNX to succeed")
+	}
+
+	// racing on the same keys, exactly one caller wins
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	won := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := s.MSetNX([]string{"x", "y", "z"}, [][]byte{[]byte("1"), []byte("2"), []byte("3")})
+			mu.Lock()
+			won += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if won != 1 {
+		t.Fatalf("Expected one MSetNX to win, got %d", won)
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Set("k", []byte("This is a string"))
+	tests := []struct {
+		start, end int
+		want       string
+	}{
+		{0, 3, "This"},
+		{-3, -1, "ing"},
+		{0, -1, "This is a string"},
+		{10, 100, "string"},
+		{-100, 3, "This"},
+		{5, 3, ""},
+		{100, 200, ""},
+		{0, -100, ""},
+	}
+	for _, tt := range tests {
+		if got, _ := s.GetRange("k", tt.start, tt.end); string(got) != tt.want {
+			t.Errorf("GetRange(%d, %d): expected %q, got %q", tt.start, tt.end, tt.want, got)
+		}
+	}
+	if got, _ := s.GetRange("missing", 0, -1); len(got) != 0 {
+		t.Errorf("Expected an empty range for a missing key, got %q", got)
+	}
+}
+
+func TestAppendSetRange(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Setx("k", []byte("Hello"), SetArgs{ExpType: ExpirePX, ExpVal: 10_000})
+	if n, _ := s.Append("k", []byte(" World")); n != 11 {
+		t.Fatalf("Expected length 11, got %d", n)
+	}
+	if exp := s.PExpireTime("k"); exp == -1 {
+		t.Errorf("Expected APPEND to keep the ttl")
+	}
+	if n, _ := s.SetRange("k", 6, []byte("Redis")); n != 11 {
+		t.Fatalf("Expected length 11, got %d", n)
+	}
+	if v, _ := s.Get("k"); string(v) != "Hello Redis" {
+		t.Errorf("Unexpected value %q", v)
+	}
+
+	if n, _ := s.SetRange("pad", 3, []byte("x")); n != 4 {
+		t.Fatalf("Expected length 4, got %d", n)
+	}
+	if v, _ := s.Get("pad"); string(v) != "\x00\x00\x00x" {
+		t.Errorf("Expected zero padding, got %q", v)
+	}
+	if n, _ := s.SetRange("empty", 10, nil); n != 0 || s.Exists([]string{"empty"}) != 0 {
+		t.Errorf("Expected an empty SETRANGE to not create the key")
+	}
+	if n, _ := s.Append("new", []byte("v")); n != 1 {
+		t.Errorf("Expected APPEND to create the key")
+	}
+
+	// an expired key is replaced, not appended to
+	s.Setx("old", []byte("stale"), SetArgs{ExpType: ExpirePXAT, ExpVal: int(time.Now().UnixMilli() - 1)})
+	if n, _ := s.Append("old", []byte("v")); n != 1 || s.PExpireTime("old") != -1 {
+		t.Errorf("Expected APPEND on an expired key to start over, got length %d", n)
+	}
+}
+
+func TestGetDelGetEx(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Set("k", []byte("v"))
+	if v, _ := s.GetEx("k", SetArgs{ExpType: ExpireEX, ExpVal: 100}); string(v) != "v" {
+		t.Fatalf("Unexpected GetEx %q", v)
+	}
+	if ttl, _ := s.TTL("k"); ttl < 99 {
+		t.Errorf("Expected a ttl of 100s, got %d", ttl)
+	}
+	s.GetEx("k", SetArgs{ExpType: ExpirePERSIST})
+	if ttl, _ := s.TTL("k"); ttl != -1 {
+		t.Errorf("Expected PERSIST to drop the ttl, got %d", ttl)
+	}
+	if v, _ := s.GetEx("missing", SetArgs{ExpType: ExpireEX, ExpVal: 1}); v != nil {
+		t.Errorf("Expected nil for a missing key, got %q", v)
+	}
+
+	if v, _ := s.GetDel("k"); string(v) != "v" {
+		t.Fatalf("Unexpected GetDel %q", v)
+	}
+	if v, _ := s.GetDel("k"); v != nil || s.UsedMemory() != 0 {
+		t.Errorf("Expected the key and its memory to be gone, got %q and %d bytes", v, s.UsedMemory())
+	}
+}
+
+func TestSetxNXExpiredKey(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Setx("k", []byte("old"), SetArgs{ExpType: ExpirePXAT, ExpVal: int(time.Now().UnixMilli() - 1)})
+	if n, _, _ := s.Setx("k", []byte("new"), SetArgs{NX_XX: 1}); n != 1 {
+		t.Fatalf("Expected NX to treat an expired key as missing")
+	}
+	if v, _ := s.Get("k"); string(v) != "new" {
+		t.Errorf("Unexpected value %q", v)
+	}
+}
+
+func TestIncrby(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial string // "" for a missing key
+		by      int64
+		want    int64
+		err     error
+	}{
+		{"missing key", "", 5, 5, nil},
+		{"missing key negative", "", -5, -5, nil},
+		{"existing", "10", 3, 13, nil},
+		{"negative value", "-10", 3, -7, nil},
+		{"to max", "9223372036854775806", 1, math.MaxInt64, nil},
+		{"overflow", "9223372036854775807", 1, 0, common.ErrIncrOverflow},
+		{"underflow", "-9223372036854775808", -1, 0, common.ErrIncrOverflow},
+		{"not a number", "abc", 1, 0, common.ErrNotIntOROutOfRange},
+		{"float", "1.5", 1, 0, common.ErrNotIntOROutOfRange},
+		{"leading plus", "+1", 1, 0, common.ErrNotIntOROutOfRange},
+		{"leading zero", "01", 1, 0, common.ErrNotIntOROutOfRange},
+		{"spaces", " 1", 1, 0, common.ErrNotIntOROutOfRange},
+		{"too big", "9223372036854775808", 1, 0, common.ErrNotIntOROutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewInMemoryStore()
+			if tt.initial != "" {
+				s.Set("k", []byte(tt.initial))
+			}
+			got, err := s.Incrby("k", tt.by)
+			if err != tt.err || got != tt.want {
+				t.Fatalf("Expected %d, %v, got %d, %v", tt.want, tt.err, got, err)
+			}
+			v, _ := s.Get("k")
+			want := tt.initial
+			if err == nil {
+				want = strconv.FormatInt(tt.want, 10)
+			}
+			if string(v) != want {
+				t.Errorf("Expected the stored value %q, got %q", want, v)
+			}
+		})
+	}
+}
+
+func TestDecrby(t *testing.T) {
+	tests := []struct {
+		initial string
+		by      int64
+		want    int64
+		err     error
+	}{
+		{"", 1, -1, nil},
+		{"10", 4, 6, nil},
+		{"-9223372036854775807", 1, math.MinInt64, nil},
+		{"-9223372036854775808", 1, 0, common.ErrIncrOverflow},
+		{"0", math.MinInt64, 0, common.ErrDecrOverflow},
+	}
+	for _, tt := range tests {
+		s := NewInMemoryStore()
+		if tt.initial != "" {
+			s.Set("k", []byte(tt.initial))
+		}
+		if got, err := s.Decrby("k", tt.by); err != tt.err || got != tt.want {
+			t.Errorf("Decrby(%q, %d): expected %d, %v, got %d, %v", tt.initial, tt.by, tt.want, tt.err, got, err)
+		}
+	}
+}
+
+func TestIncrbyFloat(t *testing.T) {
+	tests := []struct {
+		initial string
+		by      float64
+		want    string
+		err     error
+	}{
+		{"", 0.1, "0.1", nil},
+		{"10.50", 0.1, "10.6", nil},
+		{"5.0e3", 2.0e2, "5200", nil},
+		{"3", 1.5, "4.5", nil},
+		{"-1", -0.25, "-1.25", nil},
+		{"1", -1, "0", nil},
+		{"1e308", 1e308, "", common.ErrIncrNaN},
+		{"abc", 1, "", common.ErrNotFloat},
+		{"nan", 1, "", common.ErrNotFloat},
+	}
+	for _, tt := range tests {
+		s := NewInMemoryStore()
+		if tt.initial != "" {
+			s.Set("k", []byte(tt.initial))
+		}
+		got, err := s.IncrbyFloat("k", tt.by)
+		if err != tt.err || string(got) != tt.want {
+			t.Errorf("IncrbyFloat(%q, %g): expected %q, %v, got %q, %v", tt.initial, tt.by, tt.want, tt.err, got, err)
+		}
+	}
+}
+
+func TestCountersKeepTTL(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Setx("n", []byte("1"), SetArgs{ExpType: ExpirePX, ExpVal: 10_000})
+	s.Setx("f", []byte("1"), SetArgs{ExpType: ExpirePX, ExpVal: 10_000})
+	s.Incrby("n", 1)
+	s.IncrbyFloat("f", 0.5)
+	if s.PExpireTime("n") == -1 || s.PExpireTime("f") == -1 {
+		t.Fatalf("Expected the counters to keep their ttl")
+	}
+
+	// an expired counter starts over without a ttl
+	s.Setx("old", []byte("41"), SetArgs{ExpType: ExpirePXAT, ExpVal: int(time.Now().UnixMilli() - 1)})
+	if n, _ := s.Incrby("old", 1); n != 1 || s.PExpireTime("old") != -1 {
+		t.Fatalf("Expected an expired counter to start at 0, got %d", n)
+	}
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"sync/atomic"
+)
+
+type hashedName struct {
+	hash uint32
+	name string
+}
+
+// scanOrder caches the names of a collection sorted by hash, so a scan does
+// not sort the whole collection on every call. writers drop it when a name
+// is added or removed and the next scan sorts again. scans only hold the
+// read lock, hence the atomic.
+type scanOrder struct {
+	sorted atomic.Pointer[[]hashedName]
+}
+
+func (o *scanOrder) reset() {
+	if o.sorted.Load() != nil {
+		o.sorted.Store(nil)
+	}
+}
+
+func (o *scanOrder) get(names iter.Seq[string]) []hashedName {
+	if sorted := o.sorted.Load(); sorted != nil {
+		return *sorted
+	}
+	sorted := []hashedName{}
+	for name := range names {
+		sorted = append(sorted, hashedName{hashKey(name), name})
+	}
+	slices.SortFunc(sorted, func(a, b hashedName) int { return cmp.Compare(a.hash, b.hash) })
+	o.sorted.Store(&sorted)
+	return sorted
+}
+
+// scanNames is the cursor behind HSCAN, SSCAN and ZSCAN. names are visited in
+// the order of their hash and the cursor is the hash to resume from: unlike
+// an index it does not move when names are added or removed between calls,
+// so every name present during the whole scan is returned. names sharing a
+// hash are always returned together. MATCH is applied to the names of the
+// batch like redis does, a batch may come back empty with a non zero cursor.
+func scanNames(order *scanOrder, names iter.Seq[string], cursor uint64, count int, match string) (uint64, []string) {
+	sorted := order.get(names)
+	start, _ := slices.BinarySearchFunc(sorted, cursor, func(n hashedName, c uint64) int {
+		return cmp.Compare(uint64(n.hash), c)
+	})
+	candidates := sorted[start:]
+
+	count = max(count, 1)
+	out := []string{}
+	for i, c := range candidates {
+		if i >= count && c.hash != candidates[i-1].hash {
+			return uint64(c.hash), out
+		}
+		if match == "" || MatchGlob(match, c.name) {
+			out = append(out, c.name)
+		}
+	}
+	return 0, out
+}
+
+// MatchGlob reports whether s matches the glob style pattern of redis:
+// * and ? wildcards, [abc], [^abc] and [a-z] classes, \ escapes the next
+// character
+//
+// a * that fails to match is retried taking one more byte of s, only the
+// last * is ever retried so the cost stays O(len(pattern) * len(s))
+func MatchGlob(pattern, s string) bool {
+	p, i := 0, 0
+	star, starS := -1, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				star, starS = p, i
+				p++
+				continue
+			case '?':
+				p++
+				i++
+				continue
+			case '[':
+				if rest, ok := matchClass(pattern[p+1:], s[i]); ok {
+					p = len(pattern) - len(rest)
+					i++
+					continue
+				}
+			default:
+				c := p
+				if pattern[c] == '\\' && c+1 < len(pattern) {
+					c++
+				}
+				if pattern[c] == s[i] {
+					p = c + 1
+					i++
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		starS++
+		p, i = star+1, starS
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches c against the class that starts pattern, right after
+// the [, and returns the pattern after the closing ]
+func matchClass(pattern string, c byte) (string, bool) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	if not {
+		pattern = pattern[1:]
+	}
+	match := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			match = match || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			match = match || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			match = match || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:] // the ]
+	}
+	return pattern, match != not
+}
@@ -0,0 +1,508 @@
+package store
+
+import (
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// a set of integers only is kept as a sorted slice, the intset encoding of
+// redis, until it gets a member that is not an integer or grows past
+// setMaxIntsetEntries. it then switches to a map for good.
+const setMaxIntsetEntries = 512
+
+// bytes taken by a member of an intset
+const intsetEntrySize = 8
+
+type set struct {
+	ints    []int64
+	members map[string]struct{} // nil while the set is an intset
+	size    int64
+	scan    scanOrder
+}
+
+func newSet() *set {
+	return &set{}
+}
+
+func (s *set) Type() ValueType { return TypeSet }
+func (s *set) bytes() int64    { return s.size }
+
+func (s *set) Len() int {
+	if s.members != nil {
+		return len(s.members)
+	}
+	return len(s.ints)
+}
+
+func (s *set) items() [][]byte {
+	items := make([][]byte, 0, s.Len())
+	for m := range s.all() {
+		items = append(items, []byte(m))
+	}
+	return items
+}
+
+// intsetValue is the integer member stands for in an intset. only members
+// written like the integer is formatted qualify, so they come back unchanged.
+func intsetValue(member string) (int64, bool) {
+	n, err := strconv.ParseInt(member, 10, 64)
+	return n, err == nil && strconv.FormatInt(n, 10) == member
+}
+
+func (s *set) has(member string) bool {
+	if s.members != nil {
+		_, ok := s.members[member]
+		return ok
+	}
+	n, ok := intsetValue(member)
+	if !ok {
+		return false
+	}
+	_, found := slices.BinarySearch(s.ints, n)
+	return found
+}
+
+// add returns true when member is new
+func (s *set) add(member string) bool {
+	if s.members == nil {
+		if n, ok := intsetValue(member); ok {
+			i, found := slices.BinarySearch(s.ints, n)
+			if found {
+				return false
+			}
+			if len(s.ints) < setMaxIntsetEntries {
+				s.ints = slices.Insert(s.ints, i, n)
+				s.size += intsetEntrySize
+				s.scan.reset()
+				return true
+			}
+		}
+		s.convert()
+	}
+	if _, ok := s.members[member]; ok {
+		return false
+	}
+	s.members[member] = struct{}{}
+	s.size += int64(len(member) + elementOverhead)
+	s.scan.reset()
+	return true
+}
+
+func (s *set) remove(member string) bool {
+	if s.members != nil {
+		if _, ok := s.members[member]; !ok {
+			return false
+		}
+		delete(s.members, member)
+		s.size -= int64(len(member) + elementOverhead)
+		s.scan.reset()
+		return true
+	}
+	n, ok := intsetValue(member)
+	if !ok {
+		return false
+	}
+	i, found := slices.BinarySearch(s.ints, n)
+	if !found {
+		return false
+	}
+	s.ints = slices.Delete(s.ints, i, i+1)
+	s.size -= intsetEntrySize
+	s.scan.reset()
+	return true
+}
+
+// convert moves the members of an intset to the map encoding
+func (s *set) convert() {
+	s.members = make(map[string]struct{}, len(s.ints)+1)
+	s.size = 0
+	for _, n := range s.ints {
+		member := strconv.FormatInt(n, 10)
+		s.members[member] = struct{}{}
+		s.size += int64(len(member) + elementOverhead)
+	}
+	s.ints = nil
+}
+
+// all yields the members, in order for an intset
+func (s *set) all() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if s.members != nil {
+			for m := range s.members {
+				if !yield(m) {
+					return
+				}
+			}
+			return
+		}
+		for _, n := range s.ints {
+			if !yield(strconv.FormatInt(n, 10)) {
+				return
+			}
+		}
+	}
+}
+
+// readSet runs fn on the set at key under the read lock, fn is not called
+// when the key is missing
+func (s *InMemoryStore) readSet(key string, fn func(st *set)) error {
+	sh := s.getShard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	record, ok, err := sh.peek(key, TypeSet, time.Now().UnixMilli())
+	if ok {
+		fn(record.obj.(*set))
+	}
+	return err
+}
+
+// writeSet is writeHash for sets
+func (s *InMemoryStore) writeSet(key string, create bool, fn func(st *set) error) error {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	record, ok, err := sh.lookup(key, TypeSet, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if !create {
+			return nil
+		}
+		record = KVRecord{obj: newSet(), exp: -1}
+	}
+	err = fn(record.obj.(*set))
+	if ok || record.obj.Len() > 0 {
+		sh.update(key, record)
+	}
+	return err
+}
+
+// SAdd adds members and returns how many are new
+func (s *InMemoryStore) SAdd(key string, members []string) (int, error) {
+	added := 0
+	err := s.writeSet(key, true, func(st *set) error {
+		for _, m := range members {
+			if st.add(m) {
+				added++
+			}
+		}
+		return nil
+	})
+	return added, err
+}
+
+// SRem removes members and returns how many existed, the key is removed
+// with its last member
+func (s *InMemoryStore) SRem(key string, members []string) (int, error) {
+	removed := 0
+	err := s.writeSet(key, false, func(st *set) error {
+		for _, m := range members {
+			if st.remove(m) {
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+func (s *InMemoryStore) SIsMember(key, member string) (int, error) {
+	is := 0
+	err := s.readSet(key, func(st *set) {
+		if st.has(member) {
+			is = 1
+		}
+	})
+	return is, err
+}
+
+func (s *InMemoryStore) SMIsMember(key string, members []string) ([]int, error) {
+	are := make([]int, len(members))
+	err := s.readSet(key, func(st *set) {
+		for i, m := range members {
+			if st.has(m) {
+				are[i] = 1
+			}
+		}
+	})
+	return are, err
+}
+
+func (s *InMemoryStore) SMembers(key string) ([]string, error) {
+	members := []string{}
+	err := s.readSet(key, func(st *set) {
+		members = slices.AppendSeq(make([]string, 0, st.Len()), st.all())
+	})
+	return members, err
+}
+
+func (s *InMemoryStore) SCard(key string) (int, error) {
+	n := 0
+	err := s.readSet(key, func(st *set) {
+		n = st.Len()
+	})
+	return n, err
+}
+
+// SPop removes and returns up to count random members, nil when the key is
+// missing
+func (s *InMemoryStore) SPop(key string, count int) ([]string, error) {
+	var popped []string
+	err := s.writeSet(key, false, func(st *set) error {
+		popped = randomDistinct(st.Len(), count, st.random, st.all())
+		for _, m := range popped {
+			st.remove(m)
+		}
+		return nil
+	})
+	return popped, err
+}
+
+// SRandMember returns random members, see HRandField for count
+func (s *InMemoryStore) SRandMember(key string, count int) ([]string, error) {
+	var members []string
+	err := s.readSet(key, func(st *set) {
+		if count < 0 {
+			for range -count {
+				members = append(members, st.random())
+			}
+			return
+		}
+		members = randomDistinct(st.Len(), count, st.random, st.all())
+	})
+	return members, err
+}
+
+// random returns a member of a set that is not empty
+func (s *set) random() string {
+	if s.members != nil {
+		return randomKey(s.members)
+	}
+	return strconv.FormatInt(s.ints[rand.IntN(len(s.ints))], 10)
+}
+
+// randomKey returns a key of m, map iteration starts at a random position
+func randomKey[V any](m map[string]V) string {
+	for k := range m {
+		return k
+	}
+	return ""
+}
+
+// randomDistinct returns up to count distinct names out of the n of a
+// collection. a count that is a small part of n draws with pick until it has
+// enough, only a large one copies every name and shuffles them.
+func randomDistinct(n, count int, pick func() string, all iter.Seq[string]) []string {
+	count = min(count, n)
+	if count*3 > n {
+		names := slices.AppendSeq(make([]string, 0, n), all)
+		rand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+		return names[:count]
+	}
+	names := make([]string, 0, count)
+	seen := make(map[string]struct{}, count)
+	for len(names) < count {
+		name := pick()
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SMove moves member from the set at src to the one at dst, it returns 1
+// when member was in src
+func (s *InMemoryStore) SMove(src, dst, member string) (int, error) {
+	unlock := s.lockKeys([]string{src, dst})
+	defer unlock()
+
+	nowMs := time.Now().UnixMilli()
+	srcShard, dstShard := s.getShard(src), s.getShard(dst)
+	from, ok, err := srcShard.lookup(src, TypeSet, nowMs)
+	if !ok {
+		return 0, err
+	}
+	to, ok, err := dstShard.lookup(dst, TypeSet, nowMs)
+	if err != nil {
+		return 0, err
+	}
+	if src == dst {
+		if from.obj.(*set).has(member) {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	if !from.obj.(*set).remove(member) {
+		return 0, nil
+	}
+	if !ok {
+		to = KVRecord{obj: newSet(), exp: -1}
+	}
+	to.obj.(*set).add(member)
+	srcShard.update(src, from)
+	dstShard.update(dst, to)
+	return 1, nil
+}
+
+type setOp int
+
+const (
+	setInter setOp = iota
+	setUnion
+	setDiff
+)
+
+// lockedSets returns the sets at keys, nil for the missing ones. the caller
+// holds the write locks of the keys.
+func (s *InMemoryStore) lockedSets(keys []string) ([]*set, error) {
+	nowMs := time.Now().UnixMilli()
+	sets := make([]*set, len(keys))
+	for i, key := range keys {
+		record, ok, err := s.getShard(key).lookup(key, TypeSet, nowMs)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			sets[i] = record.obj.(*set)
+		}
+	}
+	return sets, nil
+}
+
+// combine applies op to sets, a nil set is empty. an intersection stops
+// once it has limit members, 0 for no limit.
+func combine(op setOp, sets []*set, limit int) []string {
+	out := []string{}
+	switch op {
+	case setInter:
+		if slices.Contains(sets, nil) {
+			return out
+		}
+		// the smallest set drives, every member of the result is in it
+		sets = slices.Clone(sets)
+		slices.SortFunc(sets, func(a, b *set) int { return a.Len() - b.Len() })
+		for m := range sets[0].all() {
+			if !slices.ContainsFunc(sets[1:], func(st *set) bool { return !st.has(m) }) {
+				out = append(out, m)
+				if len(out) == limit {
+					break
+				}
+			}
+		}
+	case setUnion:
+		seen := map[string]struct{}{}
+		for _, st := range sets {
+			if st == nil {
+				continue
+			}
+			for m := range st.all() {
+				if _, ok := seen[m]; !ok {
+					seen[m] = struct{}{}
+					out = append(out, m)
+				}
+			}
+		}
+	case setDiff:
+		if sets[0] == nil {
+			return out
+		}
+		for m := range sets[0].all() {
+			if !slices.ContainsFunc(sets[1:], func(st *set) bool { return st != nil && st.has(m) }) {
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+// setAlgebra returns the intersection, union or difference of the sets at
+// keys, a missing key is an empty set
+func (s *InMemoryStore) setAlgebra(op setOp, keys []string) ([]string, error) {
+	unlock := s.lockKeys(keys)
+	defer unlock()
+
+	sets, err := s.lockedSets(keys)
+	if err != nil {
+		return nil, err
+	}
+	return combine(op, sets, 0), nil
+}
+
+func (s *InMemoryStore) SInter(keys []string) ([]string, error) {
+	return s.setAlgebra(setInter, keys)
+}
+
+func (s *InMemoryStore) SUnion(keys []string) ([]string, error) {
+	return s.setAlgebra(setUnion, keys)
+}
+
+func (s *InMemoryStore) SDiff(keys []string) ([]string, error) {
+	return s.setAlgebra(setDiff, keys)
+}
+
+// SInterCard is the size of the intersection, counting stops at limit
+// unless it is 0
+func (s *InMemoryStore) SInterCard(keys []string, limit int) (int, error) {
+	unlock := s.lockKeys(keys)
+	defer unlock()
+
+	sets, err := s.lockedSets(keys)
+	if err != nil {
+		return 0, err
+	}
+	return len(combine(setInter, sets, limit)), nil
+}
+
+// setAlgebraStore stores the result of setAlgebra at dst, replacing whatever
+// it held, and returns its size. an empty result removes dst.
+func (s *InMemoryStore) setAlgebraStore(op setOp, dst string, keys []string) (int, error) {
+	unlock := s.lockKeys(append([]string{dst}, keys...))
+	defer unlock()
+
+	sets, err := s.lockedSets(keys)
+	if err != nil {
+		return 0, err
+	}
+	members := combine(op, sets, 0)
+	sh := s.getShard(dst)
+	if len(members) == 0 {
+		sh.remove(dst)
+		return 0, nil
+	}
+	st := newSet()
+	for _, m := range members {
+		st.add(m)
+	}
+	sh.put(dst, KVRecord{obj: st, exp: -1})
+	return len(members), nil
+}
+
+func (s *InMemoryStore) SInterStore(dst string, keys []string) (int, error) {
+	return s.setAlgebraStore(setInter, dst, keys)
+}
+
+func (s *InMemoryStore) SUnionStore(dst string, keys []string) (int, error) {
+	return s.setAlgebraStore(setUnion, dst, keys)
+}
+
+func (s *InMemoryStore) SDiffStore(dst string, keys []string) (int, error) {
+	return s.setAlgebraStore(setDiff, dst, keys)
+}
+
+// SScan returns a batch of members and the cursor of the next batch, 0 once
+// the scan is complete. see scanNames.
+func (s *InMemoryStore) SScan(key string, cursor uint64, match string, count int) (uint64, []string, error) {
+	next := uint64(0)
+	members := []string{}
+	err := s.readSet(key, func(st *set) {
+		next, members = scanNames(&st.scan, st.all(), cursor, count, match)
+	})
+	return next, members, err
+}
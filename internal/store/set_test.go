@@ -0,0 +1,196 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+func TestSetIntsetEncoding(t *testing.T) {
+	st := newSet()
+	for _, m := range []string{"10", "-3", "7", "10"} {
+		st.add(m)
+	}
+	if st.members != nil {
+		t.Fatalf("Expected integers to stay in an intset")
+	}
+	if got := slices.Collect(st.all()); !slices.Equal(got, []string{"-3", "7", "10"}) {
+		t.Errorf("Expected sorted members, got %q", got)
+	}
+	// not written like the integer, so kept as is in a map
+	for _, m := range []string{"007", "+1", "-0", "1.5"} {
+		if st.has(m) {
+			t.Errorf("Expected %q not to match an integer member", m)
+		}
+	}
+	st.add("007")
+	if st.members == nil || st.Len() != 4 || !st.has("007") || !st.has("7") {
+		t.Fatalf("Expected a non integer member to convert the set, got %v", st.members)
+	}
+
+	big := newSet()
+	for i := range setMaxIntsetEntries + 1 {
+		big.add(fmt.Sprint(i))
+	}
+	if big.members == nil || big.Len() != setMaxIntsetEntries+1 {
+		t.Errorf("Expected a set past %d integers to convert", setMaxIntsetEntries)
+	}
+}
+
+func TestSetMemoryAccounting(t *testing.T) {
+	s := NewInMemoryStore()
+	s.SAdd("s", []string{"1", "2", "3"})
+	s.SAdd("s", []string{"member"})
+	s.SRem("s", []string{"1"})
+	s.SPop("s", 2)
+	s.SMove("s", "t", "member")
+	s.SMove("s", "t", "2")
+	s.SMove("s", "t", "3")
+	s.SUnionStore("u", []string{"t", "missing"})
+	if n, _ := s.SCard("u"); n != 1 {
+		t.Fatalf("Expected the union to hold 1 member, got %d", n)
+	}
+	s.Del([]string{"t", "u"})
+	if s.UsedMemory() != 0 {
+		t.Errorf("Expected every byte to be released, %d left", s.UsedMemory())
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	s := NewInMemoryStore()
+	s.SAdd("a", []string{"x", "y", "z"})
+	s.SAdd("b", []string{"y", "z", "w"})
+	s.SAdd("c", []string{"z"})
+
+	sorted := func(members []string, err error) []string {
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		slices.Sort(members)
+		return members
+	}
+	if got := sorted(s.SInter([]string{"a", "b", "c"})); !slices.Equal(got, []string{"z"}) {
+		t.Errorf("Unexpected SINTER %q", got)
+	}
+	if got := sorted(s.SUnion([]string{"a", "b", "missing"})); !slices.Equal(got, []string{"w", "x", "y", "z"}) {
+		t.Errorf("Unexpected SUNION %q", got)
+	}
+	if got := sorted(s.SDiff([]string{"a", "b"})); !slices.Equal(got, []string{"x"}) {
+		t.Errorf("Unexpected SDIFF %q", got)
+	}
+	if n, _ := s.SInterCard([]string{"a", "b"}, 1); n != 1 {
+		t.Errorf("Expected SINTERCARD to stop at the limit, got %d", n)
+	}
+
+	s.Set("str", []byte("v"))
+	s.ExpireAt("a", 9999999999999)
+	if n, _ := s.SDiffStore("a", []string{"a", "c"}); n != 2 {
+		t.Errorf("Expected a diff of 2 into a source, got %d", n)
+	}
+	if s.PExpireTime("a") != -1 {
+		t.Errorf("Expected the stored set to have no ttl")
+	}
+	if _, err := s.SInterStore("dst", []string{"a", "str"}); !errors.Is(err, common.ErrWrongType) {
+		t.Errorf("Expected WRONGTYPE for a string source, got %v", err)
+	}
+	if n, _ := s.SInterStore("str", []string{"a", "missing"}); n != 0 || s.Exists([]string{"str"}) != 0 {
+		t.Errorf("Expected an empty result to remove the destination")
+	}
+}
+
+func TestSMove(t *testing.T) {
+	s := NewInMemoryStore()
+	s.SAdd("src", []string{"a"})
+	s.Set("str", []byte("v"))
+	if _, err := s.SMove("src", "str", "a"); !errors.Is(err, common.ErrWrongType) {
+		t.Errorf("Expected WRONGTYPE for the destination, got %v", err)
+	}
+	if n, _ := s.SMove("src", "src", "a"); n != 1 {
+		t.Errorf("Expected a move onto itself to report membership, got %d", n)
+	}
+	if n, err := s.SMove("missing", "str", "a"); n != 0 || err != nil {
+		t.Errorf("Expected 0 for a missing source, got %d, %v", n, err)
+	}
+	if n, _ := s.SMove("src", "dst", "a"); n != 1 || s.Exists([]string{"src"}) != 0 {
+		t.Errorf("Expected the emptied source to be removed")
+	}
+	if is, _ := s.SIsMember("dst", "a"); is != 1 {
+		t.Errorf("Expected the member to be moved")
+	}
+}
+
+func TestSPopSRandMember(t *testing.T) {
+	s := NewInMemoryStore()
+	s.SAdd("s", []string{"a", "b", "c"})
+	if got, _ := s.SRandMember("s", 5); len(got) != 3 {
+		t.Errorf("Expected distinct members capped at the size, got %q", got)
+	}
+	if got, _ := s.SRandMember("s", -5); len(got) != 5 {
+		t.Errorf("Expected 5 members with repeats, got %q", got)
+	}
+	popped, _ := s.SPop("s", 2)
+	if len(popped) != 2 {
+		t.Fatalf("Expected 2 popped members, got %q", popped)
+	}
+	for _, m := range popped {
+		if is, _ := s.SIsMember("s", m); is != 0 {
+			t.Errorf("Expected %q to be removed", m)
+		}
+	}
+	s.SPop("s", 5)
+	if got, _ := s.SPop("s", 1); got != nil {
+		t.Errorf("Expected nil from a missing set, got %q", got)
+	}
+}
+
+// small counts are drawn one by one instead of shuffling the whole set
+func TestSRandMemberDraws(t *testing.T) {
+	s := NewInMemoryStore()
+	ints, words := []string{}, []string{}
+	for i := range 100 {
+		ints = append(ints, strconv.Itoa(i))
+		words = append(words, fmt.Sprint("m", i))
+	}
+	s.SAdd("ints", ints)
+	s.SAdd("words", words)
+	for _, key := range []string{"ints", "words"} {
+		got, _ := s.SRandMember(key, 5)
+		seen := map[string]bool{}
+		for _, m := range got {
+			if is, _ := s.SIsMember(key, m); is != 1 || seen[m] {
+				t.Errorf("%s: expected 5 distinct members, got %q", key, got)
+			}
+			seen[m] = true
+		}
+		if len(got) != 5 {
+			t.Errorf("%s: expected 5 members, got %q", key, got)
+		}
+
+		drawn := map[string]bool{}
+		for range 50 {
+			popped, _ := s.SPop(key, 1)
+			drawn[popped[0]] = true
+		}
+		if n, _ := s.SCard(key); n != 50 || len(drawn) != 50 {
+			t.Errorf("%s: expected 50 distinct pops, got %d left and %d popped", key, n, len(drawn))
+		}
+	}
+}
+
+func TestType(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Set("str", []byte("v"))
+	s.HSet("h", []string{"f"}, [][]byte{[]byte("v")})
+	s.RPush("l", [][]byte{[]byte("v")})
+	s.SAdd("s", []string{"v"})
+	s.Restore(Entry{Key: "old", Value: []byte("v"), Exp: 1})
+	for key, want := range map[string]string{"str": "string", "h": "hash", "l": "list", "s": "set", "old": "none", "missing": "none"} {
+		if got := s.Type(key); got != want {
+			t.Errorf("Type(%q): expected %s, got %s", key, want, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// the keyspace of every database is split into shardCount lock-striped maps,
+// a key always lives in the shard picked by the hash of its name
+const shardCount = 32
+
+type shard struct {
+	mu   sync.RWMutex
+	data map[string]KVRecord
+	// index of the keys carrying a ttl, ordered by expire time
+	expires *ttlHeap
+	// estimated bytes used by the records, shared by the shards of a store
+	used *atomic.Int64
+}
+
+func newShard(used *atomic.Int64) *shard {
+	return &shard{
+		data:    make(map[string]KVRecord),
+		expires: newTTLHeap(),
+		used:    used,
+	}
+}
+
+// rough per record overhead: map bucket slot, KVRecord header, heap entry
+const recordOverhead = 64
+
+func recordSize(key string, record KVRecord) int64 {
+	size := int64(len(key) + len(record.Value) + recordOverhead)
+	if record.obj != nil {
+		size += record.obj.bytes()
+	}
+	return size
+}
+
+// fnv-1a, inlined to avoid allocating a hash.Hash32 on every lookup
+func hashKey(key string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= prime32
+	}
+	return h
+}
+
+func (s *InMemoryStore) shardIndex(key string) int {
+	return int(hashKey(key) % uint32(len(s.shards)))
+}
+
+func (s *InMemoryStore) getShard(key string) *shard {
+	return s.shards[s.shardIndex(key)]
+}
+
+// lockKeys write-locks every shard touched by keys, always in ascending shard
+// order so that two multi-key operations can never deadlock each other.
+// the returned func releases the locks.
+func (s *InMemoryStore) lockKeys(keys []string) func() {
+	idx := make([]int, 0, len(keys))
+	seen := make(map[int]bool, len(keys))
+	for _, key := range keys {
+		i := s.shardIndex(key)
+		if !seen[i] {
+			seen[i] = true
+			idx = append(idx, i)
+		}
+	}
+	sort.Ints(idx)
+	for _, i := range idx {
+		s.shards[i].mu.Lock()
+	}
+	return func() {
+		for j := len(idx) - 1; j >= 0; j-- {
+			s.shards[idx[j]].mu.Unlock()
+		}
+	}
+}
+
+// deleteIfExpired re-checks the record under the write lock, another
+// goroutine may have overwritten the key since it was read
+func (sh *shard) deleteIfExpired(key string, nowMs int64) bool {
+	if record, ok := sh.data[key]; ok && record.exp != -1 && record.exp <= nowMs {
+		sh.expire(key)
+		return true
+	}
+	return false
+}
+
+// live returns the record of key unless it is missing or expired, an
+// expired record is removed on the way. callers must hold the write lock.
+func (sh *shard) live(key string, nowMs int64) (KVRecord, bool) {
+	if sh.deleteIfExpired(key, nowMs) {
+		return KVRecord{}, false
+	}
+	record, ok := sh.data[key]
+	return record, ok
+}
+
+// put and remove are the only places that write to data, they keep the
+// expires index and the memory accounting in sync with the records. the size
+// is cached in the record, objects changed in place are accounted again when
+// put back. callers must hold the write lock.
+func (sh *shard) put(key string, record KVRecord) {
+	if old, ok := sh.data[key]; ok {
+		sh.used.Add(-old.size)
+	}
+	record.size = recordSize(key, record)
+	sh.used.Add(record.size)
+	sh.data[key] = record
+	if record.exp != -1 {
+		sh.expires.set(key, record.exp)
+	} else {
+		sh.expires.remove(key)
+	}
+}
+
+func (sh *shard) remove(key string) {
+	if old, ok := sh.data[key]; ok {
+		sh.used.Add(-old.size)
+	}
+	delete(sh.data, key)
+	sh.expires.remove(key)
+}
+
+// expireDue removes up to limit keys whose ttl is <= nowMs, in expire order
+func (sh *shard) expireDue(nowMs int64, limit int) int {
+	n := 0
+	for n < limit {
+		e, ok := sh.expires.peek()
+		if !ok || e.exp > nowMs {
+			break
+		}
+		sh.expire(e.key)
+		n++
+	}
+	return n
+}
+
+// expire removes a key whose ttl has passed and accounts for it in the stats
+func (sh *shard) expire(key string) {
+	sh.remove(key)
+	stats.expiredKeys.Add(1)
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestShardedStoreConcurrentAccess(t *testing.T) {
+	mem := NewInMemoryStore()
+	const workers = 32
+	const ops = 500
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < ops; i++ {
+				key := "key:" + strconv.Itoa(i%64)
+				switch i % 8 {
+				case 0:
+					mem.Set(key, []byte(strconv.Itoa(w)))
+				case 1:
+					mem.Setx(key, []byte("x"), SetArgs{ExpType: ExpirePX, ExpVal: 1})
+				case 2:
+					mem.Get(key)
+				case 3:
+					mem.Del([]string{key, "key:" + strconv.Itoa((i+1)%64)})
+				case 4:
+					mem.Incrby("counter:"+strconv.Itoa(w%4), 1)
+				case 5:
+					mem.Expire(key, 10)
+				case 6:
+					mem.TTL(key)
+				case 7:
+					mem.GetAllKeys()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func TestShardedStoreDistributesKeys(t *testing.T) {
+	mem := NewInMemoryStore()
+	for i := 0; i < 1000; i++ {
+		mem.Set("key:"+strconv.Itoa(i), []byte("v"))
+	}
+
+	used := 0
+	for _, sh := range mem.shards {
+		if len(sh.data) > 0 {
+			used++
+		}
+	}
+	if used != shardCount {
+		t.Errorf("Expected keys in all %d shards, got %d", shardCount, used)
+	}
+	if n := len(mem.GetAllKeys()); n != 1000 {
+		t.Errorf("Expected 1000 keys, got %d", n)
+	}
+}
+
+func TestLockKeysSameShardTwice(t *testing.T) {
+	mem := NewInMemoryStore()
+	// the same key twice must not self-deadlock
+	unlock := mem.lockKeys([]string{"a", "a", "b"})
+	unlock()
+	if n := mem.Del([]string{"a", "a"}); n != 0 {
+		t.Errorf("Expected 0 deleted, got %d", n)
+	}
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"slices"
+	"time"
+)
+
+// Entry is a detached copy of a record, used by persistence to serialize
+// and restore databases. Exp is an absolute unix time in ms, -1 for no ttl.
+// strings are kept in Value, the other types in Items in the flat form of
+// their object: field value pairs for hashes, the elements of lists and sets,
+// score member pairs for sorted sets.
+type Entry struct {
+	Key   string
+	Type  ValueType
+	Value []byte
+	Items [][]byte
+	Exp   int64
+}
+
+// SnapshotAll copies every live record of every database. shards are
+// read-locked one at a time while their records are copied, so writers only
+// ever wait for the copy of one shard: the copy is a point in time per shard,
+// a caller that needs one across the keyspace has to hold writers off itself,
+// like the AOF rewrite does. string values are never mutated in place by the
+// store, so sharing their backing arrays is safe, objects are flattened into
+// Items instead.
+func SnapshotAll(stores []*InMemoryStore) [][]Entry {
+	nowMs := time.Now().UnixMilli()
+	dbs := make([][]Entry, len(stores))
+	for i, s := range stores {
+		var entries []Entry
+		for _, sh := range s.shards {
+			entries = sh.snapshot(entries, nowMs)
+		}
+		dbs[i] = entries
+	}
+	return dbs
+}
+
+// snapshot appends the live records of the shard to entries
+func (sh *shard) snapshot(entries []Entry, nowMs int64) []Entry {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	entries = slices.Grow(entries, len(sh.data))
+	for k, rec := range sh.data {
+		if rec.exp != -1 && rec.exp <= nowMs {
+			continue
+		}
+		e := Entry{Key: k, Type: rec.valueType(), Value: rec.Value, Exp: rec.exp}
+		if rec.obj != nil {
+			e.Items = rec.obj.items()
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Restore writes the record of an entry with its absolute expire time as-is,
+// it is meant for loading persisted data and bypasses the SET option handling
+func (s *InMemoryStore) Restore(e Entry) {
+	record := KVRecord{Value: e.Value, exp: e.Exp}
+	if e.Type != TypeString {
+		record = KVRecord{obj: newObject(e.Type, e.Items), exp: e.Exp}
+		if record.obj == nil || record.obj.Len() == 0 {
+			return
+		}
+	}
+
+	sh := s.getShard(e.Key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.put(e.Key, record)
+}
+
+// Flush removes every key of the database
+func (s *InMemoryStore) Flush() {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		freed := int64(0)
+		for _, rec := range sh.data {
+			freed += rec.size
+		}
+		sh.used.Add(-freed)
+		sh.data = make(map[string]KVRecord)
+		sh.expires = newTTLHeap()
+		sh.mu.Unlock()
+	}
+}
@@ -0,0 +1,70 @@
+package store
+
+import "container/heap"
+
+// ttlHeap is the per shard expiry index: a min-heap of every key carrying a
+// ttl ordered by its absolute expire time. pos maps a key to its slot in the
+// heap so updates and removals are O(log n) as well.
+
+type ttlEntry struct {
+	key string
+	exp int64
+}
+
+type ttlHeap struct {
+	entries []ttlEntry
+	pos     map[string]int
+}
+
+func newTTLHeap() *ttlHeap {
+	return &ttlHeap{
+		pos: make(map[string]int),
+	}
+}
+
+// heap.Interface, not meant to be called directly
+
+func (h *ttlHeap) Len() int           { return len(h.entries) }
+func (h *ttlHeap) Less(i, j int) bool { return h.entries[i].exp < h.entries[j].exp }
+func (h *ttlHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.pos[h.entries[i].key] = i
+	h.pos[h.entries[j].key] = j
+}
+func (h *ttlHeap) Push(x any) {
+	e := x.(ttlEntry)
+	h.pos[e.key] = len(h.entries)
+	h.entries = append(h.entries, e)
+}
+func (h *ttlHeap) Pop() any {
+	n := len(h.entries)
+	e := h.entries[n-1]
+	h.entries[n-1] = ttlEntry{}
+	h.entries = h.entries[:n-1]
+	delete(h.pos, e.key)
+	return e
+}
+
+// set adds the key or moves it to its new expire time
+func (h *ttlHeap) set(key string, exp int64) {
+	if i, ok := h.pos[key]; ok {
+		h.entries[i].exp = exp
+		heap.Fix(h, i)
+		return
+	}
+	heap.Push(h, ttlEntry{key: key, exp: exp})
+}
+
+func (h *ttlHeap) remove(key string) {
+	if i, ok := h.pos[key]; ok {
+		heap.Remove(h, i)
+	}
+}
+
+// peek returns the key that expires first, ok is false on an empty heap
+func (h *ttlHeap) peek() (ttlEntry, bool) {
+	if len(h.entries) == 0 {
+		return ttlEntry{}, false
+	}
+	return h.entries[0], true
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTTLHeapOrder(t *testing.T) {
+	h := newTTLHeap()
+	h.set("c", 30)
+	h.set("a", 10)
+	h.set("b", 20)
+	h.set("d", 5)
+	h.remove("d")
+	h.set("c", 1) // move c to the front
+
+	want := []string{"c", "a", "b"}
+	for _, key := range want {
+		e, ok := h.peek()
+		if !ok || e.key != key {
+			t.Fatalf("Expected %q at the top, got %q", key, e.key)
+		}
+		h.remove(e.key)
+	}
+	if _, ok := h.peek(); ok {
+		t.Errorf("Expected empty heap")
+	}
+	if len(h.pos) != 0 {
+		t.Errorf("Expected empty position map, got %v", h.pos)
+	}
+}
+
+func TestExpiryIndexFollowsWrites(t *testing.T) {
+	mem := NewInMemoryStore()
+	for i := 0; i < 100; i++ {
+		mem.Setx("k"+strconv.Itoa(i), []byte("v"), SetArgs{ExpType: ExpireEX, ExpVal: 100})
+	}
+	if n := mem.ExpiresCount(); n != 100 {
+		t.Fatalf("Expected 100 keys with ttl, got %d", n)
+	}
+
+	mem.Del([]string{"k0", "k1"})
+	mem.Persist("k2")
+	mem.Set("k3", []byte("plain"))
+	mem.Setx("k4", []byte("v"), SetArgs{KeepTTL: true})
+	if n := mem.ExpiresCount(); n != 96 {
+		t.Errorf("Expected 96 keys with ttl, got %d", n)
+	}
+}
+
+func TestExpireDueStopsAtFirstFutureKey(t *testing.T) {
+	mem := NewInMemoryStore()
+	nowMs := time.Now().UnixMilli()
+	mem.Setx("past", []byte("v"), SetArgs{ExpType: ExpirePXAT, ExpVal: int(nowMs - 10)})
+	mem.Setx("future", []byte("v"), SetArgs{ExpType: ExpirePXAT, ExpVal: int(nowMs + 60_000)})
+
+	total := 0
+	for _, sh := range mem.shards {
+		sh.mu.Lock()
+		total += sh.expireDue(nowMs, 10)
+		sh.mu.Unlock()
+	}
+	if total != 1 {
+		t.Errorf("Expected 1 due key, got %d", total)
+	}
+	if v, _ := mem.Get("future"); string(v) != "v" {
+		t.Errorf("Expected future key to survive, got %q", v)
+	}
+}
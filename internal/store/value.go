@@ -0,0 +1,121 @@
+package store
+
+import "github.com/B-AJ-Amar/gokv/internal/common"
+
+// a key holds either a string, kept as the plain Value of its record, or an
+// object of one of the aggregate types. objects are mutated in place under
+// the write lock of their shard, so unlike strings they are never shared
+// outside of it: readers get copies.
+
+type ValueType uint8
+
+const (
+	TypeString ValueType = iota
+	TypeHash
+	TypeList
+	TypeSet
+	TypeZSet
+)
+
+// String is the name TYPE replies for t
+func (t ValueType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeHash:
+		return "hash"
+	case TypeList:
+		return "list"
+	case TypeSet:
+		return "set"
+	case TypeZSet:
+		return "zset"
+	}
+	return "unknown"
+}
+
+type object interface {
+	Type() ValueType
+	Len() int
+	// bytes held by the elements, for the memory accounting
+	bytes() int64
+	// items is the flat form of the object used by persistence, see Entry
+	items() [][]byte
+}
+
+// newObject rebuilds an object of type t from its flat form
+func newObject(t ValueType, items [][]byte) object {
+	switch t {
+	case TypeHash:
+		h := newHash()
+		for i := 0; i+1 < len(items); i += 2 {
+			h.set(string(items[i]), items[i+1])
+		}
+		return h
+	case TypeList:
+		l := newList()
+		for _, item := range items {
+			l.pushBack(item)
+		}
+		return l
+	case TypeSet:
+		st := newSet()
+		for _, item := range items {
+			st.add(string(item))
+		}
+		return st
+	case TypeZSet:
+		z := newZSet()
+		for i := 0; i+1 < len(items); i += 2 {
+			if score, err := ParseFloat(items[i]); err == nil {
+				z.put(string(items[i+1]), score)
+			}
+		}
+		return z
+	}
+	return nil
+}
+
+func (r KVRecord) valueType() ValueType {
+	if r.obj == nil {
+		return TypeString
+	}
+	return r.obj.Type()
+}
+
+// peek returns the record of key unless it is missing or expired, without
+// removing anything so a read lock is enough. a record of another type than
+// t is ErrWrongType.
+func (sh *shard) peek(key string, t ValueType, nowMs int64) (KVRecord, bool, error) {
+	record, ok := sh.data[key]
+	if !ok || (record.exp != -1 && record.exp <= nowMs) {
+		return KVRecord{}, false, nil
+	}
+	if record.valueType() != t {
+		return KVRecord{}, false, common.ErrWrongType
+	}
+	return record, true, nil
+}
+
+// lookup is peek for writers: an expired record is removed on the way.
+// callers must hold the write lock.
+func (sh *shard) lookup(key string, t ValueType, nowMs int64) (KVRecord, bool, error) {
+	record, ok := sh.live(key, nowMs)
+	if !ok {
+		return KVRecord{}, false, nil
+	}
+	if record.valueType() != t {
+		return KVRecord{}, false, common.ErrWrongType
+	}
+	return record, true, nil
+}
+
+// update stores a record whose object was changed in place, or removes it
+// once the object is empty like redis does
+func (sh *shard) update(key string, record KVRecord) {
+	if record.obj.Len() == 0 {
+		sh.remove(key)
+		return
+	}
+	sh.put(key, record)
+}
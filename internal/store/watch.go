@@ -0,0 +1,64 @@
+package store
+
+import "sync"
+
+// Watcher is how a client blocked on keys, by BLPOP and friends, learns that
+// data may have arrived: C receives a value after a write made one of the
+// keys grow. every watcher of the key is woken up and they race for the
+// data, so a wakeup is only a hint to try again. C is buffered, a signal
+// sent while nobody waits is not lost.
+type Watcher struct {
+	C    chan struct{}
+	keys []string
+	reg  *watchers
+}
+
+// watchers indexes the Watchers of a database by key
+type watchers struct {
+	mu   sync.Mutex
+	keys map[string]map[*Watcher]struct{}
+}
+
+func newWatchers() *watchers {
+	return &watchers{keys: make(map[string]map[*Watcher]struct{})}
+}
+
+// Watch registers a Watcher on keys, Stop must be called once done with it
+func (s *InMemoryStore) Watch(keys []string) *Watcher {
+	w := &Watcher{C: make(chan struct{}, 1), keys: keys, reg: s.watchers}
+	s.watchers.mu.Lock()
+	defer s.watchers.mu.Unlock()
+	for _, key := range keys {
+		set, ok := s.watchers.keys[key]
+		if !ok {
+			set = make(map[*Watcher]struct{})
+			s.watchers.keys[key] = set
+		}
+		set[w] = struct{}{}
+	}
+	return w
+}
+
+func (w *Watcher) Stop() {
+	w.reg.mu.Lock()
+	defer w.reg.mu.Unlock()
+	for _, key := range w.keys {
+		delete(w.reg.keys[key], w)
+		if len(w.reg.keys[key]) == 0 {
+			delete(w.reg.keys, key)
+		}
+	}
+}
+
+// signal wakes up the watchers of key, it never blocks so it can be called
+// with shard locks held
+func (ws *watchers) signal(key string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for w := range ws.keys[key] {
+		select {
+		case w.C <- struct{}{}:
+		default:
+		}
+	}
+}
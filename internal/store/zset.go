@@ -0,0 +1,575 @@
+package store
+
+import (
+	"iter"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+// zset is a sorted set: the dict gives the score of a member in O(1), the
+// skiplist keeps the members in order for ranks and ranges
+type zset struct {
+	dict map[string]float64
+	zsl  *skiplist
+	size int64
+	scan scanOrder
+}
+
+// ScoredMember is a member of a sorted set with its score
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+func newZSet() *zset {
+	return &zset{dict: make(map[string]float64), zsl: newSkiplist()}
+}
+
+func (z *zset) Type() ValueType { return TypeZSet }
+func (z *zset) Len() int        { return len(z.dict) }
+func (z *zset) bytes() int64    { return z.size }
+
+// items are score member pairs in order, the arguments ZADD takes
+func (z *zset) items() [][]byte {
+	items := make([][]byte, 0, 2*len(z.dict))
+	for x := z.zsl.head.level[0].forward; x != nil; x = x.level[0].forward {
+		items = append(items, strconv.AppendFloat(nil, x.score, 'g', -1, 64), []byte(x.member))
+	}
+	return items
+}
+
+func zsetEntrySize(member string) int64 {
+	return int64(len(member) + 8 + elementOverhead)
+}
+
+// put sets the score of member, adding it when missing
+func (z *zset) put(member string, score float64) {
+	if old, ok := z.dict[member]; ok {
+		if old == score {
+			return
+		}
+		z.zsl.delete(old, member)
+	} else {
+		z.size += zsetEntrySize(member)
+		z.scan.reset()
+	}
+	z.dict[member] = score
+	z.zsl.insert(score, member)
+}
+
+func (z *zset) del(member string) bool {
+	score, ok := z.dict[member]
+	if ok {
+		z.zsl.delete(score, member)
+		delete(z.dict, member)
+		z.size -= zsetEntrySize(member)
+		z.scan.reset()
+	}
+	return ok
+}
+
+// pop removes up to count members from the lowest scores, or the highest
+func (z *zset) pop(count int, highest bool) []ScoredMember {
+	popped := make([]ScoredMember, 0, min(count, z.Len()))
+	for len(popped) < count && z.Len() > 0 {
+		x := z.zsl.head.level[0].forward
+		if highest {
+			x = z.zsl.tail
+		}
+		popped = append(popped, ScoredMember{x.member, x.score})
+		z.del(x.member)
+	}
+	return popped
+}
+
+// step moves to the next node, or the previous one with rev
+func (x *skiplistNode) step(rev bool) *skiplistNode {
+	if rev {
+		return x.backward
+	}
+	return x.level[0].forward
+}
+
+// ZRangeBy is what ZRANGE walks: ranks, scores or members
+type ZRangeBy int
+
+const (
+	ZRangeByRank ZRangeBy = iota
+	ZRangeByScore
+	ZRangeByLex
+)
+
+// ZRangeQuery describes a ZRANGE. Start and Stop are the ranks of
+// ZRangeByRank, negative ones count from the end, Score and Lex the ranges
+// of the other kinds. Rev walks from the highest member down. Offset and
+// Count are the LIMIT of score and lex ranges: a negative Offset selects
+// nothing, a negative Count everything after it.
+type ZRangeQuery struct {
+	By          ZRangeBy
+	Start, Stop int
+	Score       ScoreRange
+	Lex         LexRange
+	Rev         bool
+	Offset      int
+	Count       int
+}
+
+func (z *zset) rangeOf(q ZRangeQuery) []ScoredMember {
+	out := []ScoredMember{}
+	if q.By == ZRangeByRank {
+		start, stop := listRange(q.Start, q.Stop, z.Len())
+		if start > stop {
+			return out
+		}
+		x := z.zsl.byRank(start + 1)
+		if q.Rev {
+			x = z.zsl.byRank(z.Len() - start)
+		}
+		for i := start; i <= stop; i++ {
+			out = append(out, ScoredMember{x.member, x.score})
+			x = x.step(q.Rev)
+		}
+		return out
+	}
+
+	if q.Offset < 0 {
+		return out
+	}
+	var r rangeSpec = q.Score
+	if q.By == ZRangeByLex {
+		r = q.Lex
+	}
+	x := z.zsl.first(r)
+	if q.Rev {
+		x = z.zsl.last(r)
+	}
+	for offset := q.Offset; x != nil && offset > 0; offset-- {
+		x = x.step(q.Rev)
+	}
+	for ; x != nil && (q.Count < 0 || len(out) < q.Count); x = x.step(q.Rev) {
+		if (q.Rev && !r.aboveMin(x)) || (!q.Rev && !r.belowMax(x)) {
+			break
+		}
+		out = append(out, ScoredMember{x.member, x.score})
+	}
+	return out
+}
+
+// readZSet runs fn on the sorted set at key under the read lock, fn is not
+// called when the key is missing
+func (s *InMemoryStore) readZSet(key string, fn func(z *zset)) error {
+	sh := s.getShard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	record, ok, err := sh.peek(key, TypeZSet, time.Now().UnixMilli())
+	if ok {
+		fn(record.obj.(*zset))
+	}
+	return err
+}
+
+// writeZSet is writeList for sorted sets, BZPOPMIN waits on them
+func (s *InMemoryStore) writeZSet(key string, create bool, fn func(z *zset) error) error {
+	sh := s.getShard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	record, ok, err := sh.lookup(key, TypeZSet, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if !create {
+			return nil
+		}
+		record = KVRecord{obj: newZSet(), exp: -1}
+	}
+	z := record.obj.(*zset)
+	before := z.Len()
+	err = fn(z)
+	if ok || z.Len() > 0 {
+		sh.update(key, record)
+	}
+	if z.Len() > before {
+		s.watchers.signal(key)
+	}
+	return err
+}
+
+// ZAddArgs are the conditions of ZADD: NX only adds, XX only updates, GT and
+// LT only update to a greater or lower score
+type ZAddArgs struct {
+	NX, XX, GT, LT bool
+}
+
+// allows reports whether a member may get score, cur is its current score
+// when it exists
+func (a ZAddArgs) allows(exists bool, cur, score float64) bool {
+	if !exists {
+		return !a.XX
+	}
+	return !a.NX && !(a.GT && score <= cur) && !(a.LT && score >= cur)
+}
+
+// ZAdd adds or updates members under the conditions of args and returns how
+// many were added and how many existing ones got a new score
+func (s *InMemoryStore) ZAdd(key string, args ZAddArgs, members []ScoredMember) (int, int, error) {
+	added, updated := 0, 0
+	err := s.writeZSet(key, !args.XX, func(z *zset) error {
+		for _, m := range members {
+			cur, ok := z.dict[m.Member]
+			if !args.allows(ok, cur, m.Score) {
+				continue
+			}
+			switch {
+			case !ok:
+				added++
+			case cur != m.Score:
+				updated++
+			}
+			z.put(m.Member, m.Score)
+		}
+		return nil
+	})
+	return added, updated, err
+}
+
+// ZAddIncr adds incr to the score of member, a missing one counts as 0, and
+// returns the new score. false means the conditions of args prevented it.
+func (s *InMemoryStore) ZAddIncr(key string, args ZAddArgs, member string, incr float64) (float64, bool, error) {
+	score, applied := 0.0, false
+	err := s.writeZSet(key, !args.XX, func(z *zset) error {
+		cur, ok := z.dict[member]
+		if (ok && args.NX) || (!ok && args.XX) {
+			return nil
+		}
+		score = cur + incr
+		if math.IsNaN(score) {
+			return common.ErrScoreNaN
+		}
+		if applied = args.allows(ok, cur, score); applied {
+			z.put(member, score)
+		}
+		return nil
+	})
+	return score, applied, err
+}
+
+// ZIncrBy is ZAddIncr without conditions
+func (s *InMemoryStore) ZIncrBy(key, member string, incr float64) (float64, error) {
+	score, _, err := s.ZAddIncr(key, ZAddArgs{}, member, incr)
+	return score, err
+}
+
+// ZRem removes members and returns how many existed, the key is removed
+// with its last member
+func (s *InMemoryStore) ZRem(key string, members []string) (int, error) {
+	removed := 0
+	err := s.writeZSet(key, false, func(z *zset) error {
+		for _, m := range members {
+			if z.del(m) {
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// ZScore returns the score of member, false when it or the key is missing
+func (s *InMemoryStore) ZScore(key, member string) (float64, bool, error) {
+	score, ok := 0.0, false
+	err := s.readZSet(key, func(z *zset) {
+		score, ok = z.dict[member]
+	})
+	return score, ok, err
+}
+
+// ZMScore returns the scores of members, found tells which ones exist
+func (s *InMemoryStore) ZMScore(key string, members []string) ([]float64, []bool, error) {
+	scores := make([]float64, len(members))
+	found := make([]bool, len(members))
+	err := s.readZSet(key, func(z *zset) {
+		for i, m := range members {
+			scores[i], found[i] = z.dict[m]
+		}
+	})
+	return scores, found, err
+}
+
+func (s *InMemoryStore) ZCard(key string) (int, error) {
+	n := 0
+	err := s.readZSet(key, func(z *zset) {
+		n = z.Len()
+	})
+	return n, err
+}
+
+// ZCount is the number of members with a score in r
+func (s *InMemoryStore) ZCount(key string, r ScoreRange) (int, error) {
+	n := 0
+	err := s.readZSet(key, func(z *zset) {
+		first := z.zsl.first(r)
+		if first == nil {
+			return
+		}
+		last := z.zsl.last(r)
+		n = z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+	})
+	return n, err
+}
+
+// ZRank returns the 0-based rank of member, from the highest score with
+// rev. false when it or the key is missing.
+func (s *InMemoryStore) ZRank(key, member string, rev bool) (int, bool, error) {
+	rank, ok := 0, false
+	err := s.readZSet(key, func(z *zset) {
+		var score float64
+		if score, ok = z.dict[member]; !ok {
+			return
+		}
+		rank = z.zsl.rank(score, member) - 1
+		if rev {
+			rank = z.Len() - 1 - rank
+		}
+	})
+	return rank, ok, err
+}
+
+// ZRange returns the members q asks for, in order
+func (s *InMemoryStore) ZRange(key string, q ZRangeQuery) ([]ScoredMember, error) {
+	members := []ScoredMember{}
+	err := s.readZSet(key, func(z *zset) {
+		members = z.rangeOf(q)
+	})
+	return members, err
+}
+
+// storeZSet replaces whatever dst held with a sorted set of members and
+// returns its size, an empty one removes dst. the caller holds the lock of
+// dst.
+func (s *InMemoryStore) storeZSet(dst string, members iter.Seq2[string, float64]) int {
+	z := newZSet()
+	for m, score := range members {
+		z.put(m, score)
+	}
+	sh := s.getShard(dst)
+	if z.Len() == 0 {
+		sh.remove(dst)
+		return 0
+	}
+	sh.put(dst, KVRecord{obj: z, exp: -1})
+	s.watchers.signal(dst)
+	return z.Len()
+}
+
+// ZRangeStore stores the result of ZRange on src at dst and returns its size
+func (s *InMemoryStore) ZRangeStore(dst, src string, q ZRangeQuery) (int, error) {
+	unlock := s.lockKeys([]string{dst, src})
+	defer unlock()
+
+	record, ok, err := s.getShard(src).lookup(src, TypeZSet, time.Now().UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	var members []ScoredMember
+	if ok {
+		members = record.obj.(*zset).rangeOf(q)
+	}
+	return s.storeZSet(dst, func(yield func(string, float64) bool) {
+		for _, m := range members {
+			if !yield(m.Member, m.Score) {
+				return
+			}
+		}
+	}), nil
+}
+
+// ZPopMin removes and returns up to count members with the lowest scores,
+// nil when the key is missing
+func (s *InMemoryStore) ZPopMin(key string, count int) ([]ScoredMember, error) {
+	return s.zpop(key, count, false)
+}
+
+// ZPopMax is ZPopMin from the highest scores
+func (s *InMemoryStore) ZPopMax(key string, count int) ([]ScoredMember, error) {
+	return s.zpop(key, count, true)
+}
+
+func (s *InMemoryStore) zpop(key string, count int, highest bool) ([]ScoredMember, error) {
+	var popped []ScoredMember
+	err := s.writeZSet(key, false, func(z *zset) error {
+		popped = z.pop(count, highest)
+		return nil
+	})
+	return popped, err
+}
+
+// ZPopFirst pops the lowest, or highest, member of the first non empty
+// sorted set of keys and returns its key. the key is empty when every
+// sorted set is.
+func (s *InMemoryStore) ZPopFirst(keys []string, highest bool) (string, ScoredMember, error) {
+	for _, key := range keys {
+		popped, err := s.zpop(key, 1, highest)
+		if err != nil {
+			return "", ScoredMember{}, err
+		}
+		if len(popped) > 0 {
+			return key, popped[0], nil
+		}
+	}
+	return "", ScoredMember{}, nil
+}
+
+// ZAggregate is how ZUNIONSTORE and ZINTERSTORE combine the scores of a
+// member found in several sets
+type ZAggregate int
+
+const (
+	ZAggregateSum ZAggregate = iota
+	ZAggregateMin
+	ZAggregateMax
+)
+
+func (agg ZAggregate) apply(a, b float64) float64 {
+	switch agg {
+	case ZAggregateMin:
+		return min(a, b)
+	case ZAggregateMax:
+		return max(a, b)
+	}
+	// inf + -inf
+	if sum := a + b; !math.IsNaN(sum) {
+		return sum
+	}
+	return 0
+}
+
+// scoredSource is an input of ZUNIONSTORE and ZINTERSTORE, a plain set
+// counts as a sorted set whose scores are all 1
+type scoredSource struct {
+	z      *zset
+	st     *set
+	weight float64
+}
+
+func (src scoredSource) len() int {
+	switch {
+	case src.z != nil:
+		return src.z.Len()
+	case src.st != nil:
+		return src.st.Len()
+	}
+	return 0
+}
+
+// score is the weighted score of member, 0 instead of NaN for 0 * inf
+func (src scoredSource) score(member string) (float64, bool) {
+	score, ok := 0.0, false
+	switch {
+	case src.z != nil:
+		score, ok = src.z.dict[member]
+	case src.st != nil:
+		score, ok = 1, src.st.has(member)
+	}
+	if score *= src.weight; math.IsNaN(score) {
+		score = 0
+	}
+	return score, ok
+}
+
+func (src scoredSource) members() iter.Seq[string] {
+	switch {
+	case src.z != nil:
+		return maps.Keys(src.z.dict)
+	case src.st != nil:
+		return src.st.all()
+	}
+	return func(func(string) bool) {}
+}
+
+// ZUnionStore stores the union of the sorted sets, or sets, at keys at dst
+// and returns its size. the score of a member is the aggregate of its
+// scores, each multiplied by the weight of its set.
+func (s *InMemoryStore) ZUnionStore(dst string, keys []string, weights []float64, agg ZAggregate) (int, error) {
+	return s.zsetAlgebraStore(dst, keys, weights, agg, false)
+}
+
+// ZInterStore is ZUnionStore with the members found in every set
+func (s *InMemoryStore) ZInterStore(dst string, keys []string, weights []float64, agg ZAggregate) (int, error) {
+	return s.zsetAlgebraStore(dst, keys, weights, agg, true)
+}
+
+func (s *InMemoryStore) zsetAlgebraStore(dst string, keys []string, weights []float64, agg ZAggregate, inter bool) (int, error) {
+	unlock := s.lockKeys(append([]string{dst}, keys...))
+	defer unlock()
+
+	nowMs := time.Now().UnixMilli()
+	sources := make([]scoredSource, len(keys))
+	for i, key := range keys {
+		sources[i].weight = 1
+		if weights != nil {
+			sources[i].weight = weights[i]
+		}
+		record, ok := s.getShard(key).live(key, nowMs)
+		switch {
+		case !ok:
+		case record.valueType() == TypeZSet:
+			sources[i].z = record.obj.(*zset)
+		case record.valueType() == TypeSet:
+			sources[i].st = record.obj.(*set)
+		default:
+			return 0, common.ErrWrongType
+		}
+	}
+
+	result := map[string]float64{}
+	if inter {
+		// the smallest set drives, every member of the result is in it
+		slices.SortStableFunc(sources, func(a, b scoredSource) int { return a.len() - b.len() })
+	members:
+		for m := range sources[0].members() {
+			score, _ := sources[0].score(m)
+			for _, src := range sources[1:] {
+				other, ok := src.score(m)
+				if !ok {
+					continue members
+				}
+				score = agg.apply(score, other)
+			}
+			result[m] = score
+		}
+	} else {
+		for _, src := range sources {
+			for m := range src.members() {
+				score, _ := src.score(m)
+				if acc, ok := result[m]; ok {
+					score = agg.apply(acc, score)
+				}
+				result[m] = score
+			}
+		}
+	}
+	return s.storeZSet(dst, maps.All(result)), nil
+}
+
+// ZScan returns a batch of members with their scores and the cursor of the
+// next batch, 0 once the scan is complete. see scanNames.
+func (s *InMemoryStore) ZScan(key string, cursor uint64, match string, count int) (uint64, []ScoredMember, error) {
+	next := uint64(0)
+	members := []ScoredMember{}
+	err := s.readZSet(key, func(z *zset) {
+		var names []string
+		next, names = scanNames(&z.scan, maps.Keys(z.dict), cursor, count, match)
+		for _, m := range names {
+			members = append(members, ScoredMember{m, z.dict[m]})
+		}
+	})
+	return next, members, err
+}
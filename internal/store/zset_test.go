@@ -0,0 +1,316 @@
+package store
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+)
+
+func members(scored []ScoredMember) []string {
+	out := make([]string, len(scored))
+	for i, m := range scored {
+		out[i] = m.Member
+	}
+	return out
+}
+
+// TestSkiplistMatchesSlice runs random inserts and deletes on a skiplist
+// and on a sorted slice, then checks ranks both ways
+func TestSkiplistMatchesSlice(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	z := newZSet()
+	model := map[string]float64{}
+	for op := 0; op < 20_000; op++ {
+		member := fmt.Sprint(rng.IntN(500))
+		if rng.IntN(3) == 0 {
+			if _, ok := model[member]; z.del(member) != ok {
+				t.Fatalf("op %d: del %q disagrees with the model", op, member)
+			}
+			delete(model, member)
+			continue
+		}
+		score := float64(rng.IntN(20))
+		z.put(member, score)
+		model[member] = score
+	}
+
+	sorted := make([]ScoredMember, 0, len(model))
+	for m, score := range model {
+		sorted = append(sorted, ScoredMember{m, score})
+	}
+	slices.SortFunc(sorted, func(a, b ScoredMember) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), cmp.Compare(a.Member, b.Member))
+	})
+	if z.zsl.length != len(sorted) || z.Len() != len(sorted) {
+		t.Fatalf("Expected %d members, got %d in the skiplist", len(sorted), z.zsl.length)
+	}
+	for i, m := range sorted {
+		if rank := z.zsl.rank(m.Score, m.Member); rank != i+1 {
+			t.Fatalf("Expected %q at rank %d, got %d", m.Member, i+1, rank)
+		}
+		if x := z.zsl.byRank(i + 1); x == nil || x.member != m.Member {
+			t.Fatalf("Expected rank %d to hold %q", i+1, m.Member)
+		}
+	}
+	if z.zsl.rank(0, "missing") != 0 || z.zsl.byRank(len(sorted)+1) != nil {
+		t.Errorf("Expected missing members and ranks to be reported")
+	}
+
+	r := ScoreRange{Min: 5, Max: 8, MinEx: true}
+	var want []string
+	for _, m := range sorted {
+		if m.Score > 5 && m.Score <= 8 {
+			want = append(want, m.Member)
+		}
+	}
+	if first := z.zsl.first(r); first == nil || first.member != want[0] {
+		t.Errorf("Expected %q first in (5 8], got %v", want[0], first)
+	}
+	if last := z.zsl.last(r); last == nil || last.member != want[len(want)-1] {
+		t.Errorf("Expected %q last in (5 8], got %v", want[len(want)-1], last)
+	}
+	if z.zsl.first(ScoreRange{Min: 30, Max: 40}) != nil || z.zsl.last(ScoreRange{Min: 3, Max: 3, MaxEx: true}) != nil {
+		t.Errorf("Expected no node in empty ranges")
+	}
+}
+
+func TestZAddConditions(t *testing.T) {
+	s := NewInMemoryStore()
+	add := func(args ZAddArgs, score float64, member string) (int, int) {
+		added, updated, err := s.ZAdd("z", args, []ScoredMember{{member, score}})
+		if err != nil {
+			t.Fatalf("ZAdd failed: %v", err)
+		}
+		return added, updated
+	}
+	if a, u := add(ZAddArgs{XX: true}, 1, "a"); a != 0 || u != 0 {
+		t.Errorf("Expected XX not to add, got %d %d", a, u)
+	}
+	if n, _ := s.ZCard("z"); n != 0 || s.Exists([]string{"z"}) != 0 {
+		t.Errorf("Expected XX not to create the key")
+	}
+	if a, _ := add(ZAddArgs{}, 5, "a"); a != 1 {
+		t.Errorf("Expected a to be added")
+	}
+	if _, u := add(ZAddArgs{NX: true}, 1, "a"); u != 0 {
+		t.Errorf("Expected NX not to update")
+	}
+	if _, u := add(ZAddArgs{GT: true}, 3, "a"); u != 0 {
+		t.Errorf("Expected GT not to lower the score")
+	}
+	if _, u := add(ZAddArgs{LT: true}, 3, "a"); u != 1 {
+		t.Errorf("Expected LT to lower the score")
+	}
+	if a, _ := add(ZAddArgs{GT: true}, 1, "b"); a != 1 {
+		t.Errorf("Expected GT to still add new members")
+	}
+	if score, _, _ := s.ZScore("z", "a"); score != 3 {
+		t.Errorf("Expected a at 3, got %v", score)
+	}
+
+	if score, ok, _ := s.ZAddIncr("z", ZAddArgs{GT: true}, "a", -1); ok {
+		t.Errorf("Expected GT INCR to refuse a lower score, got %v", score)
+	}
+	if score, _ := s.ZIncrBy("z", "c", 2.5); score != 2.5 {
+		t.Errorf("Expected a missing member to count as 0, got %v", score)
+	}
+	s.ZIncrBy("z", "c", math.Inf(1))
+	if _, err := s.ZIncrBy("z", "c", math.Inf(-1)); !errors.Is(err, common.ErrScoreNaN) {
+		t.Errorf("Expected inf - inf to be refused, got %v", err)
+	}
+	s.Set("str", []byte("v"))
+	if _, _, err := s.ZAdd("str", ZAddArgs{}, []ScoredMember{{"a", 1}}); !errors.Is(err, common.ErrWrongType) {
+		t.Errorf("Expected WRONGTYPE, got %v", err)
+	}
+}
+
+func TestZRange(t *testing.T) {
+	s := NewInMemoryStore()
+	s.ZAdd("z", ZAddArgs{}, []ScoredMember{{"a", 1}, {"b", 2}, {"c", 2}, {"d", 3}, {"e", 4}})
+	s.ZAdd("lex", ZAddArgs{}, []ScoredMember{{"a", 0}, {"b", 0}, {"c", 0}, {"d", 0}})
+
+	for _, tc := range []struct {
+		name string
+		key  string
+		q    ZRangeQuery
+		want []string
+	}{
+		{"ranks", "z", ZRangeQuery{Start: 1, Stop: -2, Count: -1}, []string{"b", "c", "d"}},
+		{"reversed ranks", "z", ZRangeQuery{Start: 0, Stop: 1, Rev: true, Count: -1}, []string{"e", "d"}},
+		{"ranks out of range", "z", ZRangeQuery{Start: 7, Stop: 9, Count: -1}, []string{}},
+		{"scores", "z", ZRangeQuery{By: ZRangeByScore, Score: ScoreRange{Min: 2, Max: 3, MinEx: true}, Count: -1}, []string{"d"}},
+		{"scores to inf", "z", ZRangeQuery{By: ZRangeByScore, Score: ScoreRange{Min: 2, Max: math.Inf(1)}, Count: -1}, []string{"b", "c", "d", "e"}},
+		{"reversed scores", "z", ZRangeQuery{By: ZRangeByScore, Score: ScoreRange{Min: 1, Max: 3}, Rev: true, Count: -1}, []string{"d", "c", "b", "a"}},
+		{"limit", "z", ZRangeQuery{By: ZRangeByScore, Score: ScoreRange{Min: 1, Max: 4}, Offset: 1, Count: 2}, []string{"b", "c"}},
+		{"reversed limit", "z", ZRangeQuery{By: ZRangeByScore, Score: ScoreRange{Min: 1, Max: 4}, Rev: true, Offset: 3, Count: 5}, []string{"b", "a"}},
+		{"negative offset", "z", ZRangeQuery{By: ZRangeByScore, Score: ScoreRange{Min: 1, Max: 4}, Offset: -1, Count: 2}, []string{}},
+		{"lex", "lex", ZRangeQuery{By: ZRangeByLex, Lex: LexRange{LexBound{Member: "b"}, LexBound{Inf: 1}}, Count: -1}, []string{"b", "c", "d"}},
+		{"exclusive lex", "lex", ZRangeQuery{By: ZRangeByLex, Lex: LexRange{LexBound{Inf: -1}, LexBound{Member: "c", Exclusive: true}}, Count: -1}, []string{"a", "b"}},
+		{"reversed lex", "lex", ZRangeQuery{By: ZRangeByLex, Lex: LexRange{LexBound{Member: "a", Exclusive: true}, LexBound{Member: "c"}}, Rev: true, Count: -1}, []string{"c", "b"}},
+		{"empty lex", "lex", ZRangeQuery{By: ZRangeByLex, Lex: LexRange{LexBound{Inf: 1}, LexBound{Inf: -1}}, Count: -1}, []string{}},
+		{"missing key", "missing", ZRangeQuery{Start: 0, Stop: -1, Count: -1}, []string{}},
+	} {
+		got, err := s.ZRange(tc.key, tc.q)
+		if err != nil || !slices.Equal(members(got), tc.want) {
+			t.Errorf("%s: expected %q, got %q, %v", tc.name, tc.want, members(got), err)
+		}
+	}
+
+	if n, _ := s.ZCount("z", ScoreRange{Min: 2, Max: 3}); n != 3 {
+		t.Errorf("Expected 3 members in [2 3], got %d", n)
+	}
+	if rank, ok, _ := s.ZRank("z", "c", false); !ok || rank != 2 {
+		t.Errorf("Expected c at rank 2, got %d", rank)
+	}
+	if rank, ok, _ := s.ZRank("z", "c", true); !ok || rank != 2 {
+		t.Errorf("Expected c at reverse rank 2, got %d", rank)
+	}
+	if _, ok, _ := s.ZRank("z", "missing", false); ok {
+		t.Errorf("Expected no rank for a missing member")
+	}
+
+	if n, _ := s.ZRangeStore("dst", "z", ZRangeQuery{Start: 0, Stop: 1, Count: -1}); n != 2 {
+		t.Errorf("Expected ZRANGESTORE to store 2 members, got %d", n)
+	}
+	if n, _ := s.ZRangeStore("dst", "z", ZRangeQuery{Start: 9, Stop: 10, Count: -1}); n != 0 || s.Exists([]string{"dst"}) != 0 {
+		t.Errorf("Expected an empty range to remove the destination")
+	}
+}
+
+func TestZSetAlgebra(t *testing.T) {
+	s := NewInMemoryStore()
+	s.ZAdd("a", ZAddArgs{}, []ScoredMember{{"x", 1}, {"y", 2}})
+	s.ZAdd("b", ZAddArgs{}, []ScoredMember{{"y", 10}, {"z", 20}})
+	s.SAdd("s", []string{"y"})
+
+	scores := func(key string) map[string]float64 {
+		got, _ := s.ZRange(key, ZRangeQuery{Start: 0, Stop: -1, Count: -1})
+		out := map[string]float64{}
+		for _, m := range got {
+			out[m.Member] = m.Score
+		}
+		return out
+	}
+	if n, _ := s.ZUnionStore("u", []string{"a", "b", "missing"}, nil, ZAggregateSum); n != 3 {
+		t.Errorf("Expected 3 members in the union, got %d", n)
+	}
+	if got := scores("u"); got["x"] != 1 || got["y"] != 12 || got["z"] != 20 {
+		t.Errorf("Unexpected union %v", got)
+	}
+	s.ZUnionStore("u", []string{"a", "b"}, []float64{2, 1}, ZAggregateMax)
+	if got := scores("u"); got["x"] != 2 || got["y"] != 10 {
+		t.Errorf("Unexpected weighted max union %v", got)
+	}
+	if n, _ := s.ZInterStore("i", []string{"a", "b", "s"}, nil, ZAggregateMin); n != 1 {
+		t.Errorf("Expected 1 member in the intersect, got %d", n)
+	}
+	if got := scores("i"); got["y"] != 1 {
+		t.Errorf("Expected a plain set member to score 1, got %v", got)
+	}
+	if n, _ := s.ZInterStore("i", []string{"a", "missing"}, nil, ZAggregateSum); n != 0 || s.Exists([]string{"i"}) != 0 {
+		t.Errorf("Expected an empty intersect to remove the destination")
+	}
+	s.ZUnionStore("inf", []string{"a"}, []float64{math.Inf(1)}, ZAggregateSum)
+	s.ZUnionStore("inf", []string{"inf", "inf"}, []float64{1, -1}, ZAggregateSum)
+	if got := scores("inf"); got["x"] != 0 {
+		t.Errorf("Expected inf - inf to sum to 0, got %v", got)
+	}
+
+	s.Set("str", []byte("v"))
+	if _, err := s.ZUnionStore("u", []string{"a", "str"}, nil, ZAggregateSum); !errors.Is(err, common.ErrWrongType) {
+		t.Errorf("Expected WRONGTYPE, got %v", err)
+	}
+}
+
+func TestZPop(t *testing.T) {
+	s := NewInMemoryStore()
+	s.ZAdd("z", ZAddArgs{}, []ScoredMember{{"a", 1}, {"b", 2}, {"c", 3}})
+	if got, _ := s.ZPopMin("z", 2); !slices.Equal(members(got), []string{"a", "b"}) {
+		t.Errorf("Unexpected ZPOPMIN %q", members(got))
+	}
+	if got, _ := s.ZPopMax("z", 5); !slices.Equal(members(got), []string{"c"}) || s.Exists([]string{"z"}) != 0 {
+		t.Errorf("Expected the last pop to remove the key, got %q", members(got))
+	}
+	if got, err := s.ZPopMin("z", 1); got != nil || err != nil {
+		t.Errorf("Expected nil for a missing key, got %v, %v", got, err)
+	}
+
+	s.ZAdd("b", ZAddArgs{}, []ScoredMember{{"x", 1}, {"y", 2}})
+	if key, m, _ := s.ZPopFirst([]string{"a", "b"}, true); key != "b" || m.Member != "y" {
+		t.Errorf("Expected y from b, got %q from %q", m.Member, key)
+	}
+	if key, _, _ := s.ZPopFirst([]string{"a", "c"}, false); key != "" {
+		t.Errorf("Expected no key when every sorted set is empty, got %q", key)
+	}
+}
+
+func TestZSetMemoryAccounting(t *testing.T) {
+	s := NewInMemoryStore()
+	s.ZAdd("z", ZAddArgs{}, []ScoredMember{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}})
+	s.ZAdd("z", ZAddArgs{}, []ScoredMember{{"a", 5}})
+	s.ZIncrBy("z", "e", 1)
+	s.ZRem("z", []string{"b", "missing"})
+	s.ZPopMax("z", 1)
+	s.ZRangeStore("r", "z", ZRangeQuery{Start: 0, Stop: 0, Count: -1})
+	s.ZUnionStore("u", []string{"z", "r"}, nil, ZAggregateSum)
+	s.Del([]string{"z", "r", "u"})
+	if s.UsedMemory() != 0 {
+		t.Errorf("Expected every byte to be released, %d left", s.UsedMemory())
+	}
+}
+
+func TestZScan(t *testing.T) {
+	s := NewInMemoryStore()
+	want := []string{}
+	for i := range 100 {
+		m := fmt.Sprint("m", i)
+		s.ZAdd("z", ZAddArgs{}, []ScoredMember{{m, float64(i)}})
+		want = append(want, m)
+	}
+	got := []string{}
+	for cursor := uint64(0); ; {
+		next, batch, err := s.ZScan("z", cursor, "", 10)
+		if err != nil {
+			t.Fatalf("ZScan failed: %v", err)
+		}
+		for _, m := range batch {
+			if score, _, _ := s.ZScore("z", m.Member); score != m.Score {
+				t.Errorf("Expected %q with score %v, got %v", m.Member, score, m.Score)
+			}
+		}
+		got = append(got, members(batch)...)
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected the scan to return every member once, got %d", len(got))
+	}
+}
+
+func TestWatchSignalsZAdd(t *testing.T) {
+	s := NewInMemoryStore()
+	w := s.Watch([]string{"z"})
+	defer w.Stop()
+
+	s.ZAdd("z", ZAddArgs{XX: true}, []ScoredMember{{"a", 1}})
+	select {
+	case <-w.C:
+		t.Fatalf("Expected no signal when nothing was added")
+	default:
+	}
+	s.ZAdd("z", ZAddArgs{}, []ScoredMember{{"a", 1}})
+	select {
+	case <-w.C:
+	default:
+		t.Fatalf("Expected ZADD to signal")
+	}
+}
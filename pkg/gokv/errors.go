@@ -0,0 +1,40 @@
+package gokv
+
+import "github.com/B-AJ-Amar/gokv/internal/common"
+
+// Error is the error a command fails with, its Code is the class a client
+// would see as the first word of the reply. use errors.As to get it, or
+// errors.Is(err, gokv.CodeOOM) to branch on the class only.
+type Error = common.Error
+
+// Code is the class of an Error
+type Code = common.Code
+
+const (
+	CodeErr       = common.CodeErr
+	CodeWrongType = common.CodeWrongType
+	CodeNoAuth    = common.CodeNoAuth
+	CodeWrongPass = common.CodeWrongPass
+	CodeNoPerm    = common.CodeNoPerm
+	CodeNoProto   = common.CodeNoProto
+	CodeExecAbort = common.CodeExecAbort
+	CodeBusy      = common.CodeBusy
+	CodeOOM       = common.CodeOOM
+	CodeReadOnly  = common.CodeReadOnly
+	CodeLoading   = common.CodeLoading
+	CodeNoScript  = common.CodeNoScript
+	CodeMoved     = common.CodeMoved
+	CodeAsk       = common.CodeAsk
+)
+
+// errors a Store call may return, to be matched with errors.Is
+var (
+	// ErrNotFound is returned by Get for a missing or expired key
+	ErrNotFound  = common.ErrKeyNotFound
+	ErrWrongType = common.ErrWrongType
+	ErrOOM       = common.ErrOOM
+	ErrSyntax    = common.ErrSyntaxError
+	ErrNotInt    = common.ErrNotIntOROutOfRange
+	// ErrInvalidExpire is returned for a negative ttl
+	ErrInvalidExpire = common.ErrInvalidExpireTime
+)
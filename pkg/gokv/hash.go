@@ -0,0 +1,59 @@
+package gokv
+
+import (
+	"strconv"
+
+	"github.com/B-AJ-Amar/gokv/internal/protocol"
+)
+
+// HSet sets the fields of the hash at key, creating it when missing, and
+// returns how many fields are new
+func (s *Store) HSet(key string, fields map[string][]byte) (int64, error) {
+	args := make([]string, 0, 2+2*len(fields))
+	args = append(args, "HSET", key)
+	for f, v := range fields {
+		args = append(args, f, string(v))
+	}
+	return s.execInt(args...)
+}
+
+// HGet returns the value of a field, or ErrNotFound
+func (s *Store) HGet(key, field string) ([]byte, error) {
+	res, err := s.exec("HGET", key, field)
+	if err != nil {
+		return nil, err
+	}
+	if res.Kind() == protocol.NotExistsRes {
+		return nil, ErrNotFound
+	}
+	return []byte(res.Message()), nil
+}
+
+// HGetAll returns every field of the hash at key, empty when missing
+func (s *Store) HGetAll(key string) (map[string][]byte, error) {
+	res, err := s.exec("HGETALL", key)
+	if err != nil {
+		return nil, err
+	}
+	items := res.Items()
+	fields := make(map[string][]byte, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		fields[items[i].Message()] = []byte(items[i+1].Message())
+	}
+	return fields, nil
+}
+
+// HDel removes fields and returns how many existed, the hash goes away with
+// its last field
+func (s *Store) HDel(key string, fields ...string) (int64, error) {
+	return s.execInt(append([]string{"HDEL", key}, fields...)...)
+}
+
+func (s *Store) HLen(key string) (int64, error) {
+	return s.execInt("HLEN", key)
+}
+
+// HIncrBy adds by to the integer in field and returns the new value
+func (s *Store) HIncrBy(key, field string, by int64) (int64, error) {
+	return s.execInt("HINCRBY", key, field, strconv.FormatInt(by, 10))
+}
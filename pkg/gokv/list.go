@@ -0,0 +1,68 @@
+package gokv
+
+import (
+	"strconv"
+
+	"github.com/B-AJ-Amar/gokv/internal/protocol"
+)
+
+// LPush prepends values to the list at key, creating it when missing, and
+// returns its new length
+func (s *Store) LPush(key string, values ...[]byte) (int64, error) {
+	return s.execInt(pushArgs("LPUSH", key, values)...)
+}
+
+// RPush appends values to the list at key, creating it when missing, and
+// returns its new length
+func (s *Store) RPush(key string, values ...[]byte) (int64, error) {
+	return s.execInt(pushArgs("RPUSH", key, values)...)
+}
+
+func pushArgs(cmd, key string, values [][]byte) []string {
+	args := make([]string, 0, 2+len(values))
+	args = append(args, cmd, key)
+	for _, v := range values {
+		args = append(args, string(v))
+	}
+	return args
+}
+
+// LPop removes and returns the first element of a list, or ErrNotFound
+func (s *Store) LPop(key string) ([]byte, error) {
+	return s.pop("LPOP", key)
+}
+
+// RPop removes and returns the last element of a list, or ErrNotFound
+func (s *Store) RPop(key string) ([]byte, error) {
+	return s.pop("RPOP", key)
+}
+
+func (s *Store) pop(cmd, key string) ([]byte, error) {
+	res, err := s.exec(cmd, key)
+	if err != nil {
+		return nil, err
+	}
+	if res.Kind() == protocol.NotExistsRes {
+		return nil, ErrNotFound
+	}
+	return []byte(res.Message()), nil
+}
+
+// LRange returns the elements from start to stop, both included, negative
+// indexes count from the end
+func (s *Store) LRange(key string, start, stop int) ([][]byte, error) {
+	res, err := s.exec("LRANGE", key, strconv.Itoa(start), strconv.Itoa(stop))
+	if err != nil {
+		return nil, err
+	}
+	items := res.Items()
+	values := make([][]byte, len(items))
+	for i, item := range items {
+		values[i] = []byte(item.Message())
+	}
+	return values, nil
+}
+
+func (s *Store) LLen(key string) (int64, error) {
+	return s.execInt("LLEN", key)
+}
@@ -0,0 +1,161 @@
+// Package gokv runs a GoKV server inside a Go program, for services that
+// embed it and for integration tests that need a real instance.
+package gokv
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/config"
+	"github.com/B-AJ-Amar/gokv/internal/protocol"
+	"github.com/B-AJ-Amar/gokv/internal/server"
+)
+
+// Option configures a Server, see the With* functions
+type Option func(*options) error
+
+type options struct {
+	cfg *config.Config
+	ln  net.Listener
+}
+
+// WithListener serves on ln instead of listening on the configured address,
+// the server takes ownership of ln and closes it on Close
+func WithListener(ln net.Listener) Option {
+	return func(o *options) error {
+		o.ln = ln
+		return nil
+	}
+}
+
+// WithAddr sets the host:port to listen on, port 0 picks a free port
+func WithAddr(addr string) Option {
+	return func(o *options) error {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return fmt.Errorf("port %q: %w", port, common.ErrInvalidConfigValue)
+		}
+		o.cfg.Bind = host
+		o.cfg.Port = p
+		return nil
+	}
+}
+
+// WithDatabases sets the number of databases available to SELECT
+func WithDatabases(n int) Option {
+	return func(o *options) error {
+		o.cfg.Databases = n
+		return nil
+	}
+}
+
+// WithPassword requires clients to AUTH with password
+func WithPassword(password string) Option {
+	return func(o *options) error {
+		o.cfg.RequirePass = password
+		return nil
+	}
+}
+
+// WithPersistence keeps a snapshot in dir: it is loaded on NewServer and
+// written again on Close
+func WithPersistence(dir string) Option {
+	return func(o *options) error {
+		o.cfg.Dir = dir
+		o.cfg.DBFilename = common.DefaultSnapshotFile
+		o.cfg.SaveOnShutdown = true
+		return nil
+	}
+}
+
+// WithAppendOnly logs every write to an append only file in the persistence
+// directory (the working directory without WithPersistence), fsync is one
+// of always, everysec or no
+func WithAppendOnly(fsync string) Option {
+	return func(o *options) error {
+		o.cfg.AppendOnly = true
+		o.cfg.AppendFsync = fsync
+		return nil
+	}
+}
+
+// WithMaxMemory limits the memory used by the data, policy is a
+// maxmemory-policy value such as noeviction or allkeys-random
+func WithMaxMemory(bytes int64, policy string) Option {
+	return func(o *options) error {
+		o.cfg.MaxMemory = bytes
+		o.cfg.MaxMemPolicy = policy
+		return nil
+	}
+}
+
+// WithLogLevel sets the level of the server logs, the logger is process
+// wide so it is shared by every server of the program
+func WithLogLevel(level string) Option {
+	return func(o *options) error {
+		o.cfg.LogLevel = level
+		return nil
+	}
+}
+
+// Server is an embedded GoKV server. the defaults differ from the gokv
+// binary: it listens on 127.0.0.1 on a free port, persists nothing and only
+// logs warnings.
+type Server struct {
+	srv *server.Server
+	ln  net.Listener
+}
+
+func NewServer(opts ...Option) (*Server, error) {
+	cfg := config.Default()
+	cfg.Bind = "127.0.0.1"
+	cfg.Port = 0
+	cfg.DBFilename = ""
+	cfg.LogLevel = "warning"
+
+	o := &options{cfg: cfg}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	srv, err := server.NewServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{srv: srv, ln: o.ln}, nil
+}
+
+// Start serves clients in the background, the server stops when ctx is
+// cancelled, on SHUTDOWN or on Close
+func (s *Server) Start(ctx context.Context) error {
+	if s.ln != nil {
+		s.srv.StartListener(ctx, s.ln)
+		return nil
+	}
+	return s.srv.Start(ctx)
+}
+
+// Addr is the address clients connect to, nil until Start
+func (s *Server) Addr() net.Addr {
+	return s.srv.Addr()
+}
+
+// Close stops the server gracefully: in-flight commands finish, clients are
+// disconnected and the data is persisted if persistence is enabled
+func (s *Server) Close() error {
+	return s.srv.Shutdown(protocol.ShutdownDefault)
+}
+
+// Wait blocks until the server is stopped, whatever stopped it
+func (s *Server) Wait() error {
+	return s.srv.Wait()
+}
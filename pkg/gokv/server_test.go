@@ -0,0 +1,145 @@
+package gokv
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// do sends each command on one connection and returns the replies, bulk
+// strings are returned without their length line
+func do(t *testing.T, addr string, cmds ...string) []string {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	r := bufio.NewReader(conn)
+	var replies []string
+	for _, cmd := range cmds {
+		args := strings.Fields(cmd)
+		msg := "*" + strconv.Itoa(len(args)) + "\r\n"
+		for _, arg := range args {
+			msg += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+		}
+		conn.Write([]byte(msg))
+
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "$") && line != "$-1" {
+			body, _ := r.ReadString('\n')
+			line = strings.TrimRight(body, "\r\n")
+		}
+		replies = append(replies, line)
+	}
+	return replies
+}
+
+func start(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	s, err := NewServer(opts...)
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return s
+}
+
+func TestServerEphemeralPort(t *testing.T) {
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	if s.Addr() != nil {
+		t.Fatalf("Expected no address before Start, got %v", s.Addr())
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Close()
+
+	got := do(t, s.Addr().String(), "SET k v", "GET k")
+	if got[0] != "+OK" || got[1] != "v" {
+		t.Fatalf("Expected [+OK v], got %v", got)
+	}
+}
+
+func TestServerWithListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	s := start(t, WithListener(ln), WithDatabases(2))
+
+	if s.Addr().String() != ln.Addr().String() {
+		t.Fatalf("Expected addr %s, got %s", ln.Addr(), s.Addr())
+	}
+	got := do(t, s.Addr().String(), "SELECT 1", "SELECT 2")
+	if got[0] != "+OK" || !strings.HasPrefix(got[1], "-ERR") {
+		t.Fatalf("Expected SELECT 1 to work and SELECT 2 to fail, got %v", got)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Fatalf("Expected the listener to be closed")
+	}
+}
+
+func TestServerWithPassword(t *testing.T) {
+	s := start(t, WithPassword("secret"))
+	defer s.Close()
+
+	got := do(t, s.Addr().String(), "GET k", "AUTH secret", "GET k")
+	if !strings.HasPrefix(got[0], "-NOAUTH") || got[1] != "+OK" || got[2] != "$-1" {
+		t.Fatalf("Unexpected replies %v", got)
+	}
+}
+
+func TestServerPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	s := start(t, WithPersistence(dir))
+	do(t, s.Addr().String(), "SET k v")
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	s = start(t, WithPersistence(dir))
+	defer s.Close()
+	if got := do(t, s.Addr().String(), "GET k"); got[0] != "v" {
+		t.Fatalf("Expected k to survive a restart, got %v", got)
+	}
+}
+
+func TestServerCloseBeforeStart(t *testing.T) {
+	s, err := NewServer(WithPersistence(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
+
+func TestServerBadOption(t *testing.T) {
+	if _, err := NewServer(WithAddr("nope")); err == nil {
+		t.Fatalf("Expected an error for a bad address")
+	}
+	if _, err := NewServer(WithAppendOnly("sometimes")); err == nil {
+		t.Fatalf("Expected an error for a bad fsync policy")
+	}
+}
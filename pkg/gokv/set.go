@@ -0,0 +1,47 @@
+package gokv
+
+// SAdd adds members to the set at key, creating it when missing, and returns
+// how many are new
+func (s *Store) SAdd(key string, members ...string) (int64, error) {
+	return s.execInt(append([]string{"SADD", key}, members...)...)
+}
+
+// SRem removes members and returns how many existed, the set goes away with
+// its last member
+func (s *Store) SRem(key string, members ...string) (int64, error) {
+	return s.execInt(append([]string{"SREM", key}, members...)...)
+}
+
+func (s *Store) SIsMember(key, member string) (bool, error) {
+	n, err := s.execInt("SISMEMBER", key, member)
+	return n == 1, err
+}
+
+// SMembers returns the members of the set at key in no particular order,
+// empty when missing
+func (s *Store) SMembers(key string) ([]string, error) {
+	res, err := s.exec("SMEMBERS", key)
+	if err != nil {
+		return nil, err
+	}
+	items := res.Items()
+	members := make([]string, len(items))
+	for i, item := range items {
+		members[i] = item.Message()
+	}
+	return members, nil
+}
+
+func (s *Store) SCard(key string) (int64, error) {
+	return s.execInt("SCARD", key)
+}
+
+// Type returns the type of the value at key: string, hash, list, set, zset,
+// or none when the key is missing
+func (s *Store) Type(key string) (string, error) {
+	res, err := s.exec("TYPE", key)
+	if err != nil {
+		return "", err
+	}
+	return res.Message(), nil
+}
@@ -0,0 +1,298 @@
+package gokv
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/B-AJ-Amar/gokv/internal/common"
+	"github.com/B-AJ-Amar/gokv/internal/protocol"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// TTL values for keys without an expire time and for missing keys, like
+// the -1 and -2 replies of the TTL command
+const (
+	NoTTL      time.Duration = -1
+	MissingTTL time.Duration = -2
+)
+
+// Store is a typed, in-process client of GoKV databases. every call runs the
+// same command as a network client would, so the semantics, the AOF and the
+// maxmemory handling are exactly those of the server. a Store is bound to
+// one database and is safe for concurrent use.
+type Store struct {
+	resp    *protocol.RESP
+	dbIndex int
+}
+
+// NewStore creates standalone databases that live only in this process
+func NewStore(databases int) (*Store, error) {
+	if databases < 1 || databases > common.MaxDatabases {
+		return nil, common.ErrDBIndexOutOfRange
+	}
+	return &Store{resp: &protocol.RESP{DBs: store.NewInMemoryStoreArray(databases)}}, nil
+}
+
+// Store returns a client of the server databases, bound to database 0. it
+// does not need AUTH and does not count as a connected client.
+func (s *Server) Store() *Store {
+	return &Store{resp: s.srv.Local()}
+}
+
+// Select returns a Store bound to another database of the same set
+func (s *Store) Select(index int) (*Store, error) {
+	if index < 0 || index >= len(s.resp.DBs) {
+		return nil, common.ErrDBIndexOutOfRange
+	}
+	return &Store{resp: s.resp, dbIndex: index}, nil
+}
+
+// DB is the index of the database the Store is bound to
+func (s *Store) DB() int {
+	return s.dbIndex
+}
+
+func (s *Store) exec(args ...string) (*protocol.RESPRes, error) {
+	dbIndex := s.dbIndex
+	res, err := s.resp.Exec(args, &dbIndex)
+	if err != nil {
+		return nil, err
+	}
+	if res.Kind() == protocol.ErrorRes {
+		return nil, res.Err()
+	}
+	return res, nil
+}
+
+func (s *Store) execInt(args ...string) (int64, error) {
+	res, err := s.exec(args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.Int(), nil
+}
+
+// Get returns the value of key, or ErrNotFound
+func (s *Store) Get(key string) ([]byte, error) {
+	res, err := s.exec("GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if res.Kind() == protocol.NotExistsRes {
+		return nil, ErrNotFound
+	}
+	return []byte(res.Message()), nil
+}
+
+// Set stores value under key without a ttl, like a plain SET
+func (s *Store) Set(key string, value []byte) error {
+	_, err := s.exec("SET", key, string(value))
+	return err
+}
+
+// GetDel returns the value of key and deletes it, or ErrNotFound
+func (s *Store) GetDel(key string) ([]byte, error) {
+	res, err := s.exec("GETDEL", key)
+	if err != nil {
+		return nil, err
+	}
+	if res.Kind() == protocol.NotExistsRes {
+		return nil, ErrNotFound
+	}
+	return []byte(res.Message()), nil
+}
+
+// Append appends value to key, creating it when missing, and returns the
+// new length
+func (s *Store) Append(key string, value []byte) (int64, error) {
+	return s.execInt("APPEND", key, string(value))
+}
+
+func (s *Store) StrLen(key string) (int64, error) {
+	return s.execInt("STRLEN", key)
+}
+
+// GetRange returns the bytes of key between start and end included,
+// negative offsets count from the end
+func (s *Store) GetRange(key string, start, end int64) ([]byte, error) {
+	res, err := s.exec("GETRANGE", key, strconv.FormatInt(start, 10), strconv.FormatInt(end, 10))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(res.Message()), nil
+}
+
+// SetRange overwrites key from offset with value, zero padding it when
+// needed, and returns the new length
+func (s *Store) SetRange(key string, offset int64, value []byte) (int64, error) {
+	return s.execInt("SETRANGE", key, strconv.FormatInt(offset, 10), string(value))
+}
+
+// MGet returns the values of keys, nil for the missing ones
+func (s *Store) MGet(keys ...string) ([][]byte, error) {
+	res, err := s.exec(append([]string{"MGET"}, keys...)...)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(res.Items()))
+	for i, item := range res.Items() {
+		if item.Kind() != protocol.NotExistsRes {
+			values[i] = []byte(item.Message())
+		}
+	}
+	return values, nil
+}
+
+// MSet sets every key of pairs in one atomic step, like MSET
+func (s *Store) MSet(pairs map[string][]byte) error {
+	_, err := s.exec(pairArgs("MSET", pairs)...)
+	return err
+}
+
+// MSetNX is MSet when none of the keys exist, it returns false and sets
+// nothing otherwise
+func (s *Store) MSetNX(pairs map[string][]byte) (bool, error) {
+	n, err := s.execInt(pairArgs("MSETNX", pairs)...)
+	return n == 1, err
+}
+
+func pairArgs(cmd string, pairs map[string][]byte) []string {
+	args := make([]string, 0, 1+2*len(pairs))
+	args = append(args, cmd)
+	for k, v := range pairs {
+		args = append(args, k, string(v))
+	}
+	return args
+}
+
+// SetOptions are the options of the SET command
+type SetOptions struct {
+	TTL      time.Duration // PX, relative expire time
+	ExpireAt time.Time     // PXAT, absolute expire time
+	KeepTTL  bool          // keep the ttl of the current value
+	NX       bool          // only set a missing key
+	XX       bool          // only set an existing key
+	Get      bool          // return the previous value
+}
+
+func (o SetOptions) args() []string {
+	var args []string
+	if o.TTL != 0 {
+		args = append(args, "PX", strconv.FormatInt(ttlMillis(o.TTL), 10))
+	}
+	if !o.ExpireAt.IsZero() {
+		args = append(args, "PXAT", strconv.FormatInt(o.ExpireAt.UnixMilli(), 10))
+	}
+	if o.KeepTTL {
+		args = append(args, "KEEPTTL")
+	}
+	if o.NX {
+		args = append(args, "NX")
+	}
+	if o.XX {
+		args = append(args, "XX")
+	}
+	if o.Get {
+		args = append(args, "GET")
+	}
+	return args
+}
+
+// SetWith is SET with options. ok is false when NX or XX prevented the
+// write, old is the previous value when opts.Get is set and there was one.
+func (s *Store) SetWith(key string, value []byte, opts SetOptions) (old []byte, ok bool, err error) {
+	res, err := s.exec(append([]string{"SET", key, string(value)}, opts.args()...)...)
+	if err != nil {
+		return nil, false, err
+	}
+	switch res.Kind() {
+	case protocol.NotExistsRes:
+		return nil, false, nil
+	case protocol.BulkStrRes:
+		return []byte(res.Message()), true, nil
+	}
+	return nil, true, nil
+}
+
+// Del removes keys and returns how many existed
+func (s *Store) Del(keys ...string) (int64, error) {
+	return s.execInt(append([]string{"DEL"}, keys...)...)
+}
+
+// Exists returns how many of keys exist, a key given twice counts twice
+func (s *Store) Exists(keys ...string) (int64, error) {
+	return s.execInt(append([]string{"EXISTS"}, keys...)...)
+}
+
+func (s *Store) Incr(key string) (int64, error) {
+	return s.execInt("INCR", key)
+}
+
+func (s *Store) IncrBy(key string, by int64) (int64, error) {
+	return s.execInt("INCRBY", key, strconv.FormatInt(by, 10))
+}
+
+// IncrByFloat adds by to the number at key and returns the new value
+func (s *Store) IncrByFloat(key string, by float64) (float64, error) {
+	res, err := s.exec("INCRBYFLOAT", key, strconv.FormatFloat(by, 'f', -1, 64))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(res.Message(), 64)
+}
+
+func (s *Store) Decr(key string) (int64, error) {
+	return s.execInt("DECR", key)
+}
+
+func (s *Store) DecrBy(key string, by int64) (int64, error) {
+	return s.execInt("DECRBY", key, strconv.FormatInt(by, 10))
+}
+
+// Expire sets a ttl on key, with a millisecond resolution. a ttl of 0
+// removes the key. it returns false when the key does not exist.
+func (s *Store) Expire(key string, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		return false, ErrInvalidExpire
+	}
+	at := time.Now().UnixMilli() + ttlMillis(ttl)
+	n, err := s.execInt("PEXPIREAT", key, strconv.FormatInt(at, 10))
+	return n == 1, err
+}
+
+// ttlMillis rounds a positive ttl up to a whole millisecond, so a ttl under
+// 1ms does not become 0
+func ttlMillis(ttl time.Duration) int64 {
+	ms := ttl.Milliseconds()
+	if ttl > 0 && ttl%time.Millisecond != 0 {
+		ms++
+	}
+	return ms
+}
+
+// ExpireAt sets an absolute expire time on key, it returns false when the
+// key does not exist
+func (s *Store) ExpireAt(key string, at time.Time) (bool, error) {
+	n, err := s.execInt("PEXPIREAT", key, strconv.FormatInt(at.UnixMilli(), 10))
+	return n == 1, err
+}
+
+// Persist removes the ttl of key, it returns false when the key does not
+// exist
+func (s *Store) Persist(key string) (bool, error) {
+	n, err := s.execInt("PERSIST", key)
+	return n == 1, err
+}
+
+// TTL returns the remaining time to live of key in whole seconds, NoTTL for
+// a key without one and MissingTTL for a missing key
+func (s *Store) TTL(key string) (time.Duration, error) {
+	n, err := s.execInt("TTL", key)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return time.Duration(n), nil
+	}
+	return time.Duration(n) * time.Second, nil
+}
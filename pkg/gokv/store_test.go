@@ -0,0 +1,315 @@
+package gokv
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(2)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	return s
+}
+
+func TestStoreGetSetDel(t *testing.T) {
+	s := newStore(t)
+
+	if _, err := s.Get("k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := s.Set("k", []byte("v")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if v, err := s.Get("k"); err != nil || string(v) != "v" {
+		t.Fatalf("Expected v, got %q, %v", v, err)
+	}
+	if n, _ := s.Exists("k", "k", "other"); n != 2 {
+		t.Fatalf("Expected Exists to count 2, got %d", n)
+	}
+	if n, _ := s.Del("k", "other"); n != 1 {
+		t.Fatalf("Expected Del to remove 1 key, got %d", n)
+	}
+}
+
+func TestStoreSetWith(t *testing.T) {
+	s := newStore(t)
+
+	if _, ok, _ := s.SetWith("k", []byte("v1"), SetOptions{XX: true}); ok {
+		t.Fatalf("Expected XX to skip a missing key")
+	}
+	if _, ok, _ := s.SetWith("k", []byte("v1"), SetOptions{NX: true}); !ok {
+		t.Fatalf("Expected NX to set a missing key")
+	}
+	old, ok, err := s.SetWith("k", []byte("v2"), SetOptions{Get: true, TTL: time.Minute})
+	if err != nil || !ok || string(old) != "v1" {
+		t.Fatalf("Expected the old value v1, got %q, %v, %v", old, ok, err)
+	}
+	if ttl, _ := s.TTL("k"); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("Expected a ttl of about a minute, got %v", ttl)
+	}
+	if _, _, err := s.SetWith("new", []byte("v3"), SetOptions{NX: true, Get: true}); err == nil {
+		t.Fatalf("Expected NX with GET to fail like the SET command")
+	}
+}
+
+func TestStoreExpire(t *testing.T) {
+	s := newStore(t)
+
+	if ttl, _ := s.TTL("k"); ttl != MissingTTL {
+		t.Fatalf("Expected MissingTTL, got %v", ttl)
+	}
+	if ok, _ := s.Expire("k", time.Minute); ok {
+		t.Fatalf("Expected Expire on a missing key to return false")
+	}
+	s.Set("k", []byte("v"))
+	if ttl, _ := s.TTL("k"); ttl != NoTTL {
+		t.Fatalf("Expected NoTTL, got %v", ttl)
+	}
+	if ok, err := s.Expire("k", 10*time.Second); !ok || err != nil {
+		t.Fatalf("Expire failed: %v, %v", ok, err)
+	}
+	if ttl, _ := s.TTL("k"); ttl != 9*time.Second && ttl != 10*time.Second {
+		t.Fatalf("Expected a ttl of 10s, got %v", ttl)
+	}
+	if _, err := s.Expire("k", -time.Second); !errors.Is(err, ErrInvalidExpire) {
+		t.Fatalf("Expected a negative ttl to fail like EXPIRE, got %v", err)
+	}
+	if ok, _ := s.Persist("k"); !ok {
+		t.Fatalf("Expected Persist to return true")
+	}
+
+	s.ExpireAt("k", time.Now().Add(-time.Second))
+	if _, err := s.Get("k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected an expired key to be gone, got %v", err)
+	}
+}
+
+// ttls under a second keep their milliseconds, and under a millisecond
+// are rounded up instead of expiring the key on the spot
+func TestStoreSubSecondTTL(t *testing.T) {
+	s := newStore(t)
+	s.Set("k", []byte("v"))
+	if ok, err := s.Expire("k", 50*time.Millisecond); !ok || err != nil {
+		t.Fatalf("Expire failed: %v, %v", ok, err)
+	}
+	if _, err := s.Get("k"); err != nil {
+		t.Fatalf("Expected the key to live for 50ms, got %v", err)
+	}
+	if _, _, err := s.SetWith("short", []byte("v"), SetOptions{TTL: 500 * time.Microsecond}); err != nil {
+		t.Fatalf("Expected a ttl under 1ms to be rounded up, got %v", err)
+	}
+	time.Sleep(80 * time.Millisecond)
+	for _, key := range []string{"k", "short"} {
+		if _, err := s.Get(key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected %s to be expired, got %v", key, err)
+		}
+	}
+}
+
+func TestStoreSelect(t *testing.T) {
+	s := newStore(t)
+	db1, err := s.Select(1)
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	db1.Set("k", []byte("v"))
+	if _, err := s.Get("k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected databases to be separate, got %v", err)
+	}
+	if _, err := s.Select(2); err == nil {
+		t.Fatalf("Expected Select 2 to fail with 2 databases")
+	}
+}
+
+func TestServerStoreSharesData(t *testing.T) {
+	srv := start(t, WithPassword("secret"))
+	defer srv.Close()
+
+	if err := srv.Store().Set("k", []byte("embedded")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	got := do(t, srv.Addr().String(), "AUTH secret", "GET k", "SET k wire")
+	if got[1] != "embedded" {
+		t.Fatalf("Expected the wire client to see the embedded write, got %v", got)
+	}
+	if v, _ := srv.Store().Get("k"); string(v) != "wire" {
+		t.Fatalf("Expected the store to see the wire write, got %q", v)
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	s := newStore(t)
+	s.Set("k", []byte("not a number"))
+	_, err := s.Incr("k")
+	var e *Error
+	if !errors.Is(err, ErrNotInt) || !errors.As(err, &e) || e.Code != CodeErr {
+		t.Fatalf("Expected ErrNotInt with the ERR code, got %v", err)
+	}
+
+	srv, err := NewServer(WithMaxMemory(1, "noeviction"))
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	defer srv.Close()
+	// the first write goes over the limit, the next one is refused
+	srv.Store().Set("k", []byte("v"))
+	if err := srv.Store().Set("k2", []byte("v")); !errors.Is(err, ErrOOM) || !errors.Is(err, CodeOOM) {
+		t.Fatalf("Expected an OOM error, got %v", err)
+	}
+}
+
+func TestStoreMSet(t *testing.T) {
+	s := newStore(t)
+	if err := s.MSet(map[string][]byte{"a": []byte("1"), "b": []byte("2")}); err != nil {
+		t.Fatalf("MSet failed: %v", err)
+	}
+	if ok, err := s.MSetNX(map[string][]byte{"b": []byte("3"), "c": []byte("4")}); ok || err != nil {
+		t.Fatalf("Expected MSetNX to refuse an existing key, got %v, %v", ok, err)
+	}
+	values, err := s.MGet("a", "b", "c")
+	if err != nil || string(values[0]) != "1" || string(values[1]) != "2" || values[2] != nil {
+		t.Fatalf("Unexpected MGet %q, %v", values, err)
+	}
+}
+
+func TestStoreStringRanges(t *testing.T) {
+	s := newStore(t)
+	s.Append("log", []byte("line1\n"))
+	if n, err := s.Append("log", []byte("line2\n")); n != 12 || err != nil {
+		t.Fatalf("Append failed: %d, %v", n, err)
+	}
+	if n, _ := s.SetRange("log", 4, []byte("X")); n != 12 {
+		t.Fatalf("Expected SetRange to keep the length, got %d", n)
+	}
+	if v, _ := s.GetRange("log", 0, 5); string(v) != "lineX\n" {
+		t.Fatalf("Unexpected GetRange %q", v)
+	}
+	if v, err := s.GetDel("log"); err != nil || len(v) != 12 {
+		t.Fatalf("GetDel failed: %q, %v", v, err)
+	}
+	if _, err := s.GetDel("log"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStoreCounters(t *testing.T) {
+	s := newStore(t)
+	if n, err := s.IncrBy("n", 5); n != 5 || err != nil {
+		t.Fatalf("Expected a new counter to return its value, got %d, %v", n, err)
+	}
+	if n, _ := s.IncrBy("n", 5); n != 10 {
+		t.Fatalf("Expected the counter to be persisted, got %d", n)
+	}
+	if f, err := s.IncrByFloat("f", 2.5); f != 2.5 || err != nil {
+		t.Fatalf("IncrByFloat failed: %v, %v", f, err)
+	}
+}
+
+func TestStoreHash(t *testing.T) {
+	s := newStore(t)
+	if n, err := s.HSet("user", map[string][]byte{"name": []byte("ada"), "visits": []byte("1")}); n != 2 || err != nil {
+		t.Fatalf("HSet failed: %d, %v", n, err)
+	}
+	if n, _ := s.HIncrBy("user", "visits", 2); n != 3 {
+		t.Errorf("Expected 3 visits, got %d", n)
+	}
+	all, err := s.HGetAll("user")
+	if err != nil || len(all) != 2 || string(all["name"]) != "ada" || string(all["visits"]) != "3" {
+		t.Errorf("Unexpected HGetAll %q, %v", all, err)
+	}
+	if _, err := s.HGet("user", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.Get("user"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+	if n, _ := s.HDel("user", "name", "visits"); n != 2 {
+		t.Errorf("Expected 2 deleted fields, got %d", n)
+	}
+	if n, _ := s.HLen("user"); n != 0 {
+		t.Errorf("Expected the hash to be gone, got %d fields", n)
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	s := newStore(t)
+	if n, err := s.RPush("jobs", []byte("a"), []byte("b")); n != 2 || err != nil {
+		t.Fatalf("RPush failed: %d, %v", n, err)
+	}
+	s.LPush("jobs", []byte("first"))
+	got, err := s.LRange("jobs", 0, -1)
+	if err != nil || len(got) != 3 || string(got[0]) != "first" || string(got[2]) != "b" {
+		t.Fatalf("Unexpected LRange %q, %v", got, err)
+	}
+	if v, _ := s.RPop("jobs"); string(v) != "b" {
+		t.Errorf("Expected b, got %q", v)
+	}
+	s.LPop("jobs")
+	s.LPop("jobs")
+	if _, err := s.LPop("jobs"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from an empty list, got %v", err)
+	}
+	if n, _ := s.LLen("jobs"); n != 0 {
+		t.Errorf("Expected the list to be gone, got %d elements", n)
+	}
+}
+
+func TestStoreSet(t *testing.T) {
+	s := newStore(t)
+	if n, err := s.SAdd("tags", "go", "kv", "go"); n != 2 || err != nil {
+		t.Fatalf("SAdd failed: %d, %v", n, err)
+	}
+	if ok, _ := s.SIsMember("tags", "kv"); !ok {
+		t.Errorf("Expected kv to be a member")
+	}
+	members, err := s.SMembers("tags")
+	slices.Sort(members)
+	if err != nil || !slices.Equal(members, []string{"go", "kv"}) {
+		t.Errorf("Unexpected SMembers %q, %v", members, err)
+	}
+	if typ, _ := s.Type("tags"); typ != "set" {
+		t.Errorf("Expected type set, got %q", typ)
+	}
+	s.SRem("tags", "go", "kv")
+	if n, _ := s.SCard("tags"); n != 0 {
+		t.Errorf("Expected the set to be gone, got %d members", n)
+	}
+	if typ, _ := s.Type("tags"); typ != "none" {
+		t.Errorf("Expected type none, got %q", typ)
+	}
+}
+
+func TestStoreSortedSet(t *testing.T) {
+	s := newStore(t)
+	if n, err := s.ZAdd("board", ScoredMember{Member: "ann", Score: 30}, ScoredMember{Member: "bob", Score: 10}, ScoredMember{Member: "cat", Score: 20}); n != 3 || err != nil {
+		t.Fatalf("ZAdd failed: %d, %v", n, err)
+	}
+	if score, _ := s.ZIncrBy("board", "bob", 25); score != 35 {
+		t.Errorf("Expected bob at 35, got %v", score)
+	}
+	if score, err := s.ZScore("board", "cat"); score != 20 || err != nil {
+		t.Errorf("Unexpected ZScore %v, %v", score, err)
+	}
+	if _, err := s.ZScore("board", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	want := []ScoredMember{{Member: "cat", Score: 20}, {Member: "ann", Score: 30}, {Member: "bob", Score: 35}}
+	if got, err := s.ZRange("board", 0, -1); err != nil || !slices.Equal(got, want) {
+		t.Errorf("Unexpected ZRange %v, %v", got, err)
+	}
+	if typ, _ := s.Type("board"); typ != "zset" {
+		t.Errorf("Expected type zset, got %q", typ)
+	}
+	if got, _ := s.ZPopMin("board", 1); !slices.Equal(got, want[:1]) {
+		t.Errorf("Unexpected ZPopMin %v", got)
+	}
+	s.ZRem("board", "ann", "bob")
+	if n, _ := s.ZCard("board"); n != 0 {
+		t.Errorf("Expected the sorted set to be gone, got %d members", n)
+	}
+}
@@ -0,0 +1,83 @@
+package gokv
+
+import (
+	"strconv"
+
+	"github.com/B-AJ-Amar/gokv/internal/protocol"
+	"github.com/B-AJ-Amar/gokv/internal/store"
+)
+
+// ScoredMember is a member of a sorted set and its score
+type ScoredMember = store.ScoredMember
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
+
+// ZAdd adds members to the sorted set at key, creating it when missing, or
+// updates their scores, and returns how many are new
+func (s *Store) ZAdd(key string, members ...ScoredMember) (int64, error) {
+	args := make([]string, 0, 2+2*len(members))
+	args = append(args, "ZADD", key)
+	for _, m := range members {
+		args = append(args, formatScore(m.Score), m.Member)
+	}
+	return s.execInt(args...)
+}
+
+// ZRem removes members and returns how many existed, the sorted set goes
+// away with its last member
+func (s *Store) ZRem(key string, members ...string) (int64, error) {
+	return s.execInt(append([]string{"ZREM", key}, members...)...)
+}
+
+// ZScore returns the score of member, or ErrNotFound
+func (s *Store) ZScore(key, member string) (float64, error) {
+	res, err := s.exec("ZSCORE", key, member)
+	if err != nil {
+		return 0, err
+	}
+	if res.Kind() == protocol.NotExistsRes {
+		return 0, ErrNotFound
+	}
+	return res.Double(), nil
+}
+
+// ZIncrBy adds by to the score of member, a missing one counts as 0, and
+// returns the new score
+func (s *Store) ZIncrBy(key, member string, by float64) (float64, error) {
+	res, err := s.exec("ZINCRBY", key, formatScore(by), member)
+	if err != nil {
+		return 0, err
+	}
+	return res.Double(), nil
+}
+
+func (s *Store) ZCard(key string) (int64, error) {
+	return s.execInt("ZCARD", key)
+}
+
+// ZRange returns the members from rank start to stop with their scores, by
+// ascending score. negative ranks count from the end.
+func (s *Store) ZRange(key string, start, stop int64) ([]ScoredMember, error) {
+	return s.execScored("ZRANGE", key, strconv.FormatInt(start, 10), strconv.FormatInt(stop, 10), "WITHSCORES")
+}
+
+// ZPopMin removes and returns up to count members with the lowest scores
+func (s *Store) ZPopMin(key string, count int64) ([]ScoredMember, error) {
+	return s.execScored("ZPOPMIN", key, strconv.FormatInt(count, 10))
+}
+
+// execScored runs a command replying flat member score pairs
+func (s *Store) execScored(args ...string) ([]ScoredMember, error) {
+	res, err := s.exec(args...)
+	if err != nil {
+		return nil, err
+	}
+	items := res.Items()
+	members := make([]ScoredMember, 0, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		members = append(members, ScoredMember{Member: items[i].Message(), Score: items[i+1].Double()})
+	}
+	return members, nil
+}
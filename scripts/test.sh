@@ -1 +1 @@
-go test ./... -v
\ No newline at end of file
+go test -race ./... -v